@@ -0,0 +1,110 @@
+package aggregator
+
+import (
+	"sync"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+)
+
+var (
+	_ bspgraph.Aggregator = (*IntAccumulator)(nil)
+	_ bspgraph.Aggregator = (*FloatAccumulator)(nil)
+)
+
+// IntAccumulator implements a concurrent-safe accumulator (counter) for int
+// values.
+type IntAccumulator struct {
+	mu      sync.Mutex
+	prevSum int
+	curSum  int
+}
+
+// Type implements bspgraph.Aggregator.
+func (a *IntAccumulator) Type() string {
+	return "IntAccumulator"
+}
+
+// Get returns the current value of the accumulator.
+func (a *IntAccumulator) Get() interface{} {
+	a.mu.Lock()
+	sum := a.curSum
+	a.mu.Unlock()
+	return sum
+}
+
+// Set the current value of the accumulator.
+func (a *IntAccumulator) Set(v interface{}) {
+	a.mu.Lock()
+	a.prevSum = v.(int)
+	a.curSum = a.prevSum
+	a.mu.Unlock()
+}
+
+// Aggregate adds an int value to the accumulator.
+func (a *IntAccumulator) Aggregate(v interface{}) {
+	a.mu.Lock()
+	a.curSum += v.(int)
+	a.mu.Unlock()
+}
+
+// Delta returns the change in the accumulator value since the last call to
+// Delta or Set.
+func (a *IntAccumulator) Delta() interface{} {
+	a.mu.Lock()
+	delta := a.curSum - a.prevSum
+	a.prevSum = a.curSum
+	a.mu.Unlock()
+	return delta
+}
+
+// FloatAccumulator implements a concurrent-safe accumulator for float64
+// values. It uses the Kahan summation algorithm to reduce the numerical error
+// that builds up when adding a large number of values.
+type FloatAccumulator struct {
+	mu      sync.Mutex
+	prevSum float64
+	curSum  float64
+	comp    float64
+}
+
+// Type implements bspgraph.Aggregator.
+func (a *FloatAccumulator) Type() string {
+	return "FloatAccumulator"
+}
+
+// Get returns the current value of the accumulator.
+func (a *FloatAccumulator) Get() interface{} {
+	a.mu.Lock()
+	sum := a.curSum
+	a.mu.Unlock()
+	return sum
+}
+
+// Set the current value of the accumulator.
+func (a *FloatAccumulator) Set(v interface{}) {
+	a.mu.Lock()
+	a.prevSum = v.(float64)
+	a.curSum = a.prevSum
+	a.comp = 0
+	a.mu.Unlock()
+}
+
+// Aggregate adds a float64 value to the accumulator.
+func (a *FloatAccumulator) Aggregate(v interface{}) {
+	a.mu.Lock()
+	y := v.(float64) - a.comp
+	t := a.curSum + y
+	a.comp = (t - a.curSum) - y
+	a.curSum = t
+	a.mu.Unlock()
+}
+
+// Delta returns the change in the accumulator value since the last call to
+// Delta or Set.
+func (a *FloatAccumulator) Delta() interface{} {
+	a.mu.Lock()
+	delta := a.curSum - a.prevSum
+	a.prevSum = a.curSum
+	a.mu.Unlock()
+	return delta
+}
@@ -0,0 +1,211 @@
+package aggregator_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/aggregator"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(AggregatorTestSuite))
+
+type AggregatorTestSuite struct{}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *AggregatorTestSuite) TestIntAccumulator(c *gc.C) {
+	numValues := 100
+	values := make([]interface{}, numValues)
+	var exp int
+	for i := 0; i < numValues; i++ {
+		next := rand.Int()
+		values[i] = next
+		exp += next
+	}
+
+	got := testConcurrentAccess(new(aggregator.IntAccumulator), values).(int)
+	c.Assert(got, gc.Equals, exp)
+}
+
+func (s *AggregatorTestSuite) TestFloatAccumulator(c *gc.C) {
+	numValues := 100
+	values := make([]interface{}, numValues)
+	var exp float64
+	for i := 0; i < numValues; i++ {
+		next := rand.Float64()
+		values[i] = next
+		exp += next
+	}
+
+	got := testConcurrentAccess(new(aggregator.FloatAccumulator), values).(float64)
+	absDelta := math.Abs(exp - got)
+	c.Assert(absDelta < 1e-6, gc.Equals, true, gc.Commentf("expected to get %f; got %f; |delta| %f > 1e-6", exp, got, absDelta))
+}
+
+func (s *AggregatorTestSuite) TestFloatAccumulatorKahanSummation(c *gc.C) {
+	acc := new(aggregator.FloatAccumulator)
+	acc.Set(1.0)
+	for i := 0; i < 10000; i++ {
+		acc.Aggregate(1e-16)
+	}
+	c.Assert(acc.Get().(float64) > 1.0, gc.Equals, true, gc.Commentf("small values were lost due to rounding errors"))
+}
+
+func (s *AggregatorTestSuite) TestAccumulatorDelta(c *gc.C) {
+	local := new(aggregator.IntAccumulator)
+	global := new(aggregator.IntAccumulator)
+	local.Set(10)
+	local.Aggregate(5)
+	local.Aggregate(2)
+	global.Aggregate(local.Delta())
+	c.Assert(global.Get(), gc.Equals, 7)
+
+	local.Aggregate(3)
+	global.Aggregate(local.Delta())
+	c.Assert(global.Get(), gc.Equals, 10)
+	c.Assert(local.Delta(), gc.Equals, 0)
+}
+
+func (s *AggregatorTestSuite) TestMinMax(c *gc.C) {
+	values := []interface{}{3, -7, 42, 0, 12}
+	c.Assert(testConcurrentAccess(new(aggregator.IntMin), values), gc.Equals, -7)
+	c.Assert(testConcurrentAccess(new(aggregator.IntMax), values), gc.Equals, 42)
+
+	fValues := []interface{}{3.5, -7.25, 42.0, 0.0, 12.5}
+	c.Assert(testConcurrentAccess(new(aggregator.FloatMin), fValues), gc.Equals, -7.25)
+	c.Assert(testConcurrentAccess(new(aggregator.FloatMax), fValues), gc.Equals, 42.0)
+
+	min := new(aggregator.FloatMin)
+	c.Assert(math.IsInf(min.Get().(float64), 1), gc.Equals, true)
+	min.Aggregate(1.0)
+	min.Set(nil)
+	c.Assert(math.IsInf(min.Get().(float64), 1), gc.Equals, true)
+}
+
+func (s *AggregatorTestSuite) TestMinMaxDelta(c *gc.C) {
+	global := new(aggregator.IntMax)
+	for _, localMax := range []int{4, 9, 2} {
+		local := new(aggregator.IntMax)
+		local.Aggregate(localMax)
+		global.Aggregate(local.Delta())
+		global.Aggregate(local.Delta())
+	}
+	c.Assert(global.Get(), gc.Equals, 9)
+}
+
+func (s *AggregatorTestSuite) TestTopN(c *gc.C) {
+	values := []interface{}{
+		aggregator.TopNEntry{ID: "a", Value: 1},
+		aggregator.TopNEntry{ID: "b", Value: 5},
+		aggregator.TopNEntry{ID: "c", Value: 3},
+		aggregator.TopNEntry{ID: "d", Value: 4},
+		aggregator.TopNEntry{ID: "a", Value: 10},
+		aggregator.TopNEntry{ID: "e", Value: 2},
+	}
+
+	topN, err := aggregator.NewTopN(3)
+	c.Assert(err, gc.IsNil)
+	got := testConcurrentAccess(topN, values)
+	c.Assert(got, gc.DeepEquals, []aggregator.TopNEntry{
+		{ID: "a", Value: 10},
+		{ID: "b", Value: 5},
+		{ID: "d", Value: 4},
+	})
+}
+
+func (s *AggregatorTestSuite) TestTopNDelta(c *gc.C) {
+	global, err := aggregator.NewTopN(2)
+	c.Assert(err, gc.IsNil)
+	local1, err := aggregator.NewTopN(2)
+	c.Assert(err, gc.IsNil)
+	local2, err := aggregator.NewTopN(2)
+	c.Assert(err, gc.IsNil)
+	local1.Aggregate(aggregator.TopNEntry{ID: "a", Value: 1})
+	local1.Aggregate(aggregator.TopNEntry{ID: "b", Value: 7})
+	local2.Aggregate(aggregator.TopNEntry{ID: "c", Value: 3})
+
+	global.Aggregate(local1.Delta())
+	global.Aggregate(local2.Delta())
+	global.Aggregate(local1.Delta())
+	c.Assert(global.Get(), gc.DeepEquals, []aggregator.TopNEntry{
+		{ID: "b", Value: 7},
+		{ID: "c", Value: 3},
+	})
+}
+
+func (s *AggregatorTestSuite) TestTopNZeroValue(c *gc.C) {
+	topN := new(aggregator.TopN)
+	c.Assert(topN.Get(), gc.HasLen, 0)
+	for i := 0; i < 15; i++ {
+		topN.Aggregate(aggregator.TopNEntry{ID: fmt.Sprint(i), Value: float64(i)})
+	}
+	got := topN.Get().([]aggregator.TopNEntry)
+	c.Assert(got, gc.HasLen, 10)
+	c.Assert(got[0], gc.Equals, aggregator.TopNEntry{ID: "14", Value: 14})
+
+	topN = new(aggregator.TopN)
+	topN.Set([]aggregator.TopNEntry{{ID: "a", Value: 1}})
+	c.Assert(topN.Get(), gc.DeepEquals, []aggregator.TopNEntry{{ID: "a", Value: 1}})
+
+	_, err := aggregator.NewTopN(0)
+	c.Assert(err, gc.ErrorMatches, ".*n must be > 0.*")
+}
+
+func (s *AggregatorTestSuite) TestStringSet(c *gc.C) {
+	values := []interface{}{"b", "a", []string{"c", "a"}, "b"}
+	got := testConcurrentAccess(new(aggregator.StringSet), values)
+	c.Assert(got, gc.DeepEquals, []string{"a", "b", "c"})
+}
+
+func (s *AggregatorTestSuite) TestStringSetDelta(c *gc.C) {
+	local := new(aggregator.StringSet)
+	global := new(aggregator.StringSet)
+	local.Aggregate("x")
+	local.Aggregate("y")
+	c.Assert(local.Delta(), gc.DeepEquals, []string{"x", "y"})
+	local.Aggregate("x")
+	local.Aggregate("z")
+	delta := local.Delta()
+	c.Assert(delta, gc.DeepEquals, []string{"z"})
+	c.Assert(local.Delta(), gc.DeepEquals, []string{})
+
+	global.Aggregate(delta)
+	c.Assert(global.Get(), gc.DeepEquals, []string{"z"})
+}
+
+// testConcurrentAccess aggregates values into a using multiple goroutines
+// and returns the final aggregator value.
+func testConcurrentAccess(a bspgraph.Aggregator, values []interface{}) interface{} {
+	startedCh := make(chan struct{})
+	syncCh := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < len(values); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			startedCh <- struct{}{}
+			<-syncCh
+			a.Aggregate(values[i])
+		}(i)
+	}
+
+	// Wait for all goroutines to start
+	for i := 0; i < len(values); i++ {
+		<-startedCh
+	}
+
+	// Allow each goroutine to update the aggregator
+	close(syncCh)
+
+	// Wait for all goroutines to exit
+	wg.Wait()
+
+	return a.Get()
+}
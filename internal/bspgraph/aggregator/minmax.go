@@ -0,0 +1,227 @@
+package aggregator
+
+import (
+	"math"
+	"sync"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+)
+
+var (
+	_ bspgraph.Aggregator = (*IntMin)(nil)
+	_ bspgraph.Aggregator = (*IntMax)(nil)
+	_ bspgraph.Aggregator = (*FloatMin)(nil)
+	_ bspgraph.Aggregator = (*FloatMax)(nil)
+)
+
+// IntMin implements a concurrent-safe aggregator that tracks the minimum of
+// the int values passed to it. The zero value of IntMin reports
+// math.MaxInt64 until a value gets aggregated.
+//
+// As min is idempotent, Delta simply returns the current minimum which can be
+// safely aggregated into a top-level IntMin instance.
+type IntMin struct {
+	mu    sync.Mutex
+	set   bool
+	value int
+}
+
+// Type implements bspgraph.Aggregator.
+func (a *IntMin) Type() string {
+	return "IntMin"
+}
+
+// Get returns the current minimum value.
+func (a *IntMin) Get() interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.set {
+		return math.MaxInt64
+	}
+	return a.value
+}
+
+// Set the current minimum value. Passing a nil value resets the
+// aggregator to its initial state.
+func (a *IntMin) Set(v interface{}) {
+	a.mu.Lock()
+	if v == nil {
+		a.value, a.set = 0, false
+	} else {
+		a.value, a.set = v.(int), true
+	}
+	a.mu.Unlock()
+}
+
+// Aggregate updates the minimum if v is less than the current value.
+func (a *IntMin) Aggregate(v interface{}) {
+	a.mu.Lock()
+	if val := v.(int); !a.set || val < a.value {
+		a.value, a.set = val, true
+	}
+	a.mu.Unlock()
+}
+
+// Delta returns the current minimum value.
+func (a *IntMin) Delta() interface{} {
+	return a.Get()
+}
+
+// IntMax implements a concurrent-safe aggregator that tracks the maximum of
+// the int values passed to it. The zero value of IntMax reports
+// math.MinInt64 until a value gets aggregated.
+//
+// As max is idempotent, Delta simply returns the current maximum which can be
+// safely aggregated into a top-level IntMax instance.
+type IntMax struct {
+	mu    sync.Mutex
+	set   bool
+	value int
+}
+
+// Type implements bspgraph.Aggregator.
+func (a *IntMax) Type() string {
+	return "IntMax"
+}
+
+// Get returns the current maximum value.
+func (a *IntMax) Get() interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.set {
+		return math.MinInt64
+	}
+	return a.value
+}
+
+// Set the current maximum value. Passing a nil value resets the
+// aggregator to its initial state.
+func (a *IntMax) Set(v interface{}) {
+	a.mu.Lock()
+	if v == nil {
+		a.value, a.set = 0, false
+	} else {
+		a.value, a.set = v.(int), true
+	}
+	a.mu.Unlock()
+}
+
+// Aggregate updates the maximum if v is greater than the current value.
+func (a *IntMax) Aggregate(v interface{}) {
+	a.mu.Lock()
+	if val := v.(int); !a.set || val > a.value {
+		a.value, a.set = val, true
+	}
+	a.mu.Unlock()
+}
+
+// Delta returns the current maximum value.
+func (a *IntMax) Delta() interface{} {
+	return a.Get()
+}
+
+// FloatMin implements a concurrent-safe aggregator that tracks the minimum of
+// the float64 values passed to it. The zero value of FloatMin reports +Inf
+// until a value gets aggregated.
+//
+// As min is idempotent, Delta simply returns the current minimum which can be
+// safely aggregated into a top-level FloatMin instance.
+type FloatMin struct {
+	mu    sync.Mutex
+	set   bool
+	value float64
+}
+
+// Type implements bspgraph.Aggregator.
+func (a *FloatMin) Type() string {
+	return "FloatMin"
+}
+
+// Get returns the current minimum value.
+func (a *FloatMin) Get() interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.set {
+		return math.Inf(1)
+	}
+	return a.value
+}
+
+// Set the current minimum value. Passing a nil value resets the
+// aggregator to its initial state.
+func (a *FloatMin) Set(v interface{}) {
+	a.mu.Lock()
+	if v == nil {
+		a.value, a.set = 0, false
+	} else {
+		a.value, a.set = v.(float64), true
+	}
+	a.mu.Unlock()
+}
+
+// Aggregate updates the minimum if v is less than the current value.
+func (a *FloatMin) Aggregate(v interface{}) {
+	a.mu.Lock()
+	if val := v.(float64); !a.set || val < a.value {
+		a.value, a.set = val, true
+	}
+	a.mu.Unlock()
+}
+
+// Delta returns the current minimum value.
+func (a *FloatMin) Delta() interface{} {
+	return a.Get()
+}
+
+// FloatMax implements a concurrent-safe aggregator that tracks the maximum of
+// the float64 values passed to it. The zero value of FloatMax reports -Inf
+// until a value gets aggregated.
+//
+// As max is idempotent, Delta simply returns the current maximum which can be
+// safely aggregated into a top-level FloatMax instance.
+type FloatMax struct {
+	mu    sync.Mutex
+	set   bool
+	value float64
+}
+
+// Type implements bspgraph.Aggregator.
+func (a *FloatMax) Type() string {
+	return "FloatMax"
+}
+
+// Get returns the current maximum value.
+func (a *FloatMax) Get() interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.set {
+		return math.Inf(-1)
+	}
+	return a.value
+}
+
+// Set the current maximum value. Passing a nil value resets the
+// aggregator to its initial state.
+func (a *FloatMax) Set(v interface{}) {
+	a.mu.Lock()
+	if v == nil {
+		a.value, a.set = 0, false
+	} else {
+		a.value, a.set = v.(float64), true
+	}
+	a.mu.Unlock()
+}
+
+// Aggregate updates the maximum if v is greater than the current value.
+func (a *FloatMax) Aggregate(v interface{}) {
+	a.mu.Lock()
+	if val := v.(float64); !a.set || val > a.value {
+		a.value, a.set = val, true
+	}
+	a.mu.Unlock()
+}
+
+// Delta returns the current maximum value.
+func (a *FloatMax) Delta() interface{} {
+	return a.Get()
+}
@@ -0,0 +1,98 @@
+package aggregator
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+)
+
+var _ bspgraph.Aggregator = (*StringSet)(nil)
+
+// StringSet implements a concurrent-safe aggregator that computes the union
+// of the string values passed to it.
+type StringSet struct {
+	mu      sync.Mutex
+	members map[string]struct{}
+	added   map[string]struct{}
+}
+
+// Type implements bspgraph.Aggregator.
+func (a *StringSet) Type() string {
+	return "StringSet"
+}
+
+// Get returns the set members as a sorted []string.
+func (a *StringSet) Get() interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return sortedKeys(a.members)
+}
+
+// Set replaces the set members with the provided []string value. Passing a
+// nil value clears the set.
+func (a *StringSet) Set(v interface{}) {
+	a.mu.Lock()
+	a.members = make(map[string]struct{})
+	a.added = nil
+	if v != nil {
+		for _, m := range v.([]string) {
+			a.members[m] = struct{}{}
+		}
+	}
+	a.mu.Unlock()
+}
+
+// Aggregate adds a string or a []string (e.g. the output of a call to Delta)
+// to the set.
+func (a *StringSet) Aggregate(v interface{}) {
+	a.mu.Lock()
+	switch val := v.(type) {
+	case string:
+		a.add(val)
+	case []string:
+		for _, m := range val {
+			a.add(m)
+		}
+	default:
+		a.mu.Unlock()
+		panic("StringSet: unsupported aggregate value type")
+	}
+	a.mu.Unlock()
+}
+
+// Delta returns a sorted []string with the members that were added to the set
+// since the last call to Delta or Set.
+func (a *StringSet) Delta() interface{} {
+	a.mu.Lock()
+	delta := sortedKeys(a.added)
+	a.added = nil
+	a.mu.Unlock()
+	return delta
+}
+
+// add inserts a member to the set and keeps track of it for the next call to
+// Delta. Callers must hold the lock.
+func (a *StringSet) add(m string) {
+	if _, exists := a.members[m]; exists {
+		return
+	}
+	if a.members == nil {
+		a.members = make(map[string]struct{})
+	}
+	if a.added == nil {
+		a.added = make(map[string]struct{})
+	}
+	a.members[m] = struct{}{}
+	a.added[m] = struct{}{}
+}
+
+// sortedKeys returns the keys of m as a sorted slice.
+func sortedKeys(m map[string]struct{}) []string {
+	list := make([]string, 0, len(m))
+	for k := range m {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
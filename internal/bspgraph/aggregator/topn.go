@@ -0,0 +1,145 @@
+package aggregator
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"golang.org/x/xerrors"
+)
+
+// defaultTopNSize is the number of entries tracked by a zero TopN value.
+const defaultTopNSize = 10
+
+var _ bspgraph.Aggregator = (*TopN)(nil)
+
+// TopNEntry is an (ID, Value) tuple that is tracked by the TopN aggregator.
+type TopNEntry struct {
+	ID    string
+	Value float64
+}
+
+// TopN implements a concurrent-safe aggregator that keeps track of the N
+// entries with the highest values. Each entry ID appears at most once; if an
+// ID is aggregated multiple times, the highest value is retained.
+//
+// TopN is meant to be used with small values of N as each insertion that
+// causes the entry list to overflow needs to scan all tracked entries. The
+// zero value is ready to use and tracks the top 10 entries.
+type TopN struct {
+	mu      sync.Mutex
+	n       int
+	entries map[string]float64
+}
+
+// NewTopN returns a new TopN aggregator that keeps track of the n entries
+// with the highest values.
+func NewTopN(n int) (*TopN, error) {
+	if n <= 0 {
+		return nil, xerrors.Errorf("NewTopN: n must be > 0; got %d", n)
+	}
+	return &TopN{
+		n:       n,
+		entries: make(map[string]float64),
+	}, nil
+}
+
+// Type implements bspgraph.Aggregator.
+func (a *TopN) Type() string {
+	return "TopN"
+}
+
+// Get returns a []TopNEntry with the currently tracked entries sorted by
+// value in descending order.
+func (a *TopN) Get() interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sortedEntries()
+}
+
+// Set replaces the tracked entries with the provided []TopNEntry value.
+// Passing a nil value clears the list of tracked entries.
+func (a *TopN) Set(v interface{}) {
+	a.mu.Lock()
+	a.entries = make(map[string]float64)
+	if v != nil {
+		for _, e := range v.([]TopNEntry) {
+			a.add(e)
+		}
+	}
+	a.mu.Unlock()
+}
+
+// Aggregate inserts a TopNEntry or a []TopNEntry (e.g. the output of a call
+// to Delta) to the list of tracked entries.
+func (a *TopN) Aggregate(v interface{}) {
+	a.mu.Lock()
+	switch val := v.(type) {
+	case TopNEntry:
+		a.add(val)
+	case []TopNEntry:
+		for _, e := range val {
+			a.add(e)
+		}
+	default:
+		a.mu.Unlock()
+		panic("TopN: unsupported aggregate value type")
+	}
+	a.mu.Unlock()
+}
+
+// Delta returns the currently tracked entries. As the entry list is keyed by
+// ID and retains the maximum value for each ID, aggregating the same entries
+// multiple times into a top-level TopN aggregator is safe.
+func (a *TopN) Delta() interface{} {
+	return a.Get()
+}
+
+// add inserts an entry and evicts the entry with the lowest value if the list
+// exceeds its capacity. Callers must hold the lock.
+func (a *TopN) add(e TopNEntry) {
+	if a.n <= 0 {
+		a.n = defaultTopNSize
+	}
+	if a.entries == nil {
+		a.entries = make(map[string]float64)
+	}
+	if cur, exists := a.entries[e.ID]; exists {
+		if e.Value > cur {
+			a.entries[e.ID] = e.Value
+		}
+		return
+	}
+	a.entries[e.ID] = e.Value
+	if len(a.entries) <= a.n {
+		return
+	}
+
+	var (
+		minID  string
+		minVal float64
+		first  = true
+	)
+	for id, val := range a.entries {
+		if first || val < minVal || (val == minVal && id > minID) {
+			minID, minVal, first = id, val, false
+		}
+	}
+	delete(a.entries, minID)
+}
+
+// sortedEntries returns the tracked entries sorted by value in descending
+// order. Ties are broken by ID. Callers must hold the lock.
+func (a *TopN) sortedEntries() []TopNEntry {
+	list := make([]TopNEntry, 0, len(a.entries))
+	for id, val := range a.entries {
+		list = append(list, TopNEntry{ID: id, Value: val})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Value != list[j].Value {
+			return list[i].Value > list[j].Value
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
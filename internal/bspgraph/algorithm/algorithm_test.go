@@ -0,0 +1,126 @@
+package algorithm
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(AlgorithmTestSuite))
+
+// AlgorithmTestSuite verifies the graph algorithms using the default
+// in-memory message queue.
+type AlgorithmTestSuite struct{}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *AlgorithmTestSuite) TestConnectedComponents(c *gc.C) {
+	// Two islands that are only weakly connected: {a, b, c, d} and {x, y}
+	// plus the isolated vertex z.
+	g := newTestGraph(c, ConnectedComponents, nil, map[string][]string{
+		"a": nil,
+		"b": {"a"},
+		"c": {"b"},
+		"d": {"c"},
+		"x": nil,
+		"y": {"x"},
+		"z": nil,
+	})
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	c.Assert(bspgraph.NewExecutor(g, ConnectedComponentsCallbacks()).RunToCompletion(context.Background()), gc.IsNil)
+
+	exp := map[string]string{"a": "a", "b": "a", "c": "a", "d": "a", "x": "x", "y": "x", "z": "z"}
+	for id, v := range g.Vertices() {
+		c.Assert(ComponentID(v), gc.Equals, exp[id], gc.Commentf("vertex %q", id))
+	}
+}
+
+func (s *AlgorithmTestSuite) TestShortestPath(c *gc.C) {
+	g := newTestGraph(c, NewShortestPathComputeFn("seed"), MinDistance, map[string][]string{
+		"seed": {"a", "b"},
+		"a":    {"c"},
+		"b":    {"c"},
+		"c":    {"d"},
+		"d":    nil,
+		"e":    {"seed"},
+	})
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	// Make the seed -> a -> c path more expensive than seed -> b -> c.
+	c.Assert(g.RemoveEdge("seed", "a"), gc.IsNil)
+	c.Assert(g.AddEdge("seed", "a", 5.0), gc.IsNil)
+	c.Assert(bspgraph.NewExecutor(g, ShortestPathCallbacks()).RunToCompletion(context.Background()), gc.IsNil)
+
+	exp := map[string]float64{"seed": 0, "a": 5, "b": 1, "c": 2, "d": 3, "e": math.Inf(1)}
+	for id, v := range g.Vertices() {
+		c.Assert(v.Value(), gc.Equals, exp[id], gc.Commentf("vertex %q", id))
+	}
+}
+
+func (s *AlgorithmTestSuite) TestShortestPathInvalidWeight(c *gc.C) {
+	g := newTestGraph(c, NewShortestPathComputeFn("a"), nil, map[string][]string{"a": nil, "b": nil})
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	c.Assert(g.AddEdge("a", "b", -1.0), gc.IsNil)
+
+	err := bspgraph.NewExecutor(g, ShortestPathCallbacks()).RunToCompletion(context.Background())
+	c.Assert(err, gc.ErrorMatches, ".*invalid edge weight")
+}
+
+func (s *AlgorithmTestSuite) TestLabelPropagation(c *gc.C) {
+	// Two densely connected clusters joined by a single c -> x edge.
+	g := newTestGraph(c, LabelPropagation, nil, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"a", "c"},
+		"c": {"a", "b", "x"},
+		"x": {"y", "z"},
+		"y": {"x", "z"},
+		"z": {"x", "y"},
+	})
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	c.Assert(bspgraph.NewExecutor(g, LabelPropagationCallbacks(20)).RunToCompletion(context.Background()), gc.IsNil)
+
+	exp := map[string]string{"a": "a", "b": "a", "c": "a", "x": "x", "y": "x", "z": "x"}
+	for id, v := range g.Vertices() {
+		c.Assert(v.Value(), gc.Equals, exp[id], gc.Commentf("vertex %q", id))
+	}
+}
+
+func (s *AlgorithmTestSuite) TestLabelPropagationMaxSteps(c *gc.C) {
+	g := newTestGraph(c, LabelPropagation, nil, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": nil,
+	})
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	c.Assert(bspgraph.NewExecutor(g, LabelPropagationCallbacks(2)).RunToCompletion(context.Background()), gc.IsNil)
+
+	// After two supersteps the label of a has only travelled a single hop.
+	c.Assert(g.Superstep(), gc.Equals, 1)
+	c.Assert(g.Vertices()["b"].Value(), gc.Equals, "a")
+	c.Assert(g.Vertices()["c"].Value(), gc.Equals, "b")
+}
+
+func newTestGraph(c *gc.C, computeFn bspgraph.ComputeFunc, combiner message.Combiner, adjacency map[string][]string) *bspgraph.Graph {
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn:      computeFn,
+		ComputeWorkers: 4,
+		Combiner:       combiner,
+	})
+	c.Assert(err, gc.IsNil)
+
+	for id := range adjacency {
+		g.AddVertex(id, nil)
+	}
+	for src, dsts := range adjacency {
+		for _, dst := range dsts {
+			c.Assert(g.AddEdge(src, dst, nil), gc.IsNil)
+		}
+	}
+	return g
+}
@@ -0,0 +1,107 @@
+package algorithm
+
+import (
+	"context"
+	"encoding/gob"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+)
+
+func init() {
+	gob.Register(ComponentState{})
+	message.RegisterCodec(ComponentMessage{}.Type(), message.NewGobCodec(ComponentMessage{}))
+}
+
+// ComponentMessage is used by the connected components algorithm to
+// propagate the smallest known component ID to the neighbors of a vertex.
+type ComponentMessage struct {
+	SenderID    string
+	ComponentID string
+}
+
+// Type returns the type of this message.
+func (ComponentMessage) Type() string { return "component" }
+
+// ComponentState is the vertex value used by the connected components
+// algorithm.
+type ComponentState struct {
+	// ComponentID is the ID of the weakly connected component that the
+	// vertex belongs to. It is equal to the smallest vertex ID in the
+	// component.
+	ComponentID string
+
+	// InNeighbors holds the IDs of the vertices with an edge pointing to
+	// this vertex. They are discovered at superstep 1.
+	InNeighbors []string
+}
+
+// ComponentID returns the component ID that the connected components
+// algorithm assigned to vertex v.
+func ComponentID(v *bspgraph.Vertex) string {
+	return v.Value().(ComponentState).ComponentID
+}
+
+// ConnectedComponents implements a bspgraph.ComputeFunc that identifies the
+// weakly connected components of a graph. Once the algorithm completes, the
+// value of each vertex is a ComponentState whose ComponentID is the smallest
+// vertex ID in the vertex's component.
+//
+// As edge direction is ignored, the algorithm spends superstep 0 discovering
+// the in-neighbors of each vertex. From superstep 1 onwards, each vertex
+// adopts the smallest component ID it receives and forwards it to all its
+// neighbors whenever it changes.
+func ConnectedComponents(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+	superstep := g.Superstep()
+	if superstep == 0 {
+		v.SetValue(ComponentState{ComponentID: v.ID()})
+		v.Freeze()
+		return g.BroadcastToNeighbors(context.Background(), v, ComponentMessage{SenderID: v.ID(), ComponentID: v.ID()})
+	}
+
+	state := v.Value().(ComponentState)
+	changed := false
+	for msgIt.Next() {
+		msg := msgIt.Message().(ComponentMessage)
+		if superstep == 1 {
+			state.InNeighbors = append(state.InNeighbors, msg.SenderID)
+		}
+		if msg.ComponentID < state.ComponentID {
+			state.ComponentID = msg.ComponentID
+			changed = true
+		}
+	}
+	v.SetValue(state)
+	v.Freeze()
+
+	// At superstep 1 the in-neighbors learn about this vertex for the first
+	// time so the component ID must be sent even if it did not change.
+	if superstep > 1 && !changed {
+		return nil
+	}
+	msg := ComponentMessage{SenderID: v.ID(), ComponentID: state.ComponentID}
+	if err := g.BroadcastToNeighbors(context.Background(), v, msg); err != nil {
+		return err
+	}
+	for _, dstID := range state.InNeighbors {
+		if err := g.SendMessage(context.Background(), dstID, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConnectedComponentsCallbacks returns the executor callbacks for running the
+// connected components algorithm. The execution stops once no vertex was
+// active in a superstep.
+func ConnectedComponentsCallbacks() bspgraph.ExecutorCallbacks {
+	return bspgraph.ExecutorCallbacks{
+		PostStepKeepRunning: untilQuiescent,
+	}
+}
+
+// untilQuiescent is a PostStepKeepRunning callback that keeps running
+// supersteps as long as at least one vertex was active.
+func untilQuiescent(_ context.Context, _ *bspgraph.Graph, activeInStep int) (bool, error) {
+	return activeInStep > 0, nil
+}
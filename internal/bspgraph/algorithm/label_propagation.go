@@ -0,0 +1,87 @@
+package algorithm
+
+import (
+	"context"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/aggregator"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+)
+
+// labelChangesAggr is the name of the aggregator that counts the number of
+// vertices whose label changed in a superstep.
+const labelChangesAggr = "label_changes"
+
+func init() {
+	message.RegisterCodec(LabelMessage{}.Type(), message.NewGobCodec(LabelMessage{}))
+}
+
+// LabelMessage is used by the label propagation algorithm to advertise the
+// current label of a vertex to its neighbors.
+type LabelMessage struct {
+	Label string
+}
+
+// Type returns the type of this message.
+func (LabelMessage) Type() string { return "label" }
+
+// LabelPropagation implements a bspgraph.ComputeFunc that clusters the graph
+// vertices using the label propagation algorithm. Once the algorithm
+// completes, the value of each vertex is a string label shared by all the
+// vertices of its cluster.
+//
+// Each vertex starts with its own ID as its label. In every subsequent
+// superstep, a vertex adopts the most frequent label among its own label and
+// the labels of its in-neighbors. Ties are broken in favor of the smallest
+// label so that the outcome does not depend on the message order.
+//
+// LabelPropagation must be used together with the callbacks returned by
+// LabelPropagationCallbacks.
+func LabelPropagation(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+	if g.Superstep() == 0 {
+		v.SetValue(v.ID())
+		return g.BroadcastToNeighbors(context.Background(), v, LabelMessage{Label: v.ID()})
+	}
+
+	label := v.Value().(string)
+	counts := map[string]int{label: 1}
+	for msgIt.Next() {
+		counts[msgIt.Message().(LabelMessage).Label]++
+	}
+
+	newLabel, best := label, 0
+	for l, count := range counts {
+		if count > best || (count == best && l < newLabel) {
+			newLabel, best = l, count
+		}
+	}
+	if newLabel != label {
+		v.SetValue(newLabel)
+		g.Aggregator(labelChangesAggr).Aggregate(1)
+	}
+	return g.BroadcastToNeighbors(context.Background(), v, LabelMessage{Label: newLabel})
+}
+
+// LabelPropagationCallbacks returns the executor callbacks for running the
+// label propagation algorithm. The execution stops once a superstep completes
+// without any label changes or after maxSteps supersteps, whichever comes
+// first. A maxSteps value of zero disables the superstep limit.
+func LabelPropagationCallbacks(maxSteps int) bspgraph.ExecutorCallbacks {
+	return bspgraph.ExecutorCallbacks{
+		PreStep: func(_ context.Context, g *bspgraph.Graph) error {
+			if aggr := g.Aggregator(labelChangesAggr); aggr != nil {
+				aggr.Set(0)
+			} else {
+				g.RegisterAggregator(labelChangesAggr, new(aggregator.IntAccumulator))
+			}
+			return nil
+		},
+		PostStepKeepRunning: func(_ context.Context, g *bspgraph.Graph, _ int) (bool, error) {
+			if maxSteps > 0 && g.Superstep()+1 >= maxSteps {
+				return false, nil
+			}
+			// Superstep 0 only initializes the labels.
+			return g.Superstep() == 0 || g.Aggregator(labelChangesAggr).Get().(int) > 0, nil
+		},
+	}
+}
@@ -0,0 +1,109 @@
+package algorithm
+
+import (
+	"context"
+	"math"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	message.RegisterCodec(DistanceMessage{}.Type(), message.NewGobCodec(DistanceMessage{}))
+}
+
+// ErrInvalidEdgeWeight is returned by the shortest path compute function when
+// an edge value is neither nil nor a non-negative float64.
+var ErrInvalidEdgeWeight = xerrors.New("invalid edge weight")
+
+// DistanceMessage is used by the shortest path algorithm to propagate
+// tentative distances from the source vertices.
+type DistanceMessage struct {
+	Distance float64
+}
+
+// Type returns the type of this message.
+func (DistanceMessage) Type() string { return "distance" }
+
+// MinDistance is a message.Combiner that keeps the DistanceMessage with the
+// smallest distance.
+func MinDistance(a, b message.Message) message.Message {
+	if a.(DistanceMessage).Distance <= b.(DistanceMessage).Distance {
+		return a
+	}
+	return b
+}
+
+// NewShortestPathComputeFn returns a bspgraph.ComputeFunc that calculates the
+// length of the shortest path from any of the specified source vertices to
+// every other vertex. Once the algorithm completes, the value of each vertex
+// is a float64 distance; vertices that cannot be reached are assigned
+// +Inf.
+//
+// Edge values are interpreted as non-negative float64 weights. Edges with a
+// nil value are assigned a weight of 1 so that, for example, the crawl depth
+// from a set of seed URLs can be calculated on an unweighted link graph.
+func NewShortestPathComputeFn(sources ...string) bspgraph.ComputeFunc {
+	isSource := make(map[string]bool, len(sources))
+	for _, id := range sources {
+		isSource[id] = true
+	}
+
+	return func(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+		var dist float64
+		if g.Superstep() == 0 {
+			dist = math.Inf(1)
+			if isSource[v.ID()] {
+				dist = 0
+			}
+		} else {
+			dist = v.Value().(float64)
+		}
+
+		minDist := dist
+		for msgIt.Next() {
+			if d := msgIt.Message().(DistanceMessage).Distance; d < minDist {
+				minDist = d
+			}
+		}
+		v.SetValue(minDist)
+		v.Freeze()
+
+		// Only propagate distances that were improved or, at superstep 0,
+		// the distances of the source vertices.
+		if math.IsInf(minDist, 1) || (g.Superstep() != 0 && minDist >= dist) {
+			return nil
+		}
+		for _, e := range v.Edges() {
+			weight, err := edgeWeight(e)
+			if err != nil {
+				return err
+			}
+			if err = g.SendMessage(context.Background(), e.DstID(), DistanceMessage{Distance: minDist + weight}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func edgeWeight(e *bspgraph.Edge) (float64, error) {
+	if e.Value() == nil {
+		return 1, nil
+	}
+	weight, ok := e.Value().(float64)
+	if !ok || weight < 0 || math.IsNaN(weight) {
+		return 0, xerrors.Errorf("edge to %q: %w", e.DstID(), ErrInvalidEdgeWeight)
+	}
+	return weight, nil
+}
+
+// ShortestPathCallbacks returns the executor callbacks for running the
+// shortest path algorithm. The execution stops once no vertex was active in a
+// superstep.
+func ShortestPathCallbacks() bspgraph.ExecutorCallbacks {
+	return bspgraph.ExecutorCallbacks{
+		PostStepKeepRunning: untilQuiescent,
+	}
+}
@@ -0,0 +1,207 @@
+package bspgraph
+
+import (
+	"context"
+	"time"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// ErrNoCheckpoint is returned by a CheckpointStore when no checkpoint
+	// is available.
+	ErrNoCheckpoint = xerrors.New("no checkpoint available")
+
+	// ErrUnknownAggregator is returned when restoring a checkpoint that
+	// contains the value of an aggregator that has not been registered
+	// with the graph.
+	ErrUnknownAggregator = xerrors.New("aggregator is not registered with the graph")
+)
+
+// Checkpoint captures the state of a graph between two supersteps. Vertex,
+// edge and aggregator values are serialized by the checkpoint stores using
+// encoding/gob; values with custom types must be registered via gob.Register.
+// Messages are serialized using the codec that is registered for their type
+// via message.RegisterCodec.
+type Checkpoint struct {
+	// Superstep is the superstep that will be executed next when
+	// resuming from this checkpoint.
+	Superstep int
+
+	// Vertices contains the state of each graph vertex.
+	Vertices []CheckpointVertex
+
+	// Aggregators contains the value of each registered aggregator.
+	Aggregators map[string]interface{}
+}
+
+// CheckpointVertex captures the state of a single vertex.
+type CheckpointVertex struct {
+	ID     string
+	Value  interface{}
+	Active bool
+	Edges  []CheckpointEdge
+
+	// Messages contains the messages that are waiting to be processed
+	// by the vertex in the next superstep.
+	Messages []CheckpointMessage
+}
+
+// CheckpointEdge captures the state of an outgoing vertex edge.
+type CheckpointEdge struct {
+	DstID string
+	Value interface{}
+}
+
+// CheckpointMessage contains a serialized message.
+type CheckpointMessage struct {
+	Type string
+	Data []byte
+}
+
+// CheckpointStore is implemented by types that can persist graph checkpoints.
+type CheckpointStore interface {
+	// Save persists a checkpoint, replacing any previously saved one.
+	Save(ctx context.Context, cp *Checkpoint) error
+
+	// Latest returns the most recently saved checkpoint or ErrNoCheckpoint
+	// if no checkpoint is available.
+	Latest(ctx context.Context) (*Checkpoint, error)
+}
+
+// CheckpointPolicy controls how often an Executor writes graph checkpoints.
+// A checkpoint is written after a superstep completes if at least EverySteps
+// supersteps or at least Every time has elapsed since the last checkpoint.
+type CheckpointPolicy struct {
+	// EverySteps, if positive, triggers a checkpoint every EverySteps
+	// supersteps.
+	EverySteps int
+
+	// Every, if positive, triggers a checkpoint when the specified amount
+	// of time has elapsed since the last checkpoint.
+	Every time.Duration
+
+	// Store is where checkpoints are written to. A store must not be
+	// shared between concurrently running jobs as a restore would load
+	// another job's state. A valid Store is required for the policy to be
+	// valid.
+	Store CheckpointStore
+}
+
+// Checkpoint returns a snapshot of the current graph state. Checkpoint must
+// not be invoked while a superstep is being executed; the ExecutorCallbacks
+// are a good place for doing so.
+func (g *Graph) Checkpoint() (*Checkpoint, error) {
+	cp := &Checkpoint{
+		Superstep:   g.superstep + 1,
+		Vertices:    make([]CheckpointVertex, 0, len(g.vertices)),
+		Aggregators: make(map[string]interface{}, len(g.aggregators)),
+	}
+	for name, aggr := range g.aggregators {
+		cp.Aggregators[name] = aggr.Get()
+	}
+
+	nextQueue := cp.Superstep % 2
+	for _, v := range g.vertices {
+		cpv := CheckpointVertex{
+			ID:     v.id,
+			Value:  v.value,
+			Active: v.active,
+			Edges:  make([]CheckpointEdge, len(v.edges)),
+		}
+		for i, e := range v.edges {
+			cpv.Edges[i] = CheckpointEdge{DstID: e.dstID, Value: e.value}
+		}
+		msgs, err := snapshotQueue(v.msgQueue[nextQueue])
+		if err != nil {
+			return nil, xerrors.Errorf("checkpoint messages for vertex %q: %w", v.id, err)
+		}
+		cpv.Messages = msgs
+		cp.Vertices = append(cp.Vertices, cpv)
+	}
+	return cp, nil
+}
+
+// RestoreCheckpoint replaces the vertices and edges of the graph with the
+// ones from the provided checkpoint, enqueues any pending messages and sets
+// the values of the registered aggregators. The aggregators contained in
+// the checkpoint must be registered with the graph before calling
+// RestoreCheckpoint.
+//
+// To continue executing supersteps from the restored state, callers should
+// create an Executor via ResumeExecutor.
+func (g *Graph) RestoreCheckpoint(cp *Checkpoint) error {
+	for name := range cp.Aggregators {
+		if g.aggregators[name] == nil {
+			return xerrors.Errorf("restore aggregator %q: %w", name, ErrUnknownAggregator)
+		}
+	}
+	if err := g.closeVertexQueues(); err != nil {
+		return err
+	}
+	g.vertices = make(map[string]*Vertex, len(cp.Vertices))
+
+	ctx := context.Background()
+	nextQueue := cp.Superstep % 2
+	for _, cpv := range cp.Vertices {
+		g.AddVertex(cpv.ID, cpv.Value)
+		v := g.vertices[cpv.ID]
+		v.active = cpv.Active
+		for _, e := range cpv.Edges {
+			v.edges = append(v.edges, &Edge{dstID: e.DstID, value: e.Value})
+		}
+		for _, cpMsg := range cpv.Messages {
+			codec, err := message.CodecFor(cpMsg.Type)
+			if err != nil {
+				return xerrors.Errorf("restore messages for vertex %q: %w", cpv.ID, err)
+			}
+			msg, err := codec.Unmarshal(cpMsg.Data)
+			if err != nil {
+				return xerrors.Errorf("restore messages for vertex %q: %w", cpv.ID, err)
+			}
+			if err = v.msgQueue[nextQueue].Enqueue(ctx, msg); err != nil {
+				return xerrors.Errorf("restore messages for vertex %q: %w", cpv.ID, err)
+			}
+		}
+	}
+	for name, val := range cp.Aggregators {
+		g.aggregators[name].Set(val)
+	}
+	g.superstep = cp.Superstep
+	return nil
+}
+
+// snapshotQueue serializes the messages in q. As reading the messages of a
+// queue consumes them, the messages are enqueued again after being
+// serialized.
+func snapshotQueue(q message.Queue) ([]CheckpointMessage, error) {
+	var (
+		msgs  []message.Message
+		out   []CheckpointMessage
+		msgIt = q.Messages()
+	)
+	for msgIt.Next() {
+		msgs = append(msgs, msgIt.Message())
+	}
+	if err := msgIt.Error(); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	for _, msg := range msgs {
+		if err := q.Enqueue(ctx, msg); err != nil {
+			return nil, err
+		}
+		codec, err := message.CodecFor(msg.Type())
+		if err != nil {
+			return nil, err
+		}
+		data, err := codec.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, CheckpointMessage{Type: msg.Type(), Data: data})
+	}
+	return out, nil
+}
@@ -0,0 +1,73 @@
+package bspgraph
+
+import (
+	"context"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+)
+
+// checkpointFile is the name of the file where FSCheckpointStore keeps the
+// latest checkpoint.
+const checkpointFile = "checkpoint.gob"
+
+// FSCheckpointStore implements a CheckpointStore that persists checkpoints to
+// the local filesystem. A store keeps the latest checkpoint of a single job so
+// each job must use its own directory. Checkpoints are first written to a temporary file
+// which then atomically replaces the previous checkpoint so a crash while
+// writing a checkpoint never corrupts the last good one.
+type FSCheckpointStore struct {
+	dir string
+}
+
+// NewFSCheckpointStore returns a new FSCheckpointStore that keeps its
+// checkpoints in dir. The directory is created if it does not exist.
+func NewFSCheckpointStore(dir string) (*FSCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, xerrors.Errorf("create checkpoint dir: %w", err)
+	}
+	return &FSCheckpointStore{dir: dir}, nil
+}
+
+// Save implements CheckpointStore.
+func (s *FSCheckpointStore) Save(_ context.Context, cp *Checkpoint) error {
+	f, err := os.CreateTemp(s.dir, checkpointFile+".*")
+	if err != nil {
+		return xerrors.Errorf("save checkpoint: %w", err)
+	}
+	tmpName := f.Name()
+	if err = gob.NewEncoder(f).Encode(cp); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, filepath.Join(s.dir, checkpointFile))
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return xerrors.Errorf("save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Latest implements CheckpointStore.
+func (s *FSCheckpointStore) Latest(_ context.Context) (*Checkpoint, error) {
+	f, err := os.Open(filepath.Join(s.dir, checkpointFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, xerrors.Errorf("load checkpoint: %w", ErrNoCheckpoint)
+		}
+		return nil, xerrors.Errorf("load checkpoint: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	cp := new(Checkpoint)
+	if err = gob.NewDecoder(f).Decode(cp); err != nil {
+		return nil, xerrors.Errorf("load checkpoint: %w", err)
+	}
+	return cp, nil
+}
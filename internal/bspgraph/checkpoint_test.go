@@ -0,0 +1,144 @@
+package bspgraph_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/aggregator"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CheckpointTestSuite))
+
+func init() {
+	message.RegisterCodec(sumMessage{}.Type(), message.NewGobCodec(sumMessage{}))
+}
+
+type CheckpointTestSuite struct {
+	dir string
+}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *CheckpointTestSuite) SetUpTest(c *gc.C) {
+	dir, err := ioutil.TempDir("", "bspgraph-checkpoint-test")
+	c.Assert(err, gc.IsNil)
+	s.dir = dir
+}
+
+func (s *CheckpointTestSuite) TearDownTest(c *gc.C) {
+	c.Assert(os.RemoveAll(s.dir), gc.IsNil)
+}
+
+func (s *CheckpointTestSuite) TestResumeFromCheckpoint(c *gc.C) {
+	ctx := context.Background()
+
+	// Run the algorithm without interruptions
+	expG := newSumGraph(c)
+	defer func() { c.Assert(expG.Close(), gc.IsNil) }()
+	err := bspgraph.NewExecutor(expG, sumCallbacks(5, nil)).RunToCompletion(ctx)
+	c.Assert(err, gc.IsNil)
+
+	// Run the algorithm with checkpoints and stop half-way through.
+	store, err := bspgraph.NewFSCheckpointStore(s.dir)
+	c.Assert(err, gc.IsNil)
+	g := newSumGraph(c)
+	cb := sumCallbacks(3, &bspgraph.CheckpointPolicy{EverySteps: 2, Store: store})
+	c.Assert(bspgraph.NewExecutor(g, cb).RunToCompletion(ctx), gc.IsNil)
+	c.Assert(g.Close(), gc.IsNil)
+
+	cp, err := store.Latest(ctx)
+	c.Assert(err, gc.IsNil)
+	c.Assert(cp.Superstep, gc.Equals, 2)
+
+	// Restore the checkpoint to a new graph and resume execution.
+	resumedG, err := bspgraph.NewGraph(bspgraph.GraphConfig{ComputeFn: sumComputeFn})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(resumedG.Close(), gc.IsNil) }()
+	resumedG.RegisterAggregator("messages", new(aggregator.IntAccumulator))
+	c.Assert(resumedG.RestoreCheckpoint(cp), gc.IsNil)
+	c.Assert(resumedG.Superstep(), gc.Equals, 2)
+	err = bspgraph.ResumeExecutor(resumedG, sumCallbacks(5, nil)).RunToCompletion(ctx)
+	c.Assert(err, gc.IsNil)
+
+	for id, v := range expG.Vertices() {
+		c.Assert(resumedG.Vertices()[id].Value(), gc.Equals, v.Value(), gc.Commentf("vertex %s", id))
+	}
+	c.Assert(resumedG.Aggregator("messages").Get(), gc.Equals, expG.Aggregator("messages").Get())
+}
+
+func (s *CheckpointTestSuite) TestRestoreWithUnknownAggregator(c *gc.C) {
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{ComputeFn: sumComputeFn})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	err = g.RestoreCheckpoint(&bspgraph.Checkpoint{
+		Aggregators: map[string]interface{}{"missing": 1},
+	})
+	c.Assert(xerrors.Is(err, bspgraph.ErrUnknownAggregator), gc.Equals, true)
+}
+
+func (s *CheckpointTestSuite) TestLatestWithoutCheckpoint(c *gc.C) {
+	store, err := bspgraph.NewFSCheckpointStore(s.dir)
+	c.Assert(err, gc.IsNil)
+	_, err = store.Latest(context.Background())
+	c.Assert(xerrors.Is(err, bspgraph.ErrNoCheckpoint), gc.Equals, true)
+}
+
+func (s *CheckpointTestSuite) TestPolicyWithoutStore(c *gc.C) {
+	g := newSumGraph(c)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	err := bspgraph.NewExecutor(g, sumCallbacks(3, &bspgraph.CheckpointPolicy{EverySteps: 1})).RunToCompletion(context.Background())
+	c.Assert(err, gc.ErrorMatches, "checkpoint policy does not specify a store")
+	c.Assert(g.Superstep(), gc.Equals, 0, gc.Commentf("no superstep should run with an invalid checkpoint policy"))
+}
+
+type sumMessage struct {
+	Value int
+}
+
+func (sumMessage) Type() string { return "sum" }
+
+// newSumGraph returns a graph where each vertex adds the values it receives
+// to its own value and forwards the result to its neighbors.
+func newSumGraph(c *gc.C) *bspgraph.Graph {
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{ComputeFn: sumComputeFn, ComputeWorkers: 2})
+	c.Assert(err, gc.IsNil)
+	g.RegisterAggregator("messages", new(aggregator.IntAccumulator))
+	for i, id := range []string{"a", "b", "c", "d"} {
+		g.AddVertex(id, i+1)
+	}
+	for _, e := range [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}, {"c", "d"}, {"d", "a"}} {
+		c.Assert(g.AddEdge(e[0], e[1], nil), gc.IsNil)
+	}
+	return g
+}
+
+func sumComputeFn(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+	sum := v.Value().(int)
+	for msgIt.Next() {
+		sum += msgIt.Message().(sumMessage).Value
+		g.Aggregator("messages").Aggregate(1)
+	}
+	v.SetValue(sum % 1000)
+	return g.BroadcastToNeighbors(context.Background(), v, sumMessage{Value: v.Value().(int)})
+}
+
+// sumCallbacks returns executor callbacks that stop after executing the
+// specified superstep.
+func sumCallbacks(lastStep int, policy *bspgraph.CheckpointPolicy) bspgraph.ExecutorCallbacks {
+	return bspgraph.ExecutorCallbacks{
+		PostStepKeepRunning: func(_ context.Context, g *bspgraph.Graph, _ int) (bool, error) {
+			return g.Superstep() < lastStep, nil
+		},
+		Checkpoint: policy,
+	}
+}
@@ -0,0 +1,135 @@
+package bspgraph_test
+
+import (
+	"context"
+	"sync"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CombinerTestSuite))
+
+type CombinerTestSuite struct{}
+
+func (s *CombinerTestSuite) TestCombineLocalAndRemoteMessages(c *gc.C) {
+	var (
+		received []int
+		mu       sync.Mutex
+	)
+	computeFn := func(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+		if g.Superstep() == 0 {
+			if v.ID() != "src" {
+				return nil
+			}
+			for i := 1; i <= 4; i++ {
+				if err := g.BroadcastToNeighbors(context.Background(), v, sumMessage{Value: i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for msgIt.Next() {
+			mu.Lock()
+			received = append(received, msgIt.Message().(sumMessage).Value)
+			mu.Unlock()
+		}
+		return nil
+	}
+
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn:      computeFn,
+		ComputeWorkers: 2,
+		Combiner: func(a, b message.Message) message.Message {
+			return sumMessage{Value: a.(sumMessage).Value + b.(sumMessage).Value}
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	relayer := &recordingRelayer{msgs: make(map[string][]message.Message)}
+	g.RegisterRelayer(relayer)
+	g.AddVertex("src", nil)
+	g.AddVertex("dst", nil)
+	c.Assert(g.AddEdge("src", "dst", nil), gc.IsNil)
+	c.Assert(g.AddEdge("src", "remote", nil), gc.IsNil)
+
+	cb := bspgraph.ExecutorCallbacks{
+		PostStepKeepRunning: func(_ context.Context, g *bspgraph.Graph, _ int) (bool, error) {
+			return g.Superstep() < 1, nil
+		},
+	}
+	c.Assert(bspgraph.NewExecutor(g, cb).RunToCompletion(context.Background()), gc.IsNil)
+
+	c.Assert(received, gc.DeepEquals, []int{10})
+	c.Assert(relayer.msgs["remote"], gc.DeepEquals, []message.Message{sumMessage{Value: 10}})
+}
+
+func (s *CombinerTestSuite) TestCombinerWithQueueFactory(c *gc.C) {
+	var (
+		received  []int
+		numQueues int
+		mu        sync.Mutex
+	)
+	computeFn := func(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+		if g.Superstep() == 0 {
+			for i := 1; i <= 4; i++ {
+				if err := g.BroadcastToNeighbors(context.Background(), v, sumMessage{Value: i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for msgIt.Next() {
+			mu.Lock()
+			received = append(received, msgIt.Message().(sumMessage).Value)
+			mu.Unlock()
+		}
+		return msgIt.Error()
+	}
+
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn: computeFn,
+		QueueFactory: func() message.Queue {
+			mu.Lock()
+			numQueues++
+			mu.Unlock()
+			return message.NewInMemoryQueue()
+		},
+		Combiner: func(a, b message.Message) message.Message {
+			return sumMessage{Value: a.(sumMessage).Value + b.(sumMessage).Value}
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	g.AddVertex("src", nil)
+	g.AddVertex("dst", nil)
+	c.Assert(g.AddEdge("src", "dst", nil), gc.IsNil)
+
+	cb := bspgraph.ExecutorCallbacks{
+		PostStepKeepRunning: func(_ context.Context, g *bspgraph.Graph, _ int) (bool, error) {
+			return g.Superstep() < 1, nil
+		},
+	}
+	c.Assert(bspgraph.NewExecutor(g, cb).RunToCompletion(context.Background()), gc.IsNil)
+
+	c.Assert(received, gc.DeepEquals, []int{10})
+	c.Assert(numQueues > 0, gc.Equals, true, gc.Commentf("expected queues to be created by the factory"))
+}
+
+type recordingRelayer struct {
+	mu   sync.Mutex
+	msgs map[string][]message.Message
+}
+
+func (r *recordingRelayer) Relay(_ context.Context, dst string, msg message.Message) error {
+	if dst != "remote" {
+		return bspgraph.ErrDestinationIsLocal
+	}
+	r.mu.Lock()
+	r.msgs[dst] = append(r.msgs[dst], msg)
+	r.mu.Unlock()
+	return nil
+}
@@ -22,15 +22,43 @@ type GraphConfig struct {
 	// the registered ComputeFunc when executing each superstep. If not
 	// specified, a single worker will be used.
 	ComputeWorkers int
+
+	// Combiner, if specified, is used to fold all messages that are sent
+	// to the same vertex within a superstep into a single message. Local
+	// messages are combined when they are enqueued, so each vertex keeps
+	// at most one pending message. Messages for remote vertices are
+	// combined per destination and handed to the Relayer at the end of
+	// each superstep. If a QueueFactory is also specified, the combined
+	// local messages are stored in the queues that it creates.
+	Combiner message.Combiner
+
+	// MetricsSink, if specified, receives the statistics that the graph
+	// collects after each superstep.
+	MetricsSink MetricsSink
+
+	// SlowestVertices specifies how many of the slowest vertices to track
+	// in the statistics for each superstep. If not specified, the 5
+	// slowest vertices will be tracked. A negative value disables the
+	// tracking.
+	SlowestVertices int
 }
 
 // validate checks whether a graph configuration is valid and sets the default
 // values where required.
 func (g *GraphConfig) validate() error {
 	var err error
-	if g.QueueFactory == nil {
+	if g.Combiner != nil && g.QueueFactory != nil {
+		combiner, factory := g.Combiner, g.QueueFactory
+		g.QueueFactory = func() message.Queue { return message.WrapWithCombiner(factory(), combiner) }
+	} else if g.Combiner != nil {
+		combiner := g.Combiner
+		g.QueueFactory = func() message.Queue { return message.NewCombiningQueue(combiner) }
+	} else if g.QueueFactory == nil {
 		g.QueueFactory = message.NewInMemoryQueue
 	}
+	if g.SlowestVertices == 0 {
+		g.SlowestVertices = 5
+	}
 	if g.ComputeWorkers == 0 {
 		g.ComputeWorkers = 1
 	}
@@ -1,6 +1,11 @@
 package bspgraph
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+)
 
 // ExecutorCallbacks encapsulates a series of callbacks that are invoked by an
 // Executor instance on a graph. All callbacks are optional and will be ignored
@@ -19,6 +24,10 @@ type ExecutorCallbacks struct {
 	// been met. The number of the active vertices in the last step is
 	// passed as the second argument.
 	PostStepKeepRunning func(ctx context.Context, g *Graph, activeInStep int) (bool, error)
+
+	// Checkpoint, if defined, configures the executor to periodically
+	// write a checkpoint of the graph state after running a superstep.
+	Checkpoint *CheckpointPolicy
 }
 
 // Executor wraps a Graph instance and provides an orchestration layer for
@@ -28,16 +37,29 @@ type ExecutorCallbacks struct {
 type Executor struct {
 	g  *Graph
 	cb ExecutorCallbacks
+
+	lastCheckpointStep int
+	lastCheckpointAt   time.Time
 }
 
 // NewExecutor returns an Executor instance for graph g that invokes the
 // provided list of callbacks inside each execution loop.
 func NewExecutor(g *Graph, cb ExecutorCallbacks) *Executor {
-	patchEmptyCallbacks(&cb)
 	g.superstep = 0
+	return ResumeExecutor(g, cb)
+}
+
+// ResumeExecutor returns an Executor instance for graph g that continues
+// executing supersteps from the current superstep of the graph instead of
+// starting from superstep 0. It is typically used after restoring the graph
+// state via RestoreCheckpoint.
+func ResumeExecutor(g *Graph, cb ExecutorCallbacks) *Executor {
+	patchEmptyCallbacks(&cb)
 	return &Executor{
-		g:  g,
-		cb: cb,
+		g:                  g,
+		cb:                 cb,
+		lastCheckpointStep: g.superstep,
+		lastCheckpointAt:   time.Now(),
 	}
 }
 
@@ -56,23 +78,54 @@ func (ex *Executor) run(ctx context.Context, maxSteps int) error {
 		keepRunning  bool
 		cb           = ex.cb
 	)
+	if cb.Checkpoint != nil && cb.Checkpoint.Store == nil {
+		return xerrors.New("checkpoint policy does not specify a store")
+	}
 	for ; maxSteps != 0; ex.g.superstep, maxSteps = ex.g.superstep+1, maxSteps-1 {
 		if err = ensureContextnotExpired(ctx); err != nil {
 			break
 		} else if err = cb.PreStep(ctx, ex.g); err != nil {
 			break
-		} else if activeInStep, err = ex.g.step(); err != nil {
+		} else if activeInStep, err = ex.g.step(ctx); err != nil {
 			break
 		} else if err = cb.PostStep(ctx, ex.g, activeInStep); err != nil {
 			break
 		} else if keepRunning, err = cb.PostStepKeepRunning(ctx, ex.g, activeInStep); !keepRunning || err != nil {
 			break
+		} else if err = ex.maybeCheckpoint(ctx); err != nil {
+			break
 		}
 	}
 
 	return err
 }
 
+// maybeCheckpoint writes a checkpoint of the graph state if required by the
+// configured checkpoint policy.
+func (ex *Executor) maybeCheckpoint(ctx context.Context) error {
+	policy := ex.cb.Checkpoint
+	if policy == nil {
+		return nil
+	}
+	nextStep := ex.g.superstep + 1
+	dueToSteps := policy.EverySteps > 0 && nextStep-ex.lastCheckpointStep >= policy.EverySteps
+	dueToTime := policy.Every > 0 && time.Since(ex.lastCheckpointAt) >= policy.Every
+	if !dueToSteps && !dueToTime {
+		return nil
+	}
+
+	cp, err := ex.g.Checkpoint()
+	if err != nil {
+		return xerrors.Errorf("checkpoint after superstep %d: %w", ex.g.superstep, err)
+	}
+	if err = policy.Store.Save(ctx, cp); err != nil {
+		return xerrors.Errorf("checkpoint after superstep %d: %w", ex.g.superstep, err)
+	}
+	ex.lastCheckpointStep = nextStep
+	ex.lastCheckpointAt = time.Now()
+	return nil
+}
+
 // ensureContextnotExpired private method for expiration context
 func ensureContextnotExpired(ctx context.Context) error {
 	select {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/joshvoll/linkrus/internal/bspgraph/message"
 	"golang.org/x/xerrors"
@@ -14,6 +15,10 @@ var (
 	// is not present in the graph.
 	ErrUnknownEdgeSource = xerrors.New("source vertex is not part of the graph")
 
+	// ErrVertexExists is returned when a mutation requests the insertion
+	// of a vertex that is already part of the graph.
+	ErrVertexExists = xerrors.New("vertex is already part of the graph")
+
 	// ErrDestinationIsLocal returnet by the SendMessage
 	ErrDestinationIsLocal = xerrors.New("Destination is loca")
 
@@ -96,6 +101,17 @@ type Graph struct {
 	queueFactory message.QueueFactory
 	relayer      Relayer
 
+	combiner      message.Combiner
+	relayMu       sync.Mutex
+	pendingRelays map[string]message.Message
+
+	mutationMu       sync.Mutex
+	pendingMutations []mutation
+
+	stats       *statsCollector
+	metricsSink MetricsSink
+	numWorkers  int
+
 	wg              sync.WaitGroup
 	vertexCh        chan *Vertex
 	errCh           chan error
@@ -114,6 +130,9 @@ func NewGraph(cfg GraphConfig) (*Graph, error) {
 	g := &Graph{
 		computeFn:    cfg.ComputeFn,
 		queueFactory: cfg.QueueFactory,
+		combiner:     cfg.Combiner,
+		stats:        &statsCollector{maxSlowest: cfg.SlowestVertices},
+		metricsSink:  cfg.MetricsSink,
 		aggregators:  make(map[string]Aggregator),
 		vertices:     make(map[string]*Vertex),
 	}
@@ -132,6 +151,18 @@ func (g *Graph) Close() error {
 // aggregators and resetting the superstep counter.
 func (g *Graph) Reset() error {
 	g.superstep = 0
+	g.discardMutations()
+	g.stats.reset()
+	if err := g.closeVertexQueues(); err != nil {
+		return err
+	}
+	g.vertices = make(map[string]*Vertex)
+	g.aggregators = make(map[string]Aggregator)
+	return nil
+}
+
+// closeVertexQueues closes the message queues of all graph vertices.
+func (g *Graph) closeVertexQueues() error {
 	for _, v := range g.vertices {
 		for i := 0; i < 2; i++ {
 			if err := v.msgQueue[i].Close(); err != nil {
@@ -139,8 +170,6 @@ func (g *Graph) Reset() error {
 			}
 		}
 	}
-	g.vertices = make(map[string]*Vertex)
-	g.aggregators = make(map[string]Aggregator)
 	return nil
 }
 
@@ -152,7 +181,7 @@ func (g *Graph) Vertices() map[string]*Vertex {
 // AddVertex inserts a new vertex with the specified id and initial value into
 // the graph. If the vertex already exists, AddVertex will just overwrite its
 // value with the provided initValue.
-func (g *Graph) addVertex(id string, initValue interface{}) {
+func (g *Graph) AddVertex(id string, initValue interface{}) {
 	v := g.vertices[id]
 	if v == nil {
 		v = &Vertex{
@@ -184,6 +213,50 @@ func (g *Graph) AddEdge(srcID, dstID string, initValue interface{}) error {
 	return nil
 }
 
+// RemoveVertex removes the vertex with the specified id from the graph and
+// releases its message queues. Edges that point to the removed vertex are not
+// affected. Removing a vertex that does not exist is a no-op.
+//
+// RemoveVertex must not be called while a superstep is executing; compute
+// functions should use RequestRemoveVertex instead.
+func (g *Graph) RemoveVertex(id string) error {
+	v := g.vertices[id]
+	if v == nil {
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := v.msgQueue[i].Close(); err != nil {
+			return xerrors.Errorf("closing message queue #%d for vertex %v: %w", i, id, err)
+		}
+	}
+	delete(g.vertices, id)
+	return nil
+}
+
+// RemoveEdge removes all directed edges from srcID to dstID. As edges are
+// owned by their source vertices, srcID must resolve to a local vertex.
+// Otherwise, RemoveEdge returns an error.
+//
+// RemoveEdge must not be called while a superstep is executing; compute
+// functions should use RequestRemoveEdge instead.
+func (g *Graph) RemoveEdge(srcID, dstID string) error {
+	srcVert := g.vertices[srcID]
+	if srcVert == nil {
+		return xerrors.Errorf("remove edge from %q to %q: %w", srcID, dstID, ErrUnknownEdgeSource)
+	}
+	edges := srcVert.edges[:0]
+	for _, e := range srcVert.edges {
+		if e.dstID != dstID {
+			edges = append(edges, e)
+		}
+	}
+	for i := len(edges); i < len(srcVert.edges); i++ {
+		srcVert.edges[i] = nil
+	}
+	srcVert.edges = edges
+	return nil
+}
+
 // RegisterAggregator adds an aggregator with the specified name into the graph.
 func (g *Graph) RegisterAggregator(name string, aggr Aggregator) {
 	g.aggregators[name] = aggr
@@ -234,20 +307,94 @@ func (g *Graph) BroadcastToNeighbors(ctx context.Context, v *Vertex, msg message
 // first check whether an UnknownVertexHandler has been provided at
 // configuration time and invoke it. Otherwise, an ErrInvalidMessageDestination
 // is returned to the caller.
+//
+// If a Combiner has been configured, messages for remote vertices are combined
+// per destination and relayed at the end of the current superstep. In that
+// case, invalid destinations are reported as an error by the superstep.
 func (g *Graph) SendMessage(ctx context.Context, dstID string, msg message.Message) error {
 	dstVert := g.vertices[dstID]
 	if dstVert != nil {
 		queueIndex := (g.superstep + 1) % 2
-		return dstVert.msgQueue[queueIndex].Enqueue(ctx, msg)
+		if err := dstVert.msgQueue[queueIndex].Enqueue(ctx, msg); err != nil {
+			return err
+		}
+		atomic.AddInt64(&g.stats.messagesSent, 1)
+		atomic.AddInt64(&g.stats.messagesReceived, 1)
+		return nil
 	}
 	if g.relayer != nil {
-		if err := g.relayer.Relay(ctx, dstID, msg); !xerrors.Is(err, ErrDestinationIsLocal) {
+		if g.combiner != nil {
+			g.bufferRelay(dstID, msg)
+			atomic.AddInt64(&g.stats.messagesSent, 1)
+			return nil
+		}
+		err := g.relayer.Relay(ctx, dstID, msg)
+		if err == nil {
+			atomic.AddInt64(&g.stats.messagesSent, 1)
+			atomic.AddInt64(&g.stats.messagesRelayed, 1)
+			return nil
+		} else if !xerrors.Is(err, ErrDestinationIsLocal) {
+			atomic.AddInt64(&g.stats.relayFailures, 1)
 			return err
 		}
 	}
 	return xerrors.Errorf("message cannot be delivered to %q: %w ", dstID, ErrInvalidMessageDestination)
 }
 
+// DeliverMessage enqueues a message that was relayed by a remote graph
+// instance for the local vertex dstID. Delivered messages count towards the
+// received but not the sent messages of the local graph as they were sent
+// by the remote graph. DeliverMessage returns an ErrInvalidMessageDestination
+// error if dstID is not a local vertex.
+func (g *Graph) DeliverMessage(ctx context.Context, dstID string, msg message.Message) error {
+	dstVert := g.vertices[dstID]
+	if dstVert == nil {
+		return xerrors.Errorf("message cannot be delivered to %q: %w", dstID, ErrInvalidMessageDestination)
+	}
+	queueIndex := (g.superstep + 1) % 2
+	if err := dstVert.msgQueue[queueIndex].Enqueue(ctx, msg); err != nil {
+		return err
+	}
+	atomic.AddInt64(&g.stats.messagesReceived, 1)
+	return nil
+}
+
+// bufferRelay combines msg with any message that is already pending for
+// delivery to the remote vertex dstID.
+func (g *Graph) bufferRelay(dstID string, msg message.Message) {
+	g.relayMu.Lock()
+	if g.pendingRelays == nil {
+		g.pendingRelays = make(map[string]message.Message)
+	}
+	if pending, exists := g.pendingRelays[dstID]; exists {
+		msg = g.combiner(pending, msg)
+	}
+	g.pendingRelays[dstID] = msg
+	g.relayMu.Unlock()
+}
+
+// flushRelays hands the combined messages for remote vertices to the
+// configured Relayer.
+func (g *Graph) flushRelays(ctx context.Context) error {
+	g.relayMu.Lock()
+	pending := g.pendingRelays
+	g.pendingRelays = nil
+	g.relayMu.Unlock()
+
+	for dstID, msg := range pending {
+		if err := g.relayer.Relay(ctx, dstID, msg); err != nil {
+			if xerrors.Is(err, ErrDestinationIsLocal) {
+				err = xerrors.Errorf("message cannot be delivered to %q: %w ", dstID, ErrInvalidMessageDestination)
+			} else {
+				atomic.AddInt64(&g.stats.relayFailures, 1)
+			}
+			return err
+		}
+		atomic.AddInt64(&g.stats.messagesRelayed, 1)
+	}
+	return nil
+}
+
 // Superstep returns the current superstep value.
 func (g *Graph) Superstep() int {
 	return g.superstep
@@ -255,8 +402,20 @@ func (g *Graph) Superstep() int {
 
 // Step executes the next superstep and returns back the number of vertices
 // that were processed either because they were still active or because they
-// received a message.
-func (g *Graph) step() (int, error) {
+// received a message. Any graph mutations requested while executing the
+// superstep are applied once all vertices have been processed.
+//
+// Once the superstep completes, its statistics are made available via Stats
+// and forwarded to the configured MetricsSink.
+func (g *Graph) step(ctx context.Context) (activeInStep int, err error) {
+	startedAt := time.Now()
+	defer func() {
+		stats := g.stats.collect(g.superstep, activeInStep, g.numWorkers, time.Since(startedAt))
+		if g.metricsSink != nil {
+			g.metricsSink.RecordStep(stats)
+		}
+	}()
+
 	g.activeInStep = 0
 	g.pendingInStep = int64(len(g.vertices))
 	if g.pendingInStep == 0 {
@@ -266,11 +425,18 @@ func (g *Graph) step() (int, error) {
 		g.vertexCh <- v
 	}
 	<-g.stepCompletedCh
-	var err error
 	select {
 	case err = <-g.errCh:
 	default:
 	}
+	if flushErr := g.flushRelays(ctx); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		g.discardMutations()
+	} else {
+		err = g.applyMutations()
+	}
 	return int(g.activeInStep), err
 }
 
@@ -278,8 +444,9 @@ func (g *Graph) step() (int, error) {
 // execute each superstep.
 func (g *Graph) startWorkers(numWorkers int) {
 	g.vertexCh = make(chan *Vertex)
-	g.errCh = make(chan error)
+	g.errCh = make(chan error, 1)
 	g.stepCompletedCh = make(chan struct{})
+	g.numWorkers = numWorkers
 
 	g.wg.Add(numWorkers)
 	for i := 0; i < numWorkers; i++ {
@@ -297,7 +464,10 @@ func (g *Graph) stepWorker() {
 		if v.active || v.msgQueue[buffer].PendingMessages() {
 			_ = atomic.AddInt64(&g.activeInStep, 1)
 			v.active = true
-			if err := g.computeFn(g, v, v.msgQueue[buffer].Messages()); err != nil {
+			startedAt := time.Now()
+			err := g.computeFn(g, v, v.msgQueue[buffer].Messages())
+			g.stats.recordCompute(v.ID(), time.Since(startedAt))
+			if err != nil {
 				tryEmitError(g.errCh, xerrors.Errorf("running compute function for vertex %q failed: %w", v.ID(), err))
 			} else if err := v.msgQueue[buffer].DiscardMessages(); err != nil {
 				tryEmitError(g.errCh, xerrors.Errorf("discarding unprocessed messages for vertex %q failed: %w", v.ID(), err))
@@ -0,0 +1,79 @@
+package message
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrUnknownMessageType is returned when looking up the codec for a message
+// type that has not been registered.
+var ErrUnknownMessageType = xerrors.New("no codec registered for message type")
+
+var (
+	codecMu  sync.RWMutex
+	codecMap = make(map[string]Codec)
+)
+
+// Codec is implemented by types that can serialize and unserialize messages
+// of a particular type.
+type Codec interface {
+	// Marshal serializes msg into a byte slice.
+	Marshal(msg Message) ([]byte, error)
+
+	// Unmarshal creates a message instance from the serialized data.
+	Unmarshal(data []byte) (Message, error)
+}
+
+// RegisterCodec associates a codec with the specified message type as
+// returned by Message.Type(). Registering a codec for a type that already
+// has one replaces the previous codec.
+func RegisterCodec(msgType string, codec Codec) {
+	codecMu.Lock()
+	codecMap[msgType] = codec
+	codecMu.Unlock()
+}
+
+// CodecFor returns the codec that is registered for the specified message
+// type or ErrUnknownMessageType if no codec has been registered.
+func CodecFor(msgType string) (Codec, error) {
+	codecMu.RLock()
+	codec, found := codecMap[msgType]
+	codecMu.RUnlock()
+	if !found {
+		return nil, xerrors.Errorf("codec for %q: %w", msgType, ErrUnknownMessageType)
+	}
+	return codec, nil
+}
+
+// gobCodec implements Codec using encoding/gob.
+type gobCodec struct {
+	msgType reflect.Type
+}
+
+// NewGobCodec returns a Codec that uses encoding/gob to serialize messages
+// with the same concrete type as prototype.
+func NewGobCodec(prototype Message) Codec {
+	return gobCodec{msgType: reflect.TypeOf(prototype)}
+}
+
+// Marshal implements Codec.
+func (c gobCodec) Marshal(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(msg); err != nil {
+		return nil, xerrors.Errorf("marshal message: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements Codec.
+func (c gobCodec) Unmarshal(data []byte) (Message, error) {
+	ptr := reflect.New(c.msgType)
+	if err := gob.NewDecoder(bytes.NewReader(data)).DecodeValue(ptr); err != nil {
+		return nil, xerrors.Errorf("unmarshal message: %w", err)
+	}
+	return ptr.Elem().Interface().(Message), nil
+}
@@ -0,0 +1,47 @@
+package message_test
+
+import (
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(codecTest))
+
+type codecTest struct{}
+
+type codecMsg struct {
+	Payload string
+	Score   float64
+}
+
+func (codecMsg) Type() string {
+	return "codec_test"
+}
+
+func (s *codecTest) TestGobCodecRoundTrip(c *gc.C) {
+	message.RegisterCodec(codecMsg{}.Type(), message.NewGobCodec(codecMsg{}))
+	codec, err := message.CodecFor(codecMsg{}.Type())
+	c.Assert(err, gc.IsNil)
+
+	orig := codecMsg{Payload: "hello", Score: 0.42}
+	data, err := codec.Marshal(orig)
+	c.Assert(err, gc.IsNil)
+	got, err := codec.Unmarshal(data)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.DeepEquals, orig)
+}
+
+func (s *codecTest) TestGobCodecWithPointerMessages(c *gc.C) {
+	codec := message.NewGobCodec(&codecMsg{})
+	data, err := codec.Marshal(&codecMsg{Payload: "ptr"})
+	c.Assert(err, gc.IsNil)
+	got, err := codec.Unmarshal(data)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.DeepEquals, &codecMsg{Payload: "ptr"})
+}
+
+func (s *codecTest) TestUnknownMessageType(c *gc.C) {
+	_, err := message.CodecFor("not-registered")
+	c.Assert(xerrors.Is(err, message.ErrUnknownMessageType), gc.Equals, true)
+}
@@ -0,0 +1,123 @@
+package message
+
+import (
+	"context"
+	"sync"
+)
+
+// combiningQueue implements a queue that folds all enqueued messages into a
+// single message using a Combiner. Messages can be enqueued concurrently but
+// the returned iterator is not safe for concurrent access.
+//
+// If the queue wraps another queue, the combined message is moved to the
+// wrapped queue when the queued messages are requested.
+type combiningQueue struct {
+	mu         sync.Mutex
+	combine    Combiner
+	next       Queue
+	pending    Message
+	latchedMsg Message
+}
+
+// NewCombiningQueue creates a new queue that uses combine to fold all
+// enqueued messages into a single message.
+func NewCombiningQueue(combine Combiner) Queue {
+	return &combiningQueue{combine: combine}
+}
+
+// WrapWithCombiner returns a queue that uses combine to fold all enqueued
+// messages into a single message before handing it off to q.
+func WrapWithCombiner(q Queue, combine Combiner) Queue {
+	return &combiningQueue{combine: combine, next: q}
+}
+
+// Close implements Queue.
+func (q *combiningQueue) Close() error {
+	if q.next != nil {
+		return q.next.Close()
+	}
+	return nil
+}
+
+// Enqueue implements Queue.
+func (q *combiningQueue) Enqueue(_ context.Context, msg Message) error {
+	q.mu.Lock()
+	if q.pending == nil {
+		q.pending = msg
+	} else {
+		q.pending = q.combine(q.pending, msg)
+	}
+	q.mu.Unlock()
+	return nil
+}
+
+// PendingMessages implements Queue.
+func (q *combiningQueue) PendingMessages() bool {
+	q.mu.Lock()
+	pending := q.pending != nil
+	q.mu.Unlock()
+	return pending || (q.next != nil && q.next.PendingMessages())
+}
+
+// DiscardMessages implements Queue.
+func (q *combiningQueue) DiscardMessages() error {
+	q.mu.Lock()
+	q.pending = nil
+	q.latchedMsg = nil
+	q.mu.Unlock()
+	if q.next != nil {
+		return q.next.DiscardMessages()
+	}
+	return nil
+}
+
+// Messages implements Queue.
+func (q *combiningQueue) Messages() Iterator {
+	if q.next == nil {
+		return q
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending != nil {
+		if err := q.next.Enqueue(context.Background(), q.pending); err != nil {
+			return errIterator{err: err}
+		}
+		q.pending = nil
+	}
+	return q.next.Messages()
+}
+
+// Next implements Iterator.
+func (q *combiningQueue) Next() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending == nil {
+		return false
+	}
+	q.latchedMsg = q.pending
+	q.pending = nil
+	return true
+}
+
+// Message implements Iterator.
+func (q *combiningQueue) Message() Message {
+	q.mu.Lock()
+	msg := q.latchedMsg
+	q.mu.Unlock()
+	return msg
+}
+
+// Error implements Iterator.
+func (q *combiningQueue) Error() error {
+	return nil
+}
+
+// errIterator is an Iterator that yields no messages and reports err.
+type errIterator struct {
+	err error
+}
+
+func (errIterator) Next() bool       { return false }
+func (errIterator) Message() Message { return nil }
+func (it errIterator) Error() error  { return it.err }
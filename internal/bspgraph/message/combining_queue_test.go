@@ -0,0 +1,75 @@
+package message_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(combiningQueueTest))
+
+type combiningQueueTest struct {
+	q message.Queue
+}
+
+func (s *combiningQueueTest) SetUpTest(c *gc.C) {
+	s.q = message.NewCombiningQueue(joinPayloads)
+}
+
+func (s *combiningQueueTest) TearDownTest(c *gc.C) {
+	c.Assert(s.q.Close(), gc.IsNil)
+}
+
+func (s *combiningQueueTest) TestEnqueueCombinesMessages(c *gc.C) {
+	ctx := context.Background()
+	c.Assert(s.q.PendingMessages(), gc.Equals, false)
+	for _, p := range []string{"a", "b"} {
+		c.Assert(s.q.Enqueue(ctx, msg{payload: p}), gc.IsNil)
+	}
+	c.Assert(s.q.PendingMessages(), gc.Equals, true)
+
+	it := s.q.Messages()
+	c.Assert(it.Next(), gc.Equals, true)
+	c.Assert(it.Message().(msg).payload, gc.Equals, "a,b")
+	c.Assert(it.Next(), gc.Equals, false)
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(s.q.PendingMessages(), gc.Equals, false)
+}
+
+func (s *combiningQueueTest) TestWrappedQueue(c *gc.C) {
+	ctx := context.Background()
+	q := message.WrapWithCombiner(message.NewInMemoryQueue(), joinPayloads)
+	defer func() { c.Assert(q.Close(), gc.IsNil) }()
+
+	for _, p := range []string{"b", "a"} {
+		c.Assert(q.Enqueue(ctx, msg{payload: p}), gc.IsNil)
+	}
+	c.Assert(q.PendingMessages(), gc.Equals, true)
+
+	it := q.Messages()
+	c.Assert(it.Next(), gc.Equals, true)
+	c.Assert(it.Message().(msg).payload, gc.Equals, "a,b")
+	c.Assert(it.Next(), gc.Equals, false)
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(q.PendingMessages(), gc.Equals, false)
+
+	c.Assert(q.Enqueue(ctx, msg{payload: "c"}), gc.IsNil)
+	c.Assert(q.DiscardMessages(), gc.IsNil)
+	c.Assert(q.PendingMessages(), gc.Equals, false)
+}
+
+func (s *combiningQueueTest) TestDiscard(c *gc.C) {
+	c.Assert(s.q.Enqueue(context.Background(), msg{payload: "a"}), gc.IsNil)
+	c.Assert(s.q.DiscardMessages(), gc.IsNil)
+	c.Assert(s.q.PendingMessages(), gc.Equals, false)
+}
+
+func joinPayloads(a, b message.Message) message.Message {
+	payloads := []string{a.(msg).payload, b.(msg).payload}
+	if payloads[0] > payloads[1] {
+		payloads[0], payloads[1] = payloads[1], payloads[0]
+	}
+	return msg{payload: strings.Join(payloads, ",")}
+}
@@ -41,3 +41,8 @@ type Iterator interface {
 
 // QueueFactory is a function that can create new Queue instances.
 type QueueFactory func() Queue
+
+// Combiner is a function that folds two messages addressed to the same vertex
+// into a single message. Combiners must be commutative and associative as
+// messages can be combined in any order.
+type Combiner func(a, b Message) Message
@@ -0,0 +1,308 @@
+package message
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// SpillingQueueConfig encapsulates the configuration options for creating
+// spilling queues.
+type SpillingQueueConfig struct {
+	// Dir is the directory where segment files are created. If not
+	// specified, the default directory for temporary files will be used.
+	Dir string
+
+	// MaxInMemoryMessages is the number of messages that are buffered in
+	// memory before the queue starts spilling messages to disk. If not
+	// specified, a default value of 1024 will be used.
+	MaxInMemoryMessages int
+
+	// MaxSegmentMessages is the maximum number of messages stored in each
+	// segment file. If not specified, a default value of 65536 will be
+	// used.
+	MaxSegmentMessages int
+}
+
+// spillingQueue implements a queue that buffers messages in memory up to a
+// threshold and then spills them to segment files on local disk. Messages are
+// serialized using the codec that is registered for their type via
+// RegisterCodec. Messages can be enqueued concurrently but the returned
+// iterator is not safe for concurrent access.
+type spillingQueue struct {
+	cfg SpillingQueueConfig
+
+	mu   sync.Mutex
+	msgs []Message
+
+	// segments contains the paths of the segment files that are ready to
+	// be read.
+	segments []string
+
+	// writeSeg is the segment file that spilled messages are currently
+	// appended to.
+	writeSeg      *os.File
+	writeBuf      *bufio.Writer
+	writeSegCount int
+	spilled       int
+
+	// readSeg is the segment file that is currently being iterated.
+	readSeg *os.File
+	readBuf *bufio.Reader
+
+	latchedMsg Message
+	lastErr    error
+}
+
+// NewSpillingQueueFactory returns a QueueFactory that creates queues which
+// spill messages to disk once the number of buffered messages exceeds the
+// configured threshold.
+func NewSpillingQueueFactory(cfg SpillingQueueConfig) QueueFactory {
+	if cfg.Dir == "" {
+		cfg.Dir = os.TempDir()
+	}
+	if cfg.MaxInMemoryMessages <= 0 {
+		cfg.MaxInMemoryMessages = 1024
+	}
+	if cfg.MaxSegmentMessages <= 0 {
+		cfg.MaxSegmentMessages = 65536
+	}
+	return func() Queue {
+		return &spillingQueue{cfg: cfg}
+	}
+}
+
+// Close implements Queue.
+func (q *spillingQueue) Close() error {
+	return q.DiscardMessages()
+}
+
+// Enqueue implements Queue.
+func (q *spillingQueue) Enqueue(_ context.Context, msg Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.msgs) < q.cfg.MaxInMemoryMessages && q.spilled == 0 {
+		q.msgs = append(q.msgs, msg)
+		return nil
+	}
+	if err := q.spill(msg); err != nil {
+		return xerrors.Errorf("spill message: %w", err)
+	}
+	return nil
+}
+
+// PendingMessages implements Queue.
+func (q *spillingQueue) PendingMessages() bool {
+	q.mu.Lock()
+	pending := len(q.msgs) != 0 || q.spilled != 0
+	q.mu.Unlock()
+	return pending
+}
+
+// DiscardMessages implements Queue.
+func (q *spillingQueue) DiscardMessages() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.msgs = q.msgs[:0]
+	q.latchedMsg = nil
+	q.lastErr = nil
+	q.spilled = 0
+
+	var err error
+	if q.writeSeg != nil {
+		err = removeSegment(q.writeSeg)
+		q.writeSeg, q.writeBuf, q.writeSegCount = nil, nil, 0
+	}
+	if q.readSeg != nil {
+		if rmErr := removeSegment(q.readSeg); err == nil {
+			err = rmErr
+		}
+		q.readSeg, q.readBuf = nil, nil
+	}
+	for _, path := range q.segments {
+		if rmErr := os.Remove(path); err == nil {
+			err = rmErr
+		}
+	}
+	q.segments = q.segments[:0]
+	return err
+}
+
+// Messages implements Queue.
+func (q *spillingQueue) Messages() Iterator {
+	return q
+}
+
+// Next implements Iterator. In-memory messages are dequeued first, followed
+// by the messages that were spilled to disk.
+func (q *spillingQueue) Next() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.lastErr != nil {
+		return false
+	}
+	if qLen := len(q.msgs); qLen != 0 {
+		q.latchedMsg = q.msgs[qLen-1]
+		q.msgs = q.msgs[:qLen-1]
+		return true
+	}
+	if q.spilled == 0 {
+		return false
+	}
+
+	msg, err := q.readSpilled()
+	if err != nil {
+		q.lastErr = err
+		return false
+	}
+	q.latchedMsg = msg
+	if q.spilled--; q.spilled == 0 && q.readSeg != nil {
+		// All spilled messages have been read; clean up the last segment.
+		if err = removeSegment(q.readSeg); err != nil {
+			q.lastErr = err
+		}
+		q.readSeg, q.readBuf = nil, nil
+	}
+	return true
+}
+
+// Message implements Iterator.
+func (q *spillingQueue) Message() Message {
+	q.mu.Lock()
+	msg := q.latchedMsg
+	q.mu.Unlock()
+	return msg
+}
+
+// Error implements Iterator.
+func (q *spillingQueue) Error() error {
+	q.mu.Lock()
+	err := q.lastErr
+	q.mu.Unlock()
+	return err
+}
+
+// spill appends a message to the current write segment, creating a new
+// segment if required. Callers must hold the lock.
+func (q *spillingQueue) spill(msg Message) error {
+	codec, err := CodecFor(msg.Type())
+	if err != nil {
+		return err
+	}
+	data, err := codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if q.writeSeg == nil {
+		if q.writeSeg, err = ioutil.TempFile(q.cfg.Dir, "bspgraph-queue-*.seg"); err != nil {
+			return err
+		}
+		q.writeBuf = bufio.NewWriter(q.writeSeg)
+	}
+	if err = writeFrame(q.writeBuf, []byte(msg.Type())); err != nil {
+		return err
+	}
+	if err = writeFrame(q.writeBuf, data); err != nil {
+		return err
+	}
+	q.spilled++
+	if q.writeSegCount++; q.writeSegCount >= q.cfg.MaxSegmentMessages {
+		return q.sealWriteSegment()
+	}
+	return nil
+}
+
+// sealWriteSegment flushes and closes the current write segment and makes it
+// available for reading. Callers must hold the lock.
+func (q *spillingQueue) sealWriteSegment() error {
+	seg := q.writeSeg
+	err := q.writeBuf.Flush()
+	if closeErr := seg.Close(); err == nil {
+		err = closeErr
+	}
+	q.writeSeg, q.writeBuf, q.writeSegCount = nil, nil, 0
+	q.segments = append(q.segments, seg.Name())
+	return err
+}
+
+// readSpilled reads the next message from the segment files. Segment files
+// are removed once all their messages have been read. Callers must hold the
+// lock.
+func (q *spillingQueue) readSpilled() (Message, error) {
+	for {
+		if q.readSeg == nil {
+			if len(q.segments) == 0 && q.writeSeg != nil {
+				if err := q.sealWriteSegment(); err != nil {
+					return nil, err
+				}
+			}
+			if len(q.segments) == 0 {
+				return nil, xerrors.New("spilled messages are missing from disk")
+			}
+			f, err := os.Open(q.segments[0])
+			if err != nil {
+				return nil, err
+			}
+			q.segments = q.segments[1:]
+			q.readSeg, q.readBuf = f, bufio.NewReader(f)
+		}
+
+		msgType, err := readFrame(q.readBuf)
+		if err == io.EOF {
+			err = removeSegment(q.readSeg)
+			q.readSeg, q.readBuf = nil, nil
+			if err != nil {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		data, err := readFrame(q.readBuf)
+		if err != nil {
+			return nil, err
+		}
+		codec, err := CodecFor(string(msgType))
+		if err != nil {
+			return nil, err
+		}
+		return codec.Unmarshal(data)
+	}
+}
+
+// writeFrame writes a length-prefixed byte slice to w.
+func writeFrame(w *bufio.Writer, b []byte) error {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+	if _, err := w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// readFrame reads a length-prefixed byte slice from r.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// removeSegment closes and deletes a segment file.
+func removeSegment(f *os.File) error {
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,106 @@
+package message_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(spillingQueueTest))
+
+func init() {
+	message.RegisterCodec(spillMsg{}.Type(), message.NewGobCodec(spillMsg{}))
+}
+
+type spillingQueueTest struct {
+	dir string
+	q   message.Queue
+}
+
+type spillMsg struct {
+	Payload string
+}
+
+func (spillMsg) Type() string {
+	return "spill"
+}
+
+func (s *spillingQueueTest) SetUpTest(c *gc.C) {
+	dir, err := ioutil.TempDir("", "spilling-queue-test")
+	c.Assert(err, gc.IsNil)
+	s.dir = dir
+	s.q = message.NewSpillingQueueFactory(message.SpillingQueueConfig{
+		Dir:                 dir,
+		MaxInMemoryMessages: 10,
+		MaxSegmentMessages:  25,
+	})()
+}
+
+func (s *spillingQueueTest) TearDownTest(c *gc.C) {
+	c.Assert(s.q.Close(), gc.IsNil)
+	c.Assert(s.segmentFiles(c), gc.HasLen, 0)
+	c.Assert(os.RemoveAll(s.dir), gc.IsNil)
+}
+
+func (s *spillingQueueTest) TestEnqueueDequeue(c *gc.C) {
+	s.enqueue(c, 100)
+	c.Assert(s.q.PendingMessages(), gc.Equals, true)
+	c.Assert(s.segmentFiles(c), gc.HasLen, 4)
+
+	seen := make(map[string]bool)
+	it := s.q.Messages()
+	for it.Next() {
+		seen[it.Message().(spillMsg).Payload] = true
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(seen, gc.HasLen, 100)
+	c.Assert(s.q.PendingMessages(), gc.Equals, false)
+	c.Assert(s.segmentFiles(c), gc.HasLen, 0)
+}
+
+func (s *spillingQueueTest) TestEnqueueWhileIterating(c *gc.C) {
+	s.enqueue(c, 30)
+	it := s.q.Messages()
+	var processed int
+	for ; processed < 15 && it.Next(); processed++ {
+	}
+	s.enqueue(c, 30)
+	for it.Next() {
+		processed++
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(processed, gc.Equals, 60)
+}
+
+func (s *spillingQueueTest) TestDiscard(c *gc.C) {
+	s.enqueue(c, 60)
+	c.Assert(s.q.PendingMessages(), gc.Equals, true)
+	c.Assert(s.q.DiscardMessages(), gc.IsNil)
+	c.Assert(s.q.PendingMessages(), gc.Equals, false)
+	c.Assert(s.segmentFiles(c), gc.HasLen, 0)
+}
+
+func (s *spillingQueueTest) TestSpillUnknownMessageType(c *gc.C) {
+	s.enqueue(c, 10)
+	err := s.q.Enqueue(context.Background(), msg{payload: "no codec"})
+	c.Assert(err, gc.ErrorMatches, ".*no codec registered.*")
+}
+
+func (s *spillingQueueTest) enqueue(c *gc.C, count int) {
+	ctx := context.Background()
+	for i := 0; i < count; i++ {
+		err := s.q.Enqueue(ctx, spillMsg{Payload: fmt.Sprint(i, "-", c.TestName())})
+		c.Assert(err, gc.IsNil)
+	}
+}
+
+func (s *spillingQueueTest) segmentFiles(c *gc.C) []string {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.seg"))
+	c.Assert(err, gc.IsNil)
+	return files
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/xerrors"
+)
+
+// PrometheusSink implements bspgraph.MetricsSink by exporting the per
+// superstep statistics of a graph as Prometheus metrics.
+type PrometheusSink struct {
+	superstep        prometheus.Gauge
+	activeVertices   prometheus.Gauge
+	utilization      prometheus.Gauge
+	slowestVertex    prometheus.Gauge
+	messagesSent     prometheus.Counter
+	messagesReceived prometheus.Counter
+	messagesRelayed  prometheus.Counter
+	relayFailures    prometheus.Counter
+	stepDuration     prometheus.Histogram
+	computeTime      prometheus.Counter
+}
+
+// NewPrometheusSink creates a PrometheusSink whose metrics are prefixed with
+// the specified namespace and registers them with reg.
+func NewPrometheusSink(namespace string, reg prometheus.Registerer) (*PrometheusSink, error) {
+	s := &PrometheusSink{
+		superstep: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "superstep",
+			Help: "The last superstep executed by the graph.",
+		}),
+		activeVertices: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "active_vertices",
+			Help: "The number of active vertices in the last superstep.",
+		}),
+		utilization: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "worker_utilization_ratio",
+			Help: "The fraction of the last superstep that compute workers spent running the compute function.",
+		}),
+		slowestVertex: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "slowest_vertex_seconds",
+			Help: "The longest compute function execution time in the last superstep.",
+		}),
+		messagesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "messages_sent_total",
+			Help: "The number of messages sent by the graph vertices.",
+		}),
+		messagesReceived: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "messages_received_total",
+			Help: "The number of messages delivered to local vertices.",
+		}),
+		messagesRelayed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "messages_relayed_total",
+			Help: "The number of messages relayed to remote vertices.",
+		}),
+		relayFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "relay_failures_total",
+			Help: "The number of messages that could not be relayed to remote vertices.",
+		}),
+		stepDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "superstep_duration_seconds",
+			Help:    "The wall time spent executing each superstep.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		}),
+		computeTime: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "bspgraph", Name: "compute_seconds_total",
+			Help: "The time spent running the compute function across all vertices.",
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		s.superstep, s.activeVertices, s.utilization, s.slowestVertex,
+		s.messagesSent, s.messagesReceived, s.messagesRelayed, s.relayFailures,
+		s.stepDuration, s.computeTime,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, xerrors.Errorf("register bspgraph metrics: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// RecordStep implements bspgraph.MetricsSink.
+func (s *PrometheusSink) RecordStep(stats bspgraph.StepStats) {
+	s.superstep.Set(float64(stats.Superstep))
+	s.activeVertices.Set(float64(stats.ActiveVertices))
+	s.utilization.Set(stats.Utilization())
+	s.messagesSent.Add(float64(stats.MessagesSent))
+	s.messagesReceived.Add(float64(stats.MessagesReceived))
+	s.messagesRelayed.Add(float64(stats.MessagesRelayed))
+	s.relayFailures.Add(float64(stats.RelayFailures))
+	s.stepDuration.Observe(stats.WallTime.Seconds())
+	s.computeTime.Add(stats.ComputeTime.Seconds())
+
+	var slowest float64
+	if len(stats.SlowestVertices) != 0 {
+		slowest = stats.SlowestVertices[0].Duration.Seconds()
+	}
+	s.slowestVertex.Set(slowest)
+}
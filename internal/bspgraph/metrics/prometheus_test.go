@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(PrometheusSinkTestSuite))
+
+type PrometheusSinkTestSuite struct{}
+
+func (s *PrometheusSinkTestSuite) TestRecordStep(c *gc.C) {
+	sink, err := NewPrometheusSink("test", prometheus.NewRegistry())
+	c.Assert(err, gc.IsNil)
+
+	for step := 0; step < 2; step++ {
+		sink.RecordStep(bspgraph.StepStats{
+			Superstep:        step,
+			ActiveVertices:   10 - step,
+			MessagesSent:     4,
+			MessagesReceived: 3,
+			MessagesRelayed:  2,
+			RelayFailures:    1,
+			WallTime:         time.Second,
+			ComputeTime:      time.Second,
+			ComputeWorkers:   2,
+			SlowestVertices:  []bspgraph.VertexTiming{{ID: "a", Duration: 250 * time.Millisecond}},
+		})
+	}
+
+	// Gauges reflect the last superstep.
+	c.Assert(testutil.ToFloat64(sink.superstep), gc.Equals, 1.0)
+	c.Assert(testutil.ToFloat64(sink.activeVertices), gc.Equals, 9.0)
+	c.Assert(testutil.ToFloat64(sink.utilization), gc.Equals, 0.5)
+	c.Assert(testutil.ToFloat64(sink.slowestVertex), gc.Equals, 0.25)
+
+	// Counters accumulate across supersteps.
+	c.Assert(testutil.ToFloat64(sink.messagesSent), gc.Equals, 8.0)
+	c.Assert(testutil.ToFloat64(sink.messagesReceived), gc.Equals, 6.0)
+	c.Assert(testutil.ToFloat64(sink.messagesRelayed), gc.Equals, 4.0)
+	c.Assert(testutil.ToFloat64(sink.relayFailures), gc.Equals, 2.0)
+	c.Assert(testutil.ToFloat64(sink.computeTime), gc.Equals, 2.0)
+	c.Assert(testutil.CollectAndCount(sink.stepDuration), gc.Equals, 1)
+}
+
+func (s *PrometheusSinkTestSuite) TestSlowestVertexResetsWithoutTimings(c *gc.C) {
+	sink, err := NewPrometheusSink("test", prometheus.NewRegistry())
+	c.Assert(err, gc.IsNil)
+
+	sink.RecordStep(bspgraph.StepStats{SlowestVertices: []bspgraph.VertexTiming{{ID: "a", Duration: time.Second}}})
+	sink.RecordStep(bspgraph.StepStats{})
+	c.Assert(testutil.ToFloat64(sink.slowestVertex), gc.Equals, 0.0)
+}
+
+func (s *PrometheusSinkTestSuite) TestDuplicateRegistration(c *gc.C) {
+	reg := prometheus.NewRegistry()
+	_, err := NewPrometheusSink("test", reg)
+	c.Assert(err, gc.IsNil)
+
+	_, err = NewPrometheusSink("test", reg)
+	c.Assert(err, gc.ErrorMatches, "register bspgraph metrics: .*")
+}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
@@ -0,0 +1,186 @@
+package bspgraph
+
+import (
+	"sort"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+// mutationType describes the kind of topology change requested by a
+// mutation.
+type mutationType uint8
+
+// The list of supported mutation types. The declaration order matches the
+// order in which mutations are applied at the end of each superstep.
+const (
+	removeEdgeMutation mutationType = iota
+	removeVertexMutation
+	addVertexMutation
+	addEdgeMutation
+)
+
+// mutation represents a pending topology change that was requested by a
+// compute function while executing a superstep.
+type mutation struct {
+	mType    mutationType
+	issuerID string
+	srcID    string
+	dstID    string
+	value    interface{}
+}
+
+// RequestAddVertex requests the insertion of a vertex with the specified id
+// and initial value on behalf of the issuer vertex. It is safe to call
+// RequestAddVertex from a ComputeFunc; the request is applied once all
+// vertices have completed the current superstep.
+// The vertex must not already exist unless it is removed within the same
+// superstep.
+func (g *Graph) RequestAddVertex(issuer *Vertex, id string, initValue interface{}) {
+	g.enqueueMutation(mutation{mType: addVertexMutation, issuerID: issuer.ID(), srcID: id, value: initValue})
+}
+
+// RequestRemoveVertex requests the removal of the vertex with the specified id
+// on behalf of the issuer vertex. It is safe to call RequestRemoveVertex from a
+// ComputeFunc; the request is applied once all vertices have completed the
+// current superstep.
+func (g *Graph) RequestRemoveVertex(issuer *Vertex, id string) {
+	g.enqueueMutation(mutation{mType: removeVertexMutation, issuerID: issuer.ID(), srcID: id})
+}
+
+// RequestAddEdge requests the insertion of a directed edge from srcID to dstID
+// on behalf of the issuer vertex. It is safe to call RequestAddEdge from a
+// ComputeFunc; the request is applied once all vertices have completed the
+// current superstep.
+func (g *Graph) RequestAddEdge(issuer *Vertex, srcID, dstID string, initValue interface{}) {
+	g.enqueueMutation(mutation{mType: addEdgeMutation, issuerID: issuer.ID(), srcID: srcID, dstID: dstID, value: initValue})
+}
+
+// RequestRemoveEdge requests the removal of all directed edges from srcID to
+// dstID on behalf of the issuer vertex. It is safe to call RequestRemoveEdge
+// from a ComputeFunc; the request is applied once all vertices have completed
+// the current superstep.
+func (g *Graph) RequestRemoveEdge(issuer *Vertex, srcID, dstID string) {
+	g.enqueueMutation(mutation{mType: removeEdgeMutation, issuerID: issuer.ID(), srcID: srcID, dstID: dstID})
+}
+
+func (g *Graph) enqueueMutation(m mutation) {
+	g.mutationMu.Lock()
+	g.pendingMutations = append(g.pendingMutations, m)
+	g.mutationMu.Unlock()
+}
+
+// applyMutations resolves and applies the mutations requested during the last
+// superstep. To make the outcome independent of the order in which the
+// compute workers processed the vertices, conflicts are resolved using the
+// following policy:
+//
+//   - edge removals are applied first, followed by vertex removals, vertex
+//     additions and finally edge additions. As a result, a vertex that is
+//     both removed and added within the same superstep is re-created without
+//     any edges or pending messages.
+//   - mutations of the same type are applied in issuer ID order; mutations
+//     requested by the same issuer are applied in the order they were made.
+//     If multiple issuers add the same vertex, the value requested by the
+//     issuer with the greatest ID wins.
+//
+// Removing a vertex discards its pending messages but leaves any edges
+// pointing to it untouched.
+//
+// The mutations are applied atomically: if any of them is invalid, none are
+// applied and an error listing all invalid mutations is returned.
+func (g *Graph) applyMutations() error {
+	g.mutationMu.Lock()
+	pending := g.pendingMutations
+	g.pendingMutations = nil
+	g.mutationMu.Unlock()
+
+	sort.SliceStable(pending, func(i, j int) bool {
+		if pending[i].mType != pending[j].mType {
+			return pending[i].mType < pending[j].mType
+		}
+		return pending[i].issuerID < pending[j].issuerID
+	})
+	if err := g.validateMutations(pending); err != nil {
+		return err
+	}
+
+	// The message queues of removed vertices are closed once the topology
+	// changes have been applied so that a failure to close a queue cannot
+	// leave the graph partially mutated.
+	var removed []*Vertex
+	for _, m := range pending {
+		switch m.mType {
+		case removeEdgeMutation:
+			_ = g.RemoveEdge(m.srcID, m.dstID)
+		case removeVertexMutation:
+			if v := g.vertices[m.srcID]; v != nil {
+				removed = append(removed, v)
+				delete(g.vertices, m.srcID)
+			}
+		case addVertexMutation:
+			g.AddVertex(m.srcID, m.value)
+		case addEdgeMutation:
+			_ = g.AddEdge(m.srcID, m.dstID, m.value)
+		}
+	}
+
+	var err error
+	for _, v := range removed {
+		for i := 0; i < 2; i++ {
+			if qErr := v.msgQueue[i].Close(); qErr != nil {
+				err = multierror.Append(err, xerrors.Errorf("closing message queue #%d for vertex %v: %w", i, v.id, qErr))
+			}
+		}
+	}
+	return err
+}
+
+// validateMutations checks the sorted list of pending mutations against the
+// graph topology as it evolves while the mutations are applied and returns
+// an error describing all mutations that cannot be applied.
+func (g *Graph) validateMutations(pending []mutation) error {
+	var (
+		err       error
+		exists    = make(map[string]bool)
+		added     = make(map[string]bool)
+		hasVertex = func(id string) bool {
+			if present, found := exists[id]; found {
+				return present
+			}
+			return g.vertices[id] != nil
+		}
+	)
+	for _, m := range pending {
+		var mErr error
+		switch m.mType {
+		case removeEdgeMutation:
+			if !hasVertex(m.srcID) {
+				mErr = xerrors.Errorf("remove edge from %q to %q: %w", m.srcID, m.dstID, ErrUnknownEdgeSource)
+			}
+		case removeVertexMutation:
+			exists[m.srcID] = false
+		case addVertexMutation:
+			if hasVertex(m.srcID) && !added[m.srcID] {
+				mErr = xerrors.Errorf("add vertex %q: %w", m.srcID, ErrVertexExists)
+				break
+			}
+			exists[m.srcID], added[m.srcID] = true, true
+		case addEdgeMutation:
+			if !hasVertex(m.srcID) {
+				mErr = xerrors.Errorf("create edge from %q to %q: %w", m.srcID, m.dstID, ErrUnknownEdgeSource)
+			}
+		}
+		if mErr != nil {
+			err = multierror.Append(err, xerrors.Errorf("mutation requested by vertex %q: %w", m.issuerID, mErr))
+		}
+	}
+	return err
+}
+
+// discardMutations drops any pending mutation requests.
+func (g *Graph) discardMutations() {
+	g.mutationMu.Lock()
+	g.pendingMutations = nil
+	g.mutationMu.Unlock()
+}
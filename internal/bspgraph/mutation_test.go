@@ -0,0 +1,138 @@
+package bspgraph_test
+
+import (
+	"context"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(MutationTestSuite))
+
+type MutationTestSuite struct{}
+
+func (s *MutationTestSuite) TestApplyMutationsAtBarrier(c *gc.C) {
+	computeFn := func(g *bspgraph.Graph, v *bspgraph.Vertex, _ message.Iterator) error {
+		switch v.ID() {
+		case "a":
+			g.RequestRemoveEdge(v, "a", "b")
+			g.RequestAddVertex(v, "d", "from-a")
+		case "b":
+			g.RequestRemoveVertex(v, "c")
+			g.RequestAddEdge(v, "b", "d", nil)
+		case "c":
+			g.RequestAddVertex(v, "d", "from-c")
+		}
+		// Mutations must not be visible until the superstep completes.
+		if g.Vertices()["d"] != nil {
+			return xerrors.New("mutation applied before the superstep barrier")
+		}
+		v.Freeze()
+		return nil
+	}
+
+	g := s.newGraph(c, computeFn)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	c.Assert(bspgraph.NewExecutor(g, oneStepCallbacks()).RunToCompletion(context.Background()), gc.IsNil)
+
+	vertices := g.Vertices()
+	c.Assert(vertices, gc.HasLen, 3)
+	c.Assert(vertices["c"], gc.IsNil)
+	c.Assert(vertices["d"].Value(), gc.Equals, "from-c")
+	c.Assert(vertices["a"].Edges(), gc.HasLen, 0)
+	c.Assert(edgeDestinations(vertices["b"]), gc.DeepEquals, []string{"c", "d"})
+}
+
+func (s *MutationTestSuite) TestRemoveAndAddVertexInSameStep(c *gc.C) {
+	computeFn := func(g *bspgraph.Graph, v *bspgraph.Vertex, _ message.Iterator) error {
+		if v.ID() == "a" {
+			g.RequestAddVertex(v, "b", "recreated")
+			g.RequestRemoveVertex(v, "b")
+		}
+		v.Freeze()
+		return nil
+	}
+
+	g := s.newGraph(c, computeFn)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	c.Assert(bspgraph.NewExecutor(g, oneStepCallbacks()).RunToCompletion(context.Background()), gc.IsNil)
+
+	b := g.Vertices()["b"]
+	c.Assert(b, gc.NotNil)
+	c.Assert(b.Value(), gc.Equals, "recreated")
+	c.Assert(b.Edges(), gc.HasLen, 0)
+}
+
+func (s *MutationTestSuite) TestAddEdgeFromUnknownSource(c *gc.C) {
+	computeFn := func(g *bspgraph.Graph, v *bspgraph.Vertex, _ message.Iterator) error {
+		g.RequestAddEdge(v, "missing", v.ID(), nil)
+		return nil
+	}
+
+	g := s.newGraph(c, computeFn)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	err := bspgraph.NewExecutor(g, oneStepCallbacks()).RunToCompletion(context.Background())
+	c.Assert(xerrors.Is(err, bspgraph.ErrUnknownEdgeSource), gc.Equals, true)
+}
+
+func (s *MutationTestSuite) TestInvalidMutationsAreNotApplied(c *gc.C) {
+	computeFn := func(g *bspgraph.Graph, v *bspgraph.Vertex, _ message.Iterator) error {
+		switch v.ID() {
+		case "a":
+			g.RequestAddVertex(v, "d", nil)
+			g.RequestRemoveEdge(v, "a", "b")
+			g.RequestRemoveVertex(v, "c")
+		case "b":
+			g.RequestAddEdge(v, "missing", "a", nil)
+		case "c":
+			g.RequestAddVertex(v, "a", "overwritten")
+		}
+		return nil
+	}
+
+	g := s.newGraph(c, computeFn)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	err := bspgraph.NewExecutor(g, oneStepCallbacks()).RunToCompletion(context.Background())
+	c.Assert(xerrors.Is(err, bspgraph.ErrUnknownEdgeSource), gc.Equals, true)
+	c.Assert(xerrors.Is(err, bspgraph.ErrVertexExists), gc.Equals, true)
+
+	// None of the valid mutations in the batch may have been applied.
+	vertices := g.Vertices()
+	c.Assert(vertices, gc.HasLen, 3)
+	c.Assert(vertices["a"].Value(), gc.IsNil)
+	c.Assert(vertices["c"], gc.NotNil)
+	c.Assert(edgeDestinations(vertices["a"]), gc.DeepEquals, []string{"b"})
+}
+
+func (s *MutationTestSuite) newGraph(c *gc.C, computeFn bspgraph.ComputeFunc) *bspgraph.Graph {
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn:      computeFn,
+		ComputeWorkers: 3,
+	})
+	c.Assert(err, gc.IsNil)
+
+	for _, id := range []string{"a", "b", "c"} {
+		g.AddVertex(id, nil)
+	}
+	c.Assert(g.AddEdge("a", "b", nil), gc.IsNil)
+	c.Assert(g.AddEdge("b", "c", nil), gc.IsNil)
+	return g
+}
+
+func oneStepCallbacks() bspgraph.ExecutorCallbacks {
+	return bspgraph.ExecutorCallbacks{
+		PostStepKeepRunning: func(_ context.Context, g *bspgraph.Graph, _ int) (bool, error) {
+			return false, nil
+		},
+	}
+}
+
+func edgeDestinations(v *bspgraph.Vertex) []string {
+	var dsts []string
+	for _, e := range v.Edges() {
+		dsts = append(dsts, e.DstID())
+	}
+	return dsts
+}
@@ -0,0 +1,153 @@
+package bspgraph
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StepStats encapsulates the statistics that a graph collects while executing
+// a superstep.
+type StepStats struct {
+	// The superstep that these statistics refer to.
+	Superstep int
+
+	// The number of vertices that executed the compute function.
+	ActiveVertices int
+
+	// The number of SendMessage calls that were successfully processed.
+	MessagesSent int64
+
+	// The number of messages that were enqueued for delivery to local
+	// vertices. This includes messages that were relayed to this graph by
+	// remote graph instances.
+	MessagesReceived int64
+
+	// The number of messages that were handed off to the Relayer.
+	MessagesRelayed int64
+
+	// The number of messages that the Relayer failed to deliver.
+	RelayFailures int64
+
+	// The time it took to execute the superstep, including the time spent
+	// applying graph mutations and flushing combined relay messages.
+	WallTime time.Duration
+
+	// The total time spent inside the compute function across all
+	// vertices.
+	ComputeTime time.Duration
+
+	// The number of compute workers that executed the superstep.
+	ComputeWorkers int
+
+	// The vertices that took the longest to execute the compute function
+	// sorted by descending duration.
+	SlowestVertices []VertexTiming
+}
+
+// Utilization returns the fraction of the superstep wall time that the
+// compute workers spent executing the compute function.
+func (s StepStats) Utilization() float64 {
+	if s.WallTime <= 0 || s.ComputeWorkers == 0 {
+		return 0
+	}
+	return float64(s.ComputeTime) / float64(s.WallTime*time.Duration(s.ComputeWorkers))
+}
+
+// VertexTiming describes how long the compute function took to execute for a
+// particular vertex.
+type VertexTiming struct {
+	ID       string
+	Duration time.Duration
+}
+
+// MetricsSink is implemented by types that can export the statistics that a
+// graph collects for each superstep.
+type MetricsSink interface {
+	// RecordStep is invoked by the graph after each superstep.
+	RecordStep(stats StepStats)
+}
+
+// statsCollector gathers the statistics for the superstep that is currently
+// being executed. All methods are safe for concurrent use.
+type statsCollector struct {
+	messagesSent     int64
+	messagesReceived int64
+	messagesRelayed  int64
+	relayFailures    int64
+	computeTime      int64
+
+	mu          sync.Mutex
+	maxSlowest  int
+	slowest     []VertexTiming
+	lastStats   StepStats
+	lastStatsMu sync.RWMutex
+}
+
+// recordCompute tracks the time it took to execute the compute function for
+// the vertex with the specified ID.
+func (c *statsCollector) recordCompute(id string, d time.Duration) {
+	atomic.AddInt64(&c.computeTime, int64(d))
+	if c.maxSlowest <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.slowest) == c.maxSlowest && c.slowest[len(c.slowest)-1].Duration >= d {
+		return
+	}
+	idx := sort.Search(len(c.slowest), func(i int) bool { return c.slowest[i].Duration < d })
+	if len(c.slowest) < c.maxSlowest {
+		c.slowest = append(c.slowest, VertexTiming{})
+	}
+	copy(c.slowest[idx+1:], c.slowest[idx:])
+	c.slowest[idx] = VertexTiming{ID: id, Duration: d}
+}
+
+// collect returns the statistics for the superstep that just completed and
+// resets the collector for the next superstep.
+func (c *statsCollector) collect(superstep, activeInStep, workers int, wallTime time.Duration) StepStats {
+	c.mu.Lock()
+	slowest := c.slowest
+	c.slowest = nil
+	c.mu.Unlock()
+
+	stats := StepStats{
+		Superstep:        superstep,
+		ActiveVertices:   activeInStep,
+		MessagesSent:     atomic.SwapInt64(&c.messagesSent, 0),
+		MessagesReceived: atomic.SwapInt64(&c.messagesReceived, 0),
+		MessagesRelayed:  atomic.SwapInt64(&c.messagesRelayed, 0),
+		RelayFailures:    atomic.SwapInt64(&c.relayFailures, 0),
+		ComputeTime:      time.Duration(atomic.SwapInt64(&c.computeTime, 0)),
+		WallTime:         wallTime,
+		ComputeWorkers:   workers,
+		SlowestVertices:  slowest,
+	}
+	c.lastStatsMu.Lock()
+	c.lastStats = stats
+	c.lastStatsMu.Unlock()
+	return stats
+}
+
+// last returns the statistics for the most recently executed superstep.
+func (c *statsCollector) last() StepStats {
+	c.lastStatsMu.RLock()
+	defer c.lastStatsMu.RUnlock()
+	return c.lastStats
+}
+
+// reset discards any collected statistics.
+func (c *statsCollector) reset() {
+	c.collect(0, 0, 0, 0)
+	c.lastStatsMu.Lock()
+	c.lastStats = StepStats{}
+	c.lastStatsMu.Unlock()
+}
+
+// Stats returns the statistics for the most recently executed superstep.
+func (g *Graph) Stats() StepStats {
+	return g.stats.last()
+}
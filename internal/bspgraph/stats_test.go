@@ -0,0 +1,121 @@
+package bspgraph_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(StatsTestSuite))
+
+type StatsTestSuite struct{}
+
+func (s *StatsTestSuite) TestCollectStepStats(c *gc.C) {
+	computeFn := func(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+		if v.ID() == "slow" {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if g.Superstep() == 0 {
+			return g.BroadcastToNeighbors(context.Background(), v, sumMessage{Value: 1})
+		}
+		v.Freeze()
+		return nil
+	}
+
+	sink := new(recordingSink)
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn:       computeFn,
+		ComputeWorkers:  2,
+		MetricsSink:     sink,
+		SlowestVertices: 1,
+	})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	relayer := &recordingRelayer{msgs: make(map[string][]message.Message)}
+	g.RegisterRelayer(relayer)
+	for _, id := range []string{"fast", "slow"} {
+		g.AddVertex(id, nil)
+	}
+	c.Assert(g.AddEdge("fast", "slow", nil), gc.IsNil)
+	c.Assert(g.AddEdge("slow", "fast", nil), gc.IsNil)
+	c.Assert(g.AddEdge("slow", "remote", nil), gc.IsNil)
+
+	cb := bspgraph.ExecutorCallbacks{
+		PostStepKeepRunning: func(_ context.Context, g *bspgraph.Graph, _ int) (bool, error) {
+			return g.Superstep() < 1, nil
+		},
+	}
+	c.Assert(bspgraph.NewExecutor(g, cb).RunToCompletion(context.Background()), gc.IsNil)
+
+	c.Assert(sink.stats, gc.HasLen, 2)
+	step0 := sink.stats[0]
+	c.Assert(step0.Superstep, gc.Equals, 0)
+	c.Assert(step0.ActiveVertices, gc.Equals, 2)
+	c.Assert(step0.MessagesSent, gc.Equals, int64(3))
+	c.Assert(step0.MessagesReceived, gc.Equals, int64(2))
+	c.Assert(step0.MessagesRelayed, gc.Equals, int64(1))
+	c.Assert(step0.RelayFailures, gc.Equals, int64(0))
+	c.Assert(step0.ComputeWorkers, gc.Equals, 2)
+	c.Assert(step0.SlowestVertices, gc.HasLen, 1)
+	c.Assert(step0.SlowestVertices[0].ID, gc.Equals, "slow")
+	c.Assert(step0.WallTime >= step0.SlowestVertices[0].Duration, gc.Equals, true)
+	c.Assert(step0.ComputeTime >= step0.SlowestVertices[0].Duration, gc.Equals, true)
+
+	step1 := g.Stats()
+	c.Assert(step1, gc.DeepEquals, sink.stats[1])
+	c.Assert(step1.Superstep, gc.Equals, 1)
+	c.Assert(step1.MessagesSent, gc.Equals, int64(0))
+}
+
+func (s *StatsTestSuite) TestDeliveredMessagesAreNotCountedAsSent(c *gc.C) {
+	var received int
+	computeFn := func(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+		for msgIt.Next() {
+			received++
+		}
+		v.Freeze()
+		return nil
+	}
+
+	sink := new(recordingSink)
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{ComputeFn: computeFn, MetricsSink: sink})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	g.AddVertex("local", nil)
+
+	cb := bspgraph.ExecutorCallbacks{
+		PreStep: func(ctx context.Context, g *bspgraph.Graph) error {
+			if g.Superstep() == 0 {
+				return g.DeliverMessage(ctx, "local", sumMessage{Value: 1})
+			}
+			return nil
+		},
+		PostStepKeepRunning: func(_ context.Context, g *bspgraph.Graph, _ int) (bool, error) {
+			return g.Superstep() < 1, nil
+		},
+	}
+	c.Assert(bspgraph.NewExecutor(g, cb).RunToCompletion(context.Background()), gc.IsNil)
+	c.Assert(received, gc.Equals, 1)
+	c.Assert(sink.stats[0].MessagesSent, gc.Equals, int64(0))
+	c.Assert(sink.stats[0].MessagesReceived, gc.Equals, int64(1))
+
+	err = g.DeliverMessage(context.Background(), "unknown", sumMessage{Value: 1})
+	c.Assert(xerrors.Is(err, bspgraph.ErrInvalidMessageDestination), gc.Equals, true)
+}
+
+type recordingSink struct {
+	mu    sync.Mutex
+	stats []bspgraph.StepStats
+}
+
+func (s *recordingSink) RecordStep(stats bspgraph.StepStats) {
+	s.mu.Lock()
+	s.stats = append(s.stats, stats)
+	s.mu.Unlock()
+}
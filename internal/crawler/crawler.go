@@ -9,6 +9,7 @@ import (
 	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
 	"github.com/joshvoll/linkrus/internal/pipeline"
 	"github.com/joshvoll/linkrus/internal/textindexer/index"
+	"golang.org/x/xerrors"
 )
 
 // URLGetter is implemented by object that can performs HTTP GET request.
@@ -25,15 +26,17 @@ type PrivateNetworkDetector interface {
 // Graph is implemented by the objects that can upsert the links and edges into a links
 // graph intnace.
 type Graph interface {
-	// UpsertLink create a new link or updates an existing link.
-	UpsertLink(ctx context.Context, link *graph.Link) error
+	// UpsertLinks creates new links or updates existing links in a
+	// single batch.
+	UpsertLinks(ctx context.Context, links []*graph.Link) error
 
-	// UpsertEdge create a new edge or updates an existing edge.
-	UpdsertEdge(ctx context.Context, edge *graph.Edge) error
+	// UpsertEdges creates new edges or updates existing edges in a single
+	// batch.
+	UpsertEdges(ctx context.Context, edges []*graph.Edge) error
 
-	// RemoveStaleEdges remove any edge that originates from the specified
+	// RemoveStalEdges remove any edge that originates from the specified
 	// link ID and was updated before the espcifiet timestamp.
-	RemoveStaleEdges(ctx context.Context, fromID uuid.UUID, updateBefore time.Time) error
+	RemoveStalEdges(ctx context.Context, fromID uuid.UUID, updateBefore time.Time) error
 }
 
 // Indexer is the implemented by objects that can index the contents of web-pages
@@ -44,6 +47,14 @@ type Indexer interface {
 	Index(ctx context.Context, doc *index.Document) error
 }
 
+// Flusher is optionally implemented by Indexer instances that buffer
+// documents, such as index.BufferedIndexer. Crawl flushes them once all links
+// have gone through the pipeline.
+type Flusher interface {
+	// Flush writes all buffered documents to the underlying index.
+	Flush(ctx context.Context) error
+}
+
 // Config encapsulates the configuration options for creating new Crawler.
 type Config struct {
 	// PrivateNetworkDetector a instance
@@ -56,10 +67,18 @@ type Config struct {
 	Graph Graph
 
 	// A TextIndexer instance for indexing the content of each retrieved links.
+	// Wrap the indexer with an index.BufferedIndexer to index documents in
+	// batches.
 	Indexer Indexer
 
 	// The numbers of concurrent worker used for retrieving links.
 	FetchWorkers int
+
+	// RecordErrorFn, if specified, is invoked with the errors that occur
+	// while recording a failed or non-HTML fetch in the link graph. Such
+	// errors do not abort the crawl. If not specified, the errors are
+	// logged using the standard library logger.
+	RecordErrorFn func(error)
 }
 
 // Crawler implements a web-page crawling pipeline consisting of the following
@@ -72,13 +91,15 @@ type Config struct {
 //   page and the links within it.
 // - Index crawled page title and text content.
 type Crawler struct {
-	p *pipeline.Pipeline
+	p       *pipeline.Pipeline
+	indexer Indexer
 }
 
 // NewCrawler returns a new crawler instnace.
 func NewCrawler(cfg Config) *Crawler {
 	return &Crawler{
-		p: assembleCrawlerPipeline(cfg),
+		p:       assembleCrawlerPipeline(cfg),
+		indexer: cfg.Indexer,
 	}
 }
 
@@ -87,7 +108,7 @@ func NewCrawler(cfg Config) *Crawler {
 func assembleCrawlerPipeline(cfg Config) *pipeline.Pipeline {
 	return pipeline.New(
 		pipeline.FixedWorkerPool(
-			newLinkFetcher(cfg.URLGetter, cfg.PrivateNetworkDetector),
+			newLinkFetcher(cfg.URLGetter, cfg.PrivateNetworkDetector, cfg.Graph, cfg.RecordErrorFn),
 			cfg.FetchWorkers,
 		),
 		pipeline.FIFO(newLinkExtractor(cfg.PrivateNetworkDetector)),
@@ -106,6 +127,11 @@ func assembleCrawlerPipeline(cfg Config) *pipeline.Pipeline {
 func (c *Crawler) Crawl(ctx context.Context, linkIt graph.LinkIterator) (int, error) {
 	sink := new(countingSink)
 	err := c.p.Process(ctx, &linkSource{linkIt: linkIt}, sink)
+	if f, ok := c.indexer.(Flusher); ok {
+		if flushErr := f.Flush(ctx); err == nil && flushErr != nil {
+			err = xerrors.Errorf("flush indexer: %w", flushErr)
+		}
+	}
 	return sink.getCount(), err
 }
 
@@ -127,6 +153,10 @@ func (l *linkSource) Payload() pipeline.Payload {
 	p.LinkID = link.ID
 	p.URL = link.URL
 	p.RetrievedAt = link.RetrievedAt
+	p.StatusCode = link.StatusCode
+	p.ContentHash = link.ContentHash
+	p.RedirectURL = link.RedirectURL
+	p.FailureCount = link.FailureCount
 	return p
 }
 
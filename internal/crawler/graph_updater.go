@@ -20,38 +20,35 @@ func newGraphUdater(updater Graph) *graphUpdater {
 }
 
 // Process of the graphUpdater using the payload interface
-// Upsert discovered links and create edges for them. Keep track of
-// the current time so we can drop stale edges that have not been
-// updated after this loop.
+// Upsert the source link and all discovered links in a single batch and
+// then create an annotated edge to each of them; edges to nofollow links
+// are flagged as such. Keep track of the current time so we can drop stale
+// edges that have not been updated after this loop.
 func (u *graphUpdater) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
 	payload := p.(*crawlerPayload)
-	src := &graph.Link{
-		ID:          payload.LinkID,
-		URL:         payload.URL,
-		RetrievedAt: time.Now(),
+	src := payload.fetchedLink(time.Now())
+	links := make([]*graph.Link, 0, 1+len(payload.Links))
+	links = append(links, src)
+	for _, dstLink := range payload.Links {
+		links = append(links, &graph.Link{URL: dstLink.URL})
 	}
-	if err := u.updater.UpsertLink(ctx, src); err != nil {
+	if err := u.updater.UpsertLinks(ctx, links); err != nil {
 		return nil, err
 	}
-	for _, dstLink := range payload.NoFollowLinks {
-		dst := &graph.Link{
-			URL: dstLink,
-		}
-		if err := u.updater.UpsertLink(ctx, dst); err != nil {
-			return nil, err
-		}
-	}
+
 	removeEdgeOlderThan := time.Now()
-	for _, dstLink := range payload.Links {
-		dst := &graph.Link{
-			URL: dstLink,
-		}
-		if err := u.updater.UpsertLink(ctx, dst); err != nil {
-			return nil, err
-		}
-		if err := u.updater.UpsertEdge(ctx, &graph.Edge{Src: src.ID, Dst: dst.ID}); err != nil {
-			return nil, err
-		}
+	edges := make([]*graph.Edge, 0, len(payload.Links))
+	for i, dstLink := range payload.Links {
+		edges = append(edges, &graph.Edge{
+			Src:        src.ID,
+			Dst:        links[i+1].ID,
+			AnchorText: dstLink.AnchorText,
+			NoFollow:   dstLink.NoFollow,
+			Position:   dstLink.Position,
+		})
+	}
+	if err := u.updater.UpsertEdges(ctx, edges); err != nil {
+		return nil, err
 	}
 	if err := u.updater.RemoveStalEdges(ctx, src.ID, removeEdgeOlderThan); err != nil {
 		return nil, err
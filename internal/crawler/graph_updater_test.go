@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(GraphUpdaterTestSuite))
+
+type GraphUpdaterTestSuite struct{}
+
+func (s *GraphUpdaterTestSuite) TestProcess(c *gc.C) {
+	g := new(stubGraph)
+	payload := &crawlerPayload{
+		LinkID:      uuid.New(),
+		URL:         "https://example.com/",
+		StatusCode:  200,
+		ContentHash: "hash",
+		Links: []extractedLink{
+			{URL: "https://example.com/about", AnchorText: "About us", Position: 0},
+			{URL: "https://example.com/sponsored", AnchorText: "Our sponsor", NoFollow: true, Position: 2},
+		},
+	}
+
+	start := time.Now()
+	got, err := newGraphUdater(g).Process(context.Background(), payload)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.Equals, payload)
+
+	// The source link is upserted with its fetch metadata followed by the
+	// discovered links.
+	c.Assert(g.links, gc.HasLen, 3)
+	src := g.links[0]
+	c.Assert(src.ID, gc.Equals, payload.LinkID)
+	c.Assert(src.StatusCode, gc.Equals, 200)
+	c.Assert(src.ContentHash, gc.Equals, "hash")
+	c.Assert(src.RetrievedAt.Before(start), gc.Equals, false)
+	c.Assert(g.links[1].URL, gc.Equals, "https://example.com/about")
+	c.Assert(g.links[2].URL, gc.Equals, "https://example.com/sponsored")
+
+	// Edges are annotated with the anchor details and nofollow links still
+	// get an edge.
+	c.Assert(g.edges, gc.DeepEquals, []*graph.Edge{
+		{Src: payload.LinkID, Dst: g.links[1].ID, AnchorText: "About us", Position: 0},
+		{Src: payload.LinkID, Dst: g.links[2].ID, AnchorText: "Our sponsor", NoFollow: true, Position: 2},
+	})
+
+	c.Assert(g.staleEdgesFrom, gc.Equals, payload.LinkID)
+	c.Assert(g.staleEdgesBefore.Before(src.RetrievedAt), gc.Equals, false)
+}
+
+func (s *GraphUpdaterTestSuite) TestProcessWithoutLinks(c *gc.C) {
+	g := new(stubGraph)
+	payload := &crawlerPayload{LinkID: uuid.New(), URL: "https://example.com/"}
+
+	_, err := newGraphUdater(g).Process(context.Background(), payload)
+	c.Assert(err, gc.IsNil)
+	c.Assert(g.links, gc.HasLen, 1)
+	c.Assert(g.edges, gc.HasLen, 0)
+
+	// Edges from earlier crawls of the page are removed.
+	c.Assert(g.staleEdgesFrom, gc.Equals, payload.LinkID)
+}
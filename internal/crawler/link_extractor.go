@@ -2,8 +2,11 @@ package crawler
 
 import (
 	"context"
+	"html"
 	"net/url"
 	"regexp"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/joshvoll/linkrus/internal/pipeline"
 )
@@ -13,8 +16,14 @@ var (
 	baesHrefRegex  = regexp.MustCompile(`(?i)<base.*?href\s*?=\s*?"(.*?)\s*?"`)
 	findLinkRegex  = regexp.MustCompile(`(?i)<a.*?href\s*?=\s*?"\s*?(.*?)\s*?".*?>`)
 	nofollowRegex  = regexp.MustCompile(`(?i)rel\s*?=\s*?"?nofollow"?`)
+	anchorEndRegex = regexp.MustCompile(`(?i)<\s*/?\s*a[\s>]`)
+	stripTagsRegex = regexp.MustCompile(`<[^>]*>`)
 )
 
+// maxAnchorTextLen is the maximum number of runes of anchor text that are
+// retained for each link.
+const maxAnchorTextLen = 256
+
 // linkExtractor model definition
 type linkExtractor struct {
 	netDetector PrivateNetworkDetector
@@ -30,7 +39,7 @@ func newLinkExtractor(netDetector PrivateNetworkDetector) *linkExtractor {
 // Process is the encapsulation of the link extractor method
 // Search page content for a <base> tag and resolve it to an abs URL.
 // Find the unique set of links from the document, resolve them and
-// add them to the payload.
+// add them to the payload together with their anchor text and position.
 func (le *linkExtractor) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
 	payload := p.(*crawlerPayload)
 	relTo, err := url.Parse(payload.URL)
@@ -44,8 +53,8 @@ func (le *linkExtractor) Process(ctx context.Context, p pipeline.Payload) (pipel
 		}
 	}
 	seenMap := make(map[string]struct{})
-	for _, match := range findLinkRegex.FindAllStringSubmatch(content, -1) {
-		link := resolveURL(relTo, match[1])
+	for pos, loc := range findLinkRegex.FindAllStringSubmatchIndex(content, -1) {
+		link := resolveURL(relTo, content[loc[2]:loc[3]])
 		if !le.retainLink(relTo.Hostname(), link) {
 			continue
 		}
@@ -58,15 +67,32 @@ func (le *linkExtractor) Process(ctx context.Context, p pipeline.Payload) (pipel
 			continue
 		}
 		seenMap[linkStr] = struct{}{}
-		if nofollowRegex.MatchString(match[0]) {
-			payload.NoFollowLinks = append(payload.NoFollowLinks, linkStr)
-		} else {
-			payload.Links = append(payload.Links, linkStr)
-		}
+		payload.Links = append(payload.Links, extractedLink{
+			URL:        linkStr,
+			AnchorText: extractAnchorText(content[loc[1]:]),
+			NoFollow:   nofollowRegex.MatchString(content[loc[0]:loc[1]]),
+			Position:   pos,
+		})
 	}
 	return payload, nil
 }
 
+// extractAnchorText returns the text enclosed by an anchor element given the
+// content that follows its opening tag. The text ends at the closing tag or,
+// for malformed documents, at the next anchor element. Nested tags are
+// stripped, entities are unescaped and whitespace is collapsed.
+func extractAnchorText(content string) string {
+	if loc := anchorEndRegex.FindStringIndex(content); loc != nil {
+		content = content[:loc[0]]
+	}
+	text := html.UnescapeString(stripTagsRegex.ReplaceAllString(content, " "))
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > maxAnchorTextLen {
+		text = string([]rune(text)[:maxAnchorTextLen])
+	}
+	return text
+}
+
 // ensureHasTrailingSlash is check if the url has /
 func ensureHasTrailingSlash(s string) string {
 	if s[len(s)-1] != '/' {
@@ -110,8 +136,9 @@ func resolveURL(relTo *url.URL, target string) *url.URL {
 			target = relTo.Scheme + ":" + target
 		}
 	}
-	if targetURL, err := url.Parse(target); err != nil {
-		return relTo.ResolveReference(targetURL)
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil
 	}
-	return nil
+	return relTo.ResolveReference(targetURL)
 }
@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(LinkExtractorTestSuite))
+
+type LinkExtractorTestSuite struct{}
+
+func (s *LinkExtractorTestSuite) TestExtractAnchorText(c *gc.C) {
+	specs := []struct {
+		descr   string
+		content string
+		exp     string
+	}{
+		{
+			descr:   "plain text",
+			content: `Hello world</a><p>after</p>`,
+			exp:     "Hello world",
+		},
+		{
+			descr:   "nested tags",
+			content: `<span class="x">Hello</span> <b>bold</b><img src="a.png"/></a>`,
+			exp:     "Hello bold",
+		},
+		{
+			descr:   "entities",
+			content: `Fish &amp; chips &lt;3 &quot;caf&eacute;&quot;</a>`,
+			exp:     `Fish & chips <3 "café"`,
+		},
+		{
+			descr:   "collapsed whitespace",
+			content: "\n\t  spread\n  over\t\tlines  </A >",
+			exp:     "spread over lines",
+		},
+		{
+			descr:   "unterminated anchor",
+			content: `first<a href="/second">second</a>`,
+			exp:     "first",
+		},
+		{
+			descr:   "empty anchor",
+			content: `<img src="logo.png"></a>`,
+			exp:     "",
+		},
+		{
+			descr:   "truncated text",
+			content: strings.Repeat("é", maxAnchorTextLen+10) + "</a>",
+			exp:     strings.Repeat("é", maxAnchorTextLen),
+		},
+	}
+
+	for specIndex, spec := range specs {
+		c.Assert(extractAnchorText(spec.content), gc.Equals, spec.exp, gc.Commentf("[spec %d] %s", specIndex, spec.descr))
+	}
+}
+
+func (s *LinkExtractorTestSuite) TestProcess(c *gc.C) {
+	content := `<html>
+<head><base href="https://example.com/blog"></head>
+<body>
+  <a href="https://example.com/about">About <em>us</em></a>
+  <a href="//example.com/feed.css">Styles</a>
+  <a rel="nofollow" href="/sponsored">Our &amp; their sponsor</a>
+  <a href="post-1#comments">First post</a>
+  <a href="https://example.com/about">About again</a>
+  <a href="http://private.example.com/">Intranet</a>
+  <a href="mailto:me@example.com">Mail me</a>
+  <a href="https://other.example.org/" REL="NOFOLLOW">Elsewhere</a>
+</body>
+</html>`
+
+	le := newLinkExtractor(privateHostDetector{"private.example.com": true})
+	payload := &crawlerPayload{URL: "https://example.com/"}
+	_, _ = payload.RawContent.WriteString(content)
+
+	got, err := le.Process(context.Background(), payload)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.Equals, payload)
+	c.Assert(payload.Links, gc.DeepEquals, []extractedLink{
+		{URL: "https://example.com/about", AnchorText: "About us", Position: 0},
+		{URL: "https://example.com/sponsored", AnchorText: "Our & their sponsor", NoFollow: true, Position: 2},
+		{URL: "https://example.com/blog/post-1", AnchorText: "First post", Position: 3},
+		{URL: "https://other.example.org/", AnchorText: "Elsewhere", NoFollow: true, Position: 7},
+	})
+}
+
+func (s *LinkExtractorTestSuite) TestResolveURL(c *gc.C) {
+	relTo, err := url.Parse("https://example.com/a/b/")
+	c.Assert(err, gc.IsNil)
+	specs := []struct {
+		target string
+		exp    string
+	}{
+		{target: "//cdn.example.com/c", exp: "https://cdn.example.com/c"},
+		{target: "/c", exp: "https://example.com/c"},
+		{target: "c/d", exp: "https://example.com/a/b/c/d"},
+		{target: "../c", exp: "https://example.com/a/c"},
+		{target: "http://other.example.com/", exp: "http://other.example.com/"},
+	}
+	for specIndex, spec := range specs {
+		got := resolveURL(relTo, spec.target)
+		c.Assert(got, gc.NotNil, gc.Commentf("[spec %d] %s", specIndex, spec.target))
+		c.Assert(got.String(), gc.Equals, spec.exp, gc.Commentf("[spec %d] %s", specIndex, spec.target))
+	}
+
+	c.Assert(resolveURL(relTo, ""), gc.IsNil)
+	c.Assert(resolveURL(relTo, "http://[::1"), gc.IsNil)
+}
+
+// privateHostDetector reports the hosts in the map as private.
+type privateHostDetector map[string]bool
+
+func (d privateHostDetector) IsPrivate(host string) (bool, error) { return d[host], nil }
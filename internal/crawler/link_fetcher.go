@@ -2,42 +2,61 @@ package crawler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"log"
 	"net/url"
 	"strings"
+	"time"
 
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
 	"github.com/joshvoll/linkrus/internal/pipeline"
+	"golang.org/x/xerrors"
 )
 
 // linkFetcher definition
 type linkFetcher struct {
 	urlGetter   URLGetter
 	netDetector PrivateNetworkDetector
+	graph       Graph
+	recordErrFn func(error)
 }
 
 // newLinkFetcher is the private constructor method to get the LinkFetcher struct
-func newLinkFetcher(urlGetter URLGetter, netDetector PrivateNetworkDetector) *linkFetcher {
+func newLinkFetcher(urlGetter URLGetter, netDetector PrivateNetworkDetector, graph Graph, recordErrFn func(error)) *linkFetcher {
+	if recordErrFn == nil {
+		recordErrFn = func(err error) { log.Printf("crawler: %v", err) }
+	}
 	return &linkFetcher{
 		urlGetter:   urlGetter,
 		netDetector: netDetector,
+		graph:       graph,
+		recordErrFn: recordErrFn,
 	}
 }
 
 // Process implementes the pipeline.Payload interface
 // Skip the url that point to a file that cannot contain html content.
 // never crawl link in private network (e.g. local address), this is a security risk!
-// skip payloads for invalid http status code.
-// skip payloads for non-html page headers
+// record a failed fetch attempt for payloads with invalid http status code.
+// record the fetch outcome but skip payloads for non-html page headers
 func (lf *linkFetcher) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
 	payload := p.(*crawlerPayload)
 	if exclusionRegex.MatchString(payload.URL) {
 		return nil, nil
 	}
-	if isPrivate, err := lf.isPrivate(payload.URL); err != nil || isPrivate {
+	if isPrivate, err := lf.isPrivate(payload.URL); err != nil {
+		lf.recordFailure(ctx, payload, 0, err.Error())
+		return nil, nil
+	} else if isPrivate {
+		lf.recordFailure(ctx, payload, 0, "link points to a private network address")
 		return nil, nil
 	}
 	res, err := lf.urlGetter.Get(payload.URL)
 	if err != nil {
+		lf.recordFailure(ctx, payload, 0, err.Error())
 		return nil, nil
 	}
 	_, err = io.Copy(&payload.RawContent, res.Body)
@@ -45,15 +64,61 @@ func (lf *linkFetcher) Process(ctx context.Context, p pipeline.Payload) (pipelin
 	if err != nil {
 		return nil, err
 	}
+	payload.StatusCode = res.StatusCode
 	if res.StatusCode < 200 || res.StatusCode > 299 {
+		lf.recordFailure(ctx, payload, res.StatusCode, fmt.Sprintf("unexpected HTTP status code %d", res.StatusCode))
 		return nil, nil
 	}
+
+	hash := sha256.Sum256(payload.RawContent.Bytes())
+	payload.ContentHash = hex.EncodeToString(hash[:])
+	payload.RedirectURL = ""
+	if res.Request != nil && res.Request.URL != nil {
+		if finalURL := res.Request.URL.String(); finalURL != payload.URL {
+			payload.RedirectURL = finalURL
+		}
+	}
+	payload.FailureCount = 0
+
+	// Pages without HTML content do not go through the rest of the
+	// pipeline so their fetch outcome needs to be recorded here.
 	if contentType := res.Header.Get("Content-Type"); !strings.Contains(contentType, "html") {
+		if err := lf.recordFetch(ctx, payload.fetchedLink(time.Now())); err != nil {
+			lf.recordErrFn(xerrors.Errorf("record fetch of %q: %w", payload.URL, err))
+		}
 		return nil, nil
 	}
 	return payload, nil
 }
 
+// recordFailure updates the link graph entry for the payload link with the
+// details of a failed fetch attempt. Errors are reported to recordErrFn
+// rather than returned so that a single failed link does not abort the
+// crawl.
+func (lf *linkFetcher) recordFailure(ctx context.Context, payload *crawlerPayload, statusCode int, reason string) {
+	link := &graph.Link{
+		ID:           payload.LinkID,
+		URL:          payload.URL,
+		RetrievedAt:  time.Now(),
+		StatusCode:   statusCode,
+		ContentHash:  payload.ContentHash,
+		RedirectURL:  payload.RedirectURL,
+		FailureCount: payload.FailureCount + 1,
+		LastError:    reason,
+	}
+	if err := lf.recordFetch(ctx, link); err != nil {
+		lf.recordErrFn(xerrors.Errorf("record failed fetch of %q: %w", payload.URL, err))
+	}
+}
+
+// recordFetch persists the fetch metadata for link.
+func (lf *linkFetcher) recordFetch(ctx context.Context, link *graph.Link) error {
+	if lf.graph == nil {
+		return nil
+	}
+	return lf.graph.UpsertLinks(ctx, []*graph.Link{link})
+}
+
 // isPrivate check if the network is private or not
 // parse the url
 func (lf *linkFetcher) isPrivate(URL string) (bool, error) {
@@ -0,0 +1,263 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(LinkFetcherTestSuite))
+
+type LinkFetcherTestSuite struct{}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *LinkFetcherTestSuite) TestProcess(c *gc.C) {
+	const body = "<html>hello</html>"
+	hash := sha256.Sum256([]byte(body))
+	bodyHash := hex.EncodeToString(hash[:])
+
+	specs := []struct {
+		descr   string
+		url     string
+		res     *stubResponse
+		getErr  error
+		private bool
+
+		expPayload  bool
+		expStatus   int
+		expHash     string
+		expRedirect string
+		expRecorded *graph.Link
+	}{
+		{
+			descr:      "html page",
+			url:        "https://example.com/",
+			res:        &stubResponse{status: 200, contentType: "text/html", body: body},
+			expPayload: true,
+			expStatus:  200,
+			expHash:    bodyHash,
+		},
+		{
+			descr:       "redirected html page",
+			url:         "https://example.com/old",
+			res:         &stubResponse{status: 200, contentType: "text/html", body: body, finalURL: "https://example.com/new"},
+			expPayload:  true,
+			expStatus:   200,
+			expHash:     bodyHash,
+			expRedirect: "https://example.com/new",
+		},
+		{
+			descr:       "non-html page",
+			url:         "https://example.com/feed",
+			res:         &stubResponse{status: 200, contentType: "application/json", body: body},
+			expRecorded: &graph.Link{URL: "https://example.com/feed", StatusCode: 200, ContentHash: bodyHash},
+		},
+		{
+			descr: "unexpected status code",
+			url:   "https://example.com/missing",
+			res:   &stubResponse{status: 404, contentType: "text/html", body: body},
+			expRecorded: &graph.Link{
+				URL:          "https://example.com/missing",
+				StatusCode:   404,
+				ContentHash:  "stale-hash",
+				FailureCount: 3,
+				LastError:    "unexpected HTTP status code 404",
+			},
+		},
+		{
+			descr:  "get error",
+			url:    "https://example.com/timeout",
+			getErr: xerrors.New("connection timed out"),
+			expRecorded: &graph.Link{
+				URL:          "https://example.com/timeout",
+				ContentHash:  "stale-hash",
+				FailureCount: 3,
+				LastError:    "connection timed out",
+			},
+		},
+		{
+			descr:   "private network address",
+			url:     "http://10.0.0.1/",
+			private: true,
+			expRecorded: &graph.Link{
+				URL:          "http://10.0.0.1/",
+				ContentHash:  "stale-hash",
+				FailureCount: 3,
+				LastError:    "link points to a private network address",
+			},
+		},
+	}
+
+	for specIndex, spec := range specs {
+		c.Logf("[spec %d] %s", specIndex, spec.descr)
+
+		g := new(stubGraph)
+		lf := newLinkFetcher(
+			stubURLGetter{res: spec.res, err: spec.getErr},
+			stubNetDetector{private: spec.private},
+			g,
+			func(err error) { c.Fatalf("unexpected record error: %v", err) },
+		)
+		payload := &crawlerPayload{
+			LinkID:       uuid.New(),
+			URL:          spec.url,
+			ContentHash:  "stale-hash",
+			RedirectURL:  "https://example.com/stale",
+			FailureCount: 2,
+		}
+		got, err := lf.Process(context.Background(), payload)
+		c.Assert(err, gc.IsNil)
+		c.Assert(got != nil, gc.Equals, spec.expPayload)
+		if spec.expPayload {
+			c.Assert(payload.StatusCode, gc.Equals, spec.expStatus)
+			c.Assert(payload.ContentHash, gc.Equals, spec.expHash)
+			c.Assert(payload.RedirectURL, gc.Equals, spec.expRedirect)
+			c.Assert(payload.FailureCount, gc.Equals, 0)
+			c.Assert(payload.RawContent.String(), gc.Equals, body)
+		}
+
+		if spec.expRecorded == nil {
+			c.Assert(g.links, gc.HasLen, 0)
+			continue
+		}
+		c.Assert(g.links, gc.HasLen, 1)
+		recorded := g.links[0]
+		c.Assert(recorded.RetrievedAt.IsZero(), gc.Equals, false)
+		spec.expRecorded.ID = payload.LinkID
+		spec.expRecorded.RetrievedAt = recorded.RetrievedAt
+		if spec.expRecorded.FailureCount != 0 {
+			spec.expRecorded.RedirectURL = "https://example.com/stale"
+		}
+		c.Assert(recorded, gc.DeepEquals, spec.expRecorded)
+	}
+}
+
+func (s *LinkFetcherTestSuite) TestGraphErrorWhileRecordingFailure(c *gc.C) {
+	var recordErr error
+	lf := newLinkFetcher(
+		stubURLGetter{err: xerrors.New("connection refused")},
+		stubNetDetector{},
+		&stubGraph{err: xerrors.New("graph unavailable")},
+		func(err error) { recordErr = err },
+	)
+
+	got, err := lf.Process(context.Background(), &crawlerPayload{URL: "https://example.com/"})
+	c.Assert(err, gc.IsNil, gc.Commentf("graph errors must not abort the crawl"))
+	c.Assert(got, gc.IsNil)
+	c.Assert(recordErr, gc.ErrorMatches, `record failed fetch of "https://example.com/": graph unavailable`)
+}
+
+func (s *LinkFetcherTestSuite) TestGraphErrorWhileRecordingNonHTMLFetch(c *gc.C) {
+	var recordErr error
+	lf := newLinkFetcher(
+		stubURLGetter{res: &stubResponse{status: 200, contentType: "application/json", body: "{}"}},
+		stubNetDetector{},
+		&stubGraph{err: xerrors.New("graph unavailable")},
+		func(err error) { recordErr = err },
+	)
+
+	got, err := lf.Process(context.Background(), &crawlerPayload{URL: "https://example.com/feed"})
+	c.Assert(err, gc.IsNil, gc.Commentf("graph errors must not abort the crawl"))
+	c.Assert(got, gc.IsNil)
+	c.Assert(recordErr, gc.ErrorMatches, `record fetch of "https://example.com/feed": graph unavailable`)
+}
+
+func (s *LinkFetcherTestSuite) TestSkipExcludedExtensions(c *gc.C) {
+	g := new(stubGraph)
+	lf := newLinkFetcher(stubURLGetter{err: xerrors.New("unexpected fetch")}, stubNetDetector{}, g, nil)
+
+	got, err := lf.Process(context.Background(), &crawlerPayload{URL: "https://example.com/image.png"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.IsNil)
+	c.Assert(g.links, gc.HasLen, 0)
+}
+
+// stubResponse describes the response returned by a stubURLGetter.
+type stubResponse struct {
+	status      int
+	contentType string
+	body        string
+	finalURL    string
+}
+
+type stubURLGetter struct {
+	res *stubResponse
+	err error
+}
+
+func (g stubURLGetter) Get(reqURL string) (*http.Response, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	finalURL := reqURL
+	if g.res.finalURL != "" {
+		finalURL = g.res.finalURL
+	}
+	u, err := url.Parse(finalURL)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: g.res.status,
+		Header:     http.Header{"Content-Type": []string{g.res.contentType}},
+		Body:       ioutil.NopCloser(strings.NewReader(g.res.body)),
+		Request:    &http.Request{URL: u},
+	}, nil
+}
+
+type stubNetDetector struct {
+	private bool
+}
+
+func (d stubNetDetector) IsPrivate(string) (bool, error) { return d.private, nil }
+
+// stubGraph records the links and edges that are upserted into it and the
+// arguments of the last RemoveStalEdges call.
+type stubGraph struct {
+	links []*graph.Link
+	edges []*graph.Edge
+	err   error
+
+	staleEdgesFrom   uuid.UUID
+	staleEdgesBefore time.Time
+}
+
+func (g *stubGraph) UpsertLinks(_ context.Context, links []*graph.Link) error {
+	if g.err != nil {
+		return g.err
+	}
+	for _, link := range links {
+		if link.ID == uuid.Nil {
+			link.ID = uuid.New()
+		}
+		g.links = append(g.links, link)
+	}
+	return nil
+}
+
+func (g *stubGraph) UpsertEdges(_ context.Context, edges []*graph.Edge) error {
+	if g.err != nil {
+		return g.err
+	}
+	g.edges = append(g.edges, edges...)
+	return nil
+}
+
+func (g *stubGraph) RemoveStalEdges(_ context.Context, fromID uuid.UUID, updatedBefore time.Time) error {
+	g.staleEdgesFrom, g.staleEdgesBefore = fromID, updatedBefore
+	return g.err
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
 	"github.com/joshvoll/linkrus/internal/pipeline"
 )
 
@@ -26,12 +27,35 @@ type crawlerPayload struct {
 	RetrievedAt time.Time
 	RawContent  bytes.Buffer
 
-	// NoFollowLinks are still added to the graph but no outgoint edges
-	// will be created from this link to them
-	NoFollowLinks []string
-	Links         []string
-	Title         string
-	TextContext   string
+	// Metadata describing the outcome of fetching the link.
+	StatusCode   int
+	ContentHash  string
+	RedirectURL  string
+	FailureCount int
+
+	// Links contains the unique set of links found in the page content
+	// in order of appearance. NoFollow links are still added to the graph
+	// but the edges pointing to them are flagged as nofollow.
+	Links       []extractedLink
+	Title       string
+	TextContext string
+}
+
+// extractedLink describes a link that was found in the content of a page.
+type extractedLink struct {
+	// The absolute link URL.
+	URL string
+
+	// The text enclosed by the anchor element.
+	AnchorText string
+
+	// NoFollow is true if the anchor element carries a rel="nofollow"
+	// attribute.
+	NoFollow bool
+
+	// The zero-based ordinal position of the anchor element within the
+	// page.
+	Position int
 }
 
 // Clone implements the pipeline.Payload
@@ -40,8 +64,11 @@ func (p *crawlerPayload) Clone() pipeline.Payload {
 	newP.LinkID = p.LinkID
 	newP.URL = p.URL
 	newP.RetrievedAt = p.RetrievedAt
-	newP.NoFollowLinks = append([]string(nil), p.NoFollowLinks...)
-	newP.Links = append([]string(nil), p.Links...)
+	newP.StatusCode = p.StatusCode
+	newP.ContentHash = p.ContentHash
+	newP.RedirectURL = p.RedirectURL
+	newP.FailureCount = p.FailureCount
+	newP.Links = append([]extractedLink(nil), p.Links...)
 	newP.Title = p.Title
 	newP.TextContext = p.TextContext
 	_, err := io.Copy(&newP.RawContent, &p.RawContent)
@@ -54,10 +81,26 @@ func (p *crawlerPayload) Clone() pipeline.Payload {
 // MarkAsProcessed implementes the pipeline.Payload
 func (p *crawlerPayload) MarkAsProcessed() {
 	p.URL = p.URL[:0]
+	p.StatusCode = 0
+	p.ContentHash = p.ContentHash[:0]
+	p.RedirectURL = p.RedirectURL[:0]
+	p.FailureCount = 0
 	p.RawContent.Reset()
-	p.NoFollowLinks = p.NoFollowLinks[:0]
 	p.Links = p.Links[:0]
 	p.Title = p.Title[:0]
 	p.TextContext = p.TextContext[:0]
 	payloadPool.Put(p)
 }
+
+// fetchedLink returns a link graph entry for a successfully fetched payload
+// using retrievedAt as the retrieval timestamp.
+func (p *crawlerPayload) fetchedLink(retrievedAt time.Time) *graph.Link {
+	return &graph.Link{
+		ID:          p.LinkID,
+		URL:         p.URL,
+		RetrievedAt: retrievedAt,
+		StatusCode:  p.StatusCode,
+		ContentHash: p.ContentHash,
+		RedirectURL: p.RedirectURL,
+	}
+}
@@ -0,0 +1,244 @@
+package dbspgraph
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/aggregator"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"github.com/joshvoll/linkrus/internal/partition"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(DistributedGraphTestSuite))
+
+func init() {
+	RegisterType(maxValueMessage{})
+}
+
+type DistributedGraphTestSuite struct{}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *DistributedGraphTestSuite) TestMaxValuePropagation(c *gc.C) {
+	// Arrange vertices in a ring; each vertex ends up with the max value.
+	ids := make([]string, 30)
+	for i := range ids {
+		ids[i] = uuid.New().String()
+	}
+	sort.Strings(ids)
+	values := make(map[string]int)
+	edges := make(map[string]string)
+	for i, id := range ids {
+		values[id] = (i * 7) % len(ids)
+		edges[id] = ids[(i+1)%len(ids)]
+	}
+
+	masterRunner := &maxValueMasterRunner{}
+	results := make(map[string]int)
+	var resultsMu sync.Mutex
+	newWorkerRunner := func() JobRunner {
+		return &maxValueWorkerRunner{
+			values: values,
+			edges:  edges,
+			onComplete: func(g *bspgraph.Graph) {
+				resultsMu.Lock()
+				for id, v := range g.Vertices() {
+					results[id] = v.Value().(int)
+				}
+				resultsMu.Unlock()
+			},
+		}
+	}
+
+	err := runJob(c, masterRunner, 3, newWorkerRunner)
+	c.Assert(err, gc.IsNil)
+
+	c.Assert(results, gc.HasLen, len(ids))
+	for id, v := range results {
+		c.Assert(v, gc.Equals, len(ids)-1, gc.Commentf("vertex %s", id))
+	}
+	c.Assert(masterRunner.vertexCount, gc.Equals, len(ids))
+	c.Assert(masterRunner.maxValue, gc.Equals, len(ids)-1)
+	c.Assert(masterRunner.completed, gc.Equals, true)
+}
+
+func (s *DistributedGraphTestSuite) TestWorkerFailureAbortsJob(c *gc.C) {
+	masterRunner := &maxValueMasterRunner{}
+	values := make(map[string]int)
+	edges := make(map[string]string)
+	for i := 0; i < 10; i++ {
+		id := uuid.New().String()
+		values[id] = i
+		edges[id] = id
+	}
+	newWorkerRunner := func() JobRunner {
+		return &maxValueWorkerRunner{values: values, edges: edges, failAtStep: 1}
+	}
+
+	err := runJob(c, masterRunner, 2, newWorkerRunner)
+	c.Assert(err, gc.ErrorMatches, ".*job was aborted.*")
+	c.Assert(masterRunner.aborted, gc.Equals, true)
+	c.Assert(masterRunner.completed, gc.Equals, false)
+}
+
+func (s *DistributedGraphTestSuite) TestUnableToReserveWorkers(c *gc.C) {
+	m, err := NewMaster(MasterConfig{ListenAddress: "127.0.0.1:0", JobRunner: &maxValueMasterRunner{}})
+	c.Assert(err, gc.IsNil)
+	c.Assert(m.Start(), gc.IsNil)
+	defer func() { c.Assert(m.Close(), gc.IsNil) }()
+
+	err = m.RunJob(context.Background(), 1, 10*time.Millisecond)
+	c.Assert(xerrors.Is(err, ErrUnableToReserveWorkers), gc.Equals, true)
+}
+
+func (s *DistributedGraphTestSuite) TestCheckpointPolicyIsNotForwarded(c *gc.C) {
+	cb := bspgraph.ExecutorCallbacks{Checkpoint: &bspgraph.CheckpointPolicy{EverySteps: 1}}
+	c.Assert(new(masterJob).wrapCallbacks(cb).Checkpoint, gc.IsNil)
+	c.Assert(new(workerJob).wrapCallbacks(cb).Checkpoint, gc.IsNil)
+}
+
+// runJob starts a master and numWorkers in-process workers and runs a job.
+func runJob(c *gc.C, masterRunner JobRunner, numWorkers int, newWorkerRunner func() JobRunner) error {
+	m, err := NewMaster(MasterConfig{ListenAddress: "127.0.0.1:0", JobRunner: masterRunner})
+	c.Assert(err, gc.IsNil)
+	c.Assert(m.Start(), gc.IsNil)
+	defer func() { c.Assert(m.Close(), gc.IsNil) }()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFn()
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		w, err := NewWorker(WorkerConfig{MasterAddress: m.Addr(), JobRunner: newWorkerRunner()})
+		c.Assert(err, gc.IsNil)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = w.RunJob(ctx)
+		}()
+	}
+
+	err = m.RunJob(ctx, numWorkers, time.Second)
+	wg.Wait()
+	return err
+}
+
+type maxValueMessage struct {
+	Value int
+}
+
+func (maxValueMessage) Type() string { return "max_value" }
+
+func registerMaxValueAggregators(g *bspgraph.Graph) {
+	g.RegisterAggregator("vertex_count", new(aggregator.IntAccumulator))
+	g.RegisterAggregator("max_value", new(aggregator.IntMax))
+}
+
+type maxValueMasterRunner struct {
+	g           *bspgraph.Graph
+	vertexCount int
+	maxValue    int
+	completed   bool
+	aborted     bool
+}
+
+func (r *maxValueMasterRunner) StartJob(_ context.Context, _ JobDetails) (*bspgraph.Graph, bspgraph.ExecutorCallbacks, error) {
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn: func(*bspgraph.Graph, *bspgraph.Vertex, message.Iterator) error { return nil },
+	})
+	if err != nil {
+		return nil, bspgraph.ExecutorCallbacks{}, err
+	}
+	registerMaxValueAggregators(g)
+	r.g = g
+	return g, bspgraph.ExecutorCallbacks{
+		PostStepKeepRunning: func(_ context.Context, _ *bspgraph.Graph, activeInStep int) (bool, error) {
+			return activeInStep != 0, nil
+		},
+	}, nil
+}
+
+func (r *maxValueMasterRunner) CompleteJob(context.Context, JobDetails) error {
+	r.vertexCount = r.g.Aggregator("vertex_count").Get().(int)
+	r.maxValue = r.g.Aggregator("max_value").Get().(int)
+	r.completed = true
+	return r.g.Close()
+}
+
+func (r *maxValueMasterRunner) AbortJob(JobDetails) {
+	r.aborted = true
+	_ = r.g.Close()
+}
+
+type maxValueWorkerRunner struct {
+	values     map[string]int
+	edges      map[string]string
+	failAtStep int
+	onComplete func(*bspgraph.Graph)
+
+	g *bspgraph.Graph
+}
+
+func (r *maxValueWorkerRunner) StartJob(_ context.Context, details JobDetails) (*bspgraph.Graph, bspgraph.ExecutorCallbacks, error) {
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{ComputeFn: r.compute})
+	if err != nil {
+		return nil, bspgraph.ExecutorCallbacks{}, err
+	}
+	registerMaxValueAggregators(g)
+	for id, val := range r.values {
+		if partition.Contains(uuid.MustParse(id), details.PartitionFromID, details.PartitionToID) {
+			g.AddVertex(id, val)
+		}
+	}
+	for id := range g.Vertices() {
+		if err = g.AddEdge(id, r.edges[id], nil); err != nil {
+			return nil, bspgraph.ExecutorCallbacks{}, err
+		}
+	}
+	r.g = g
+	return g, bspgraph.ExecutorCallbacks{}, nil
+}
+
+func (r *maxValueWorkerRunner) compute(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+	if r.failAtStep != 0 && g.Superstep() == r.failAtStep {
+		return xerrors.New("boom")
+	}
+	if g.Superstep() == 0 {
+		g.Aggregator("vertex_count").Aggregate(1)
+		g.Aggregator("max_value").Aggregate(v.Value().(int))
+		return g.BroadcastToNeighbors(context.Background(), v, maxValueMessage{Value: v.Value().(int)})
+	}
+
+	changed := false
+	for msgIt.Next() {
+		if val := msgIt.Message().(maxValueMessage).Value; val > v.Value().(int) {
+			v.SetValue(val)
+			changed = true
+		}
+	}
+	if changed {
+		return g.BroadcastToNeighbors(context.Background(), v, maxValueMessage{Value: v.Value().(int)})
+	}
+	v.Freeze()
+	return nil
+}
+
+func (r *maxValueWorkerRunner) CompleteJob(context.Context, JobDetails) error {
+	if r.onComplete != nil {
+		r.onComplete(r.g)
+	}
+	return r.g.Close()
+}
+
+func (r *maxValueWorkerRunner) AbortJob(JobDetails) {
+	_ = r.g.Close()
+}
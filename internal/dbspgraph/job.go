@@ -0,0 +1,57 @@
+package dbspgraph
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+)
+
+// JobDetails encapsulates the information about a job that is executed by
+// the master and a set of workers.
+type JobDetails struct {
+	// JobID is a unique ID for the job.
+	JobID string
+
+	// CreatedAt is the timestamp when the job was created.
+	CreatedAt time.Time
+
+	// PartitionFromID and PartitionToID define the [from, to) UUID range
+	// that is assigned to a worker. For the master, the range covers the
+	// entire UUID space.
+	PartitionFromID uuid.UUID
+	PartitionToID   uuid.UUID
+}
+
+// JobRunner is implemented by types that can set up and tear down the local
+// state for executing a distributed graph job. Both the master and the
+// workers use a JobRunner.
+type JobRunner interface {
+	// StartJob is invoked when a new job is assigned. It must return a
+	// graph instance and the executor callbacks to use for the job.
+	//
+	// Workers must return a graph populated with the vertices and edges
+	// that belong to the partition described by the job details. Vertex
+	// IDs must be UUID values so messages can be routed to the worker
+	// that owns each vertex.
+	//
+	// The master must return a graph with no vertices that has the same
+	// set of aggregators as the worker graphs registered. The master
+	// callbacks are invoked with the number of vertices that were active
+	// across all workers and their PostStepKeepRunning callback decides
+	// whether the job should keep running.
+	//
+	// Distributed jobs cannot be resumed from a checkpoint as the
+	// in-flight relayed messages and the master state are not persisted;
+	// the Checkpoint policy of the returned callbacks is therefore ignored.
+	StartJob(ctx context.Context, details JobDetails) (*bspgraph.Graph, bspgraph.ExecutorCallbacks, error)
+
+	// CompleteJob is invoked once the job has run to completion. Workers
+	// can use it to persist the computation results.
+	CompleteJob(ctx context.Context, details JobDetails) error
+
+	// AbortJob is invoked when the job cannot be completed due to an
+	// error.
+	AbortJob(details JobDetails)
+}
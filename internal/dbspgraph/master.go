@@ -0,0 +1,402 @@
+package dbspgraph
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/partition"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// ErrUnableToReserveWorkers is returned by the master when the
+	// requested number of workers did not connect before the worker
+	// acquire timeout expired.
+	ErrUnableToReserveWorkers = xerrors.New("unable to reserve required number of workers")
+
+	// ErrJobAborted is returned when a job is aborted by the remote end.
+	ErrJobAborted = xerrors.New("job was aborted")
+
+	// ErrUnexpectedEnvelope is returned when an envelope of an unexpected
+	// type is received.
+	ErrUnexpectedEnvelope = xerrors.New("unexpected envelope")
+
+	// ErrMasterClosed is returned when attempting to run a job on a master
+	// that has been closed.
+	ErrMasterClosed = xerrors.New("master has been closed")
+)
+
+// MasterConfig encapsulates the configuration options for creating a new
+// Master instance.
+type MasterConfig struct {
+	// ListenAddress is the address where the master listens for incoming
+	// worker connections.
+	ListenAddress string
+
+	// JobRunner sets up the master-side state for each job.
+	JobRunner JobRunner
+}
+
+// validate checks whether a master configuration is valid.
+func (cfg *MasterConfig) validate() error {
+	var err error
+	if cfg.ListenAddress == "" {
+		err = multierror.Append(err, xerrors.New("listen address not specified"))
+	}
+	if cfg.JobRunner == nil {
+		err = multierror.Append(err, xerrors.New("job runner not specified"))
+	}
+	return err
+}
+
+// Master coordinates the execution of graph jobs across a set of workers.
+// The master splits the UUID space into one partition per worker, routes
+// messages between workers and synchronizes the workers at the end of each
+// superstep where the aggregator deltas from each worker are reduced into a
+// global value.
+type Master struct {
+	cfg      MasterConfig
+	listener net.Listener
+	workerCh chan *conn
+	doneCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewMaster returns a new Master instance using the specified configuration.
+func NewMaster(cfg MasterConfig) (*Master, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("master config validation failed: %w", err)
+	}
+	return &Master{
+		cfg:      cfg,
+		workerCh: make(chan *conn),
+		doneCh:   make(chan struct{}),
+	}, nil
+}
+
+// Start listens for incoming worker connections.
+func (m *Master) Start() error {
+	l, err := net.Listen("tcp", m.cfg.ListenAddress)
+	if err != nil {
+		return xerrors.Errorf("master start: %w", err)
+	}
+	m.listener = l
+	m.wg.Add(1)
+	go m.acceptLoop()
+	return nil
+}
+
+// Addr returns the address where the master listens for worker connections.
+func (m *Master) Addr() string {
+	return m.listener.Addr().String()
+}
+
+// Close shuts down the master and drops any connected workers that have not
+// been assigned a job.
+func (m *Master) Close() error {
+	close(m.doneCh)
+	err := m.listener.Close()
+	m.wg.Wait()
+	return err
+}
+
+// acceptLoop accepts worker connections and queues them until a job
+// reserves them.
+func (m *Master) acceptLoop() {
+	defer m.wg.Done()
+	for {
+		netConn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+		select {
+		case m.workerCh <- newConn(netConn):
+		case <-m.doneCh:
+			_ = netConn.Close()
+			return
+		}
+	}
+}
+
+// RunJob waits for numWorkers workers to connect, assigns each one a
+// partition of the UUID space and coordinates the execution of the job until
+// it completes or an error occurs. If the required number of workers do not
+// connect within workerAcquireTimeout, RunJob returns
+// ErrUnableToReserveWorkers.
+func (m *Master) RunJob(ctx context.Context, numWorkers int, workerAcquireTimeout time.Duration) error {
+	workers, err := m.reserveWorkers(ctx, numWorkers, workerAcquireTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, w := range workers {
+			_ = w.Close()
+		}
+	}()
+
+	partRange, err := partition.NewFullRange(numWorkers)
+	if err != nil {
+		return xerrors.Errorf("run job: %w", err)
+	}
+	details := JobDetails{
+		JobID:           uuid.New().String(),
+		CreatedAt:       time.Now(),
+		PartitionFromID: uuid.Nil,
+		PartitionToID:   partition.MaxUUID,
+	}
+
+	job := &masterJob{
+		details:   details,
+		runner:    m.cfg.JobRunner,
+		workers:   workers,
+		partRange: partRange,
+		barrierCh: make(chan *envelope, numWorkers),
+		doneCh:    make(chan struct{}, numWorkers),
+		errCh:     make(chan error, numWorkers),
+	}
+	if err = job.run(ctx); err != nil {
+		job.abort(err)
+		return err
+	}
+	return nil
+}
+
+// reserveWorkers waits for numWorkers workers to connect.
+func (m *Master) reserveWorkers(ctx context.Context, numWorkers int, timeout time.Duration) ([]*conn, error) {
+	var (
+		workers = make([]*conn, 0, numWorkers)
+		timer   = time.NewTimer(timeout)
+		err     error
+	)
+	defer timer.Stop()
+	for len(workers) < numWorkers && err == nil {
+		select {
+		case w := <-m.workerCh:
+			workers = append(workers, w)
+		case <-timer.C:
+			err = ErrUnableToReserveWorkers
+		case <-m.doneCh:
+			err = ErrMasterClosed
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		for _, w := range workers {
+			_ = w.Close()
+		}
+		return nil, xerrors.Errorf("reserve workers: %w", err)
+	}
+	return workers, nil
+}
+
+// masterJob encapsulates the state of a job that is coordinated by the
+// master.
+type masterJob struct {
+	details   JobDetails
+	runner    JobRunner
+	workers   []*conn
+	partRange partition.Range
+
+	barrierCh chan *envelope
+	doneCh    chan struct{}
+	errCh     chan error
+
+	keepRunning bool
+}
+
+// run assigns the job to the workers and drives the execution of the job
+// until it completes.
+func (j *masterJob) run(ctx context.Context) error {
+	g, cb, err := j.runner.StartJob(ctx, j.details)
+	if err != nil {
+		return xerrors.Errorf("start job: %w", err)
+	}
+
+	for partIdx, w := range j.workers {
+		workerDetails := j.details
+		if workerDetails.PartitionFromID, workerDetails.PartitionToID, err = j.partRange.PartitionExtents(partIdx); err != nil {
+			return xerrors.Errorf("assign partition: %w", err)
+		}
+		if err = w.send(&envelope{Type: envJobDetails, Job: &workerDetails}); err != nil {
+			return xerrors.Errorf("assign partition: %w", err)
+		}
+	}
+
+	ctx, cancelFn := context.WithCancel(ctx)
+	defer cancelFn()
+	for _, w := range j.workers {
+		go j.recvLoop(ctx, w)
+	}
+
+	if err = bspgraph.NewExecutor(g, j.wrapCallbacks(cb)).RunToCompletion(ctx); err != nil {
+		return xerrors.Errorf("run job: %w", err)
+	}
+
+	// Wait for all workers to complete the job.
+	for completed := 0; completed < len(j.workers); completed++ {
+		select {
+		case <-j.doneCh:
+		case err = <-j.errCh:
+			return xerrors.Errorf("complete job: %w", err)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err = j.runner.CompleteJob(ctx, j.details); err != nil {
+		return xerrors.Errorf("complete job: %w", err)
+	}
+	return nil
+}
+
+// abort notifies the workers and the job runner that the job has failed.
+func (j *masterJob) abort(err error) {
+	for _, w := range j.workers {
+		_ = w.send(&envelope{Type: envError, Error: err.Error()})
+	}
+	j.runner.AbortJob(j.details)
+}
+
+// wrapCallbacks wraps the executor callbacks returned by the job runner so
+// that the master waits for all workers to reach the step barrier, reduces
+// their aggregator deltas and invokes the runner's callbacks with the global
+// active vertex count.
+func (j *masterJob) wrapCallbacks(cb bspgraph.ExecutorCallbacks) bspgraph.ExecutorCallbacks {
+	return bspgraph.ExecutorCallbacks{
+		PreStep: cb.PreStep,
+		PostStep: func(ctx context.Context, g *bspgraph.Graph, _ int) error {
+			activeInStep, err := j.stepBarrier(ctx, g)
+			if err != nil {
+				return err
+			}
+			if cb.PostStep != nil {
+				if err = cb.PostStep(ctx, g, activeInStep); err != nil {
+					return err
+				}
+			}
+			j.keepRunning = true
+			if cb.PostStepKeepRunning != nil {
+				if j.keepRunning, err = cb.PostStepKeepRunning(ctx, g, activeInStep); err != nil {
+					return err
+				}
+			}
+			return j.ackStepBarrier(g, activeInStep)
+		},
+		PostStepKeepRunning: func(context.Context, *bspgraph.Graph, int) (bool, error) {
+			return j.keepRunning, nil
+		},
+	}
+}
+
+// stepBarrier waits for all workers to reach the step barrier and reduces
+// their aggregator deltas into the master's aggregators. It returns the total
+// number of active vertices across all workers.
+func (j *masterJob) stepBarrier(ctx context.Context, g *bspgraph.Graph) (int, error) {
+	var activeInStep int
+	for pending := len(j.workers); pending > 0; pending-- {
+		select {
+		case env := <-j.barrierCh:
+			if env.Superstep != g.Superstep() {
+				return 0, xerrors.Errorf("step barrier: %w", ErrUnexpectedEnvelope)
+			}
+			activeInStep += env.ActiveInStep
+			for name, delta := range env.Aggregators {
+				if aggr := g.Aggregator(name); aggr != nil {
+					aggr.Aggregate(delta)
+				}
+			}
+		case err := <-j.errCh:
+			return 0, xerrors.Errorf("step barrier: %w", err)
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	return activeInStep, nil
+}
+
+// ackStepBarrier releases the workers from the step barrier and sends them
+// the global aggregator values.
+func (j *masterJob) ackStepBarrier(g *bspgraph.Graph, activeInStep int) error {
+	values := make(map[string]interface{})
+	for name, aggr := range g.Aggregators() {
+		values[name] = aggr.Get()
+	}
+	ack := &envelope{
+		Type:         envStepBarrierAck,
+		Superstep:    g.Superstep(),
+		ActiveInStep: activeInStep,
+		KeepRunning:  j.keepRunning,
+		Aggregators:  values,
+	}
+	for _, w := range j.workers {
+		if err := w.send(ack); err != nil {
+			return xerrors.Errorf("step barrier ack: %w", err)
+		}
+	}
+	return nil
+}
+
+// recvLoop processes the envelopes sent by a worker until the worker
+// completes the job, the connection is closed or an error occurs.
+func (j *masterJob) recvLoop(ctx context.Context, w *conn) {
+	for {
+		env, err := w.recv()
+		if err != nil {
+			j.emitError(ctx, xerrors.Errorf("connection to worker: %w", err))
+			return
+		}
+
+		switch env.Type {
+		case envRelay:
+			if err = j.relay(env); err != nil {
+				j.emitError(ctx, err)
+				return
+			}
+		case envStepBarrier:
+			select {
+			case j.barrierCh <- env:
+			case <-ctx.Done():
+				return
+			}
+		case envJobCompleted:
+			j.doneCh <- struct{}{}
+			return
+		case envError:
+			j.emitError(ctx, xerrors.Errorf("%s: %w", env.Error, ErrJobAborted))
+			return
+		default:
+			j.emitError(ctx, ErrUnexpectedEnvelope)
+			return
+		}
+	}
+}
+
+// relay forwards a relayed message to the worker that owns the destination
+// vertex.
+func (j *masterJob) relay(env *envelope) error {
+	dstID, err := uuid.Parse(env.DstID)
+	if err != nil {
+		return xerrors.Errorf("relay message to %q: %w", env.DstID, bspgraph.ErrInvalidMessageDestination)
+	}
+	partIdx, err := j.partRange.PartitionForID(dstID)
+	if err != nil {
+		return xerrors.Errorf("relay message to %q: %w", env.DstID, err)
+	}
+	if err = j.workers[partIdx].send(env); err != nil {
+		return xerrors.Errorf("relay message to %q: %w", env.DstID, err)
+	}
+	return nil
+}
+
+// emitError queues an error unless the job has already terminated.
+func (j *masterJob) emitError(ctx context.Context, err error) {
+	select {
+	case j.errCh <- err:
+	case <-ctx.Done():
+	}
+}
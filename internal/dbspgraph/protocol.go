@@ -0,0 +1,112 @@
+package dbspgraph
+
+import (
+	"encoding/gob"
+	"net"
+	"sync"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph/aggregator"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+)
+
+func init() {
+	RegisterType([]aggregator.TopNEntry(nil))
+}
+
+// RegisterType registers the concrete type of value so it can be exchanged
+// between the master and the workers. All message types used by a job and
+// any aggregator value types that are not basic Go types must be registered
+// on both the master and the workers before running a job.
+func RegisterType(value interface{}) {
+	gob.Register(value)
+}
+
+// envelopeType describes the type of a protocol envelope.
+type envelopeType uint8
+
+const (
+	// envJobDetails is sent by the master to assign a job to a worker.
+	envJobDetails envelopeType = iota
+
+	// envRelay carries a message for a vertex that is owned by another
+	// worker together with the superstep where it was sent. Workers send
+	// relay envelopes to the master which forwards them to the worker that
+	// owns the destination vertex.
+	envRelay
+
+	// envStepBarrier is sent by the workers after executing a superstep.
+	// It carries the local number of active vertices and the aggregator
+	// deltas for the step.
+	envStepBarrier
+
+	// envStepBarrierAck is sent by the master once all workers have
+	// reached the step barrier. It carries the global number of active
+	// vertices, the global aggregator values and whether the job should
+	// keep running.
+	envStepBarrierAck
+
+	// envJobCompleted is sent by the workers once they have successfully
+	// completed a job.
+	envJobCompleted
+
+	// envError notifies the remote end that the job must be aborted.
+	envError
+)
+
+// envelope is the unit of data exchanged between the master and the workers.
+type envelope struct {
+	Type envelopeType
+
+	Job *JobDetails
+
+	Superstep    int
+	ActiveInStep int
+	KeepRunning  bool
+	Aggregators  map[string]interface{}
+
+	DstID   string
+	Message message.Message
+
+	Error string
+}
+
+// conn wraps a net.Conn and provides methods for exchanging envelopes. Calls
+// to send are safe for concurrent use; recv must only be invoked by a single
+// goroutine.
+type conn struct {
+	mu  sync.Mutex
+	c   net.Conn
+	enc *gob.Encoder
+	dec *gob.Decoder
+}
+
+// newConn wraps c into a conn instance.
+func newConn(c net.Conn) *conn {
+	return &conn{
+		c:   c,
+		enc: gob.NewEncoder(c),
+		dec: gob.NewDecoder(c),
+	}
+}
+
+// send writes an envelope to the connection.
+func (c *conn) send(env *envelope) error {
+	c.mu.Lock()
+	err := c.enc.Encode(env)
+	c.mu.Unlock()
+	return err
+}
+
+// recv reads the next envelope from the connection.
+func (c *conn) recv() (*envelope, error) {
+	env := new(envelope)
+	if err := c.dec.Decode(env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Close shuts down the underlying connection.
+func (c *conn) Close() error {
+	return c.c.Close()
+}
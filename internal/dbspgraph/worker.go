@@ -0,0 +1,311 @@
+package dbspgraph
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"github.com/joshvoll/linkrus/internal/partition"
+	"golang.org/x/xerrors"
+)
+
+// WorkerConfig encapsulates the configuration options for creating a new
+// Worker instance.
+type WorkerConfig struct {
+	// MasterAddress is the address of the master node.
+	MasterAddress string
+
+	// JobRunner sets up the local graph for each job that is assigned to
+	// the worker.
+	JobRunner JobRunner
+}
+
+// validate checks whether a worker configuration is valid.
+func (cfg *WorkerConfig) validate() error {
+	var err error
+	if cfg.MasterAddress == "" {
+		err = multierror.Append(err, xerrors.New("master address not specified"))
+	}
+	if cfg.JobRunner == nil {
+		err = multierror.Append(err, xerrors.New("job runner not specified"))
+	}
+	return err
+}
+
+// Worker connects to a master node and executes the jobs assigned to it on a
+// local partition of the graph.
+type Worker struct {
+	cfg WorkerConfig
+}
+
+// NewWorker returns a new Worker instance using the specified configuration.
+func NewWorker(cfg WorkerConfig) (*Worker, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("worker config validation failed: %w", err)
+	}
+	return &Worker{cfg: cfg}, nil
+}
+
+// RunJob connects to the master, waits for a job to be assigned and executes
+// it. Calls to RunJob block until the job completes, an error occurs or the
+// provided context expires.
+func (w *Worker) RunJob(ctx context.Context) error {
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", w.cfg.MasterAddress)
+	if err != nil {
+		return xerrors.Errorf("dial master: %w", err)
+	}
+	c := newConn(netConn)
+	defer func() { _ = c.Close() }()
+
+	// Unblock any pending reads if the context expires.
+	ctx, cancelFn := context.WithCancel(ctx)
+	defer cancelFn()
+	go func() {
+		<-ctx.Done()
+		_ = c.Close()
+	}()
+
+	env, err := c.recv()
+	if err != nil {
+		return xerrors.Errorf("wait for job details: %w", err)
+	} else if env.Type != envJobDetails || env.Job == nil {
+		return xerrors.Errorf("wait for job details: %w", ErrUnexpectedEnvelope)
+	}
+
+	job := &workerJob{
+		c:       c,
+		details: *env.Job,
+		runner:  w.cfg.JobRunner,
+		ackCh:   make(chan *envelope, 1),
+		doneCh:  make(chan struct{}),
+	}
+	if err = job.run(ctx); err != nil {
+		_ = c.send(&envelope{Type: envError, Error: err.Error()})
+		return err
+	}
+	return nil
+}
+
+// workerJob encapsulates the state of a job that is executed by a worker.
+type workerJob struct {
+	c       *conn
+	details JobDetails
+	runner  JobRunner
+	g       *bspgraph.Graph
+
+	ackCh  chan *envelope
+	doneCh chan struct{}
+
+	mu      sync.Mutex
+	recvErr error
+
+	// step is the superstep that is currently executed by the local
+	// graph. Relayed messages that were sent by other workers while
+	// executing a later superstep are kept in pendingRelays until the
+	// local graph catches up.
+	step          int
+	pendingRelays []*envelope
+}
+
+// run executes the job until it completes or an error occurs.
+func (j *workerJob) run(ctx context.Context) error {
+	g, cb, err := j.runner.StartJob(ctx, j.details)
+	if err != nil {
+		return xerrors.Errorf("start job: %w", err)
+	}
+	j.g = g
+	j.step = -1
+	g.RegisterRelayer(&relayer{
+		c:      j.c,
+		g:      g,
+		fromID: j.details.PartitionFromID,
+		toID:   j.details.PartitionToID,
+	})
+
+	exec := bspgraph.NewExecutor(g, j.wrapCallbacks(cb))
+	go j.recvLoop(ctx)
+	if err = exec.RunToCompletion(ctx); err != nil {
+		j.runner.AbortJob(j.details)
+		return xerrors.Errorf("run job: %w", err)
+	}
+	if err = j.runner.CompleteJob(ctx, j.details); err != nil {
+		return xerrors.Errorf("complete job: %w", err)
+	}
+	if err = j.c.send(&envelope{Type: envJobCompleted}); err != nil {
+		return xerrors.Errorf("complete job: %w", err)
+	}
+	return nil
+}
+
+// wrapCallbacks wraps the executor callbacks returned by the job runner so
+// that each superstep ends with a barrier where the aggregator deltas are
+// reduced on the master and the master decides whether the job should keep
+// running.
+func (j *workerJob) wrapCallbacks(cb bspgraph.ExecutorCallbacks) bspgraph.ExecutorCallbacks {
+	var keepRunning bool
+	return bspgraph.ExecutorCallbacks{
+		PreStep: func(ctx context.Context, g *bspgraph.Graph) error {
+			if err := j.advanceStep(ctx, g); err != nil {
+				return err
+			}
+			if cb.PreStep != nil {
+				return cb.PreStep(ctx, g)
+			}
+			return nil
+		},
+		PostStep: func(ctx context.Context, g *bspgraph.Graph, activeInStep int) error {
+			ack, err := j.stepBarrier(ctx, g, activeInStep)
+			if err != nil {
+				return err
+			}
+			for name, val := range ack.Aggregators {
+				if aggr := g.Aggregator(name); aggr != nil {
+					aggr.Set(val)
+				}
+			}
+			keepRunning = ack.KeepRunning
+			if cb.PostStep != nil {
+				return cb.PostStep(ctx, g, ack.ActiveInStep)
+			}
+			return nil
+		},
+		PostStepKeepRunning: func(context.Context, *bspgraph.Graph, int) (bool, error) {
+			return keepRunning, nil
+		},
+	}
+}
+
+// stepBarrier sends the local step results to the master and blocks until the
+// master acknowledges that all workers have completed the step.
+func (j *workerJob) stepBarrier(ctx context.Context, g *bspgraph.Graph, activeInStep int) (*envelope, error) {
+	deltas := make(map[string]interface{})
+	for name, aggr := range g.Aggregators() {
+		deltas[name] = aggr.Delta()
+	}
+	err := j.c.send(&envelope{
+		Type:         envStepBarrier,
+		Superstep:    g.Superstep(),
+		ActiveInStep: activeInStep,
+		Aggregators:  deltas,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("step barrier: %w", err)
+	}
+
+	select {
+	case ack := <-j.ackCh:
+		if ack.Superstep != g.Superstep() {
+			return nil, xerrors.Errorf("step barrier: %w", ErrUnexpectedEnvelope)
+		}
+		return ack, nil
+	case <-j.doneCh:
+		return nil, xerrors.Errorf("step barrier: %w", j.recvError())
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// recvLoop processes the envelopes sent by the master until the connection
+// is closed or an error occurs.
+func (j *workerJob) recvLoop(ctx context.Context) {
+	defer close(j.doneCh)
+	for {
+		env, err := j.c.recv()
+		if err != nil {
+			j.setRecvError(xerrors.Errorf("connection to master: %w", err))
+			return
+		}
+
+		switch env.Type {
+		case envRelay:
+			if err = j.deliverRelay(ctx, env); err != nil {
+				j.setRecvError(err)
+				return
+			}
+		case envStepBarrierAck:
+			select {
+			case j.ackCh <- env:
+			case <-ctx.Done():
+				return
+			}
+		case envError:
+			j.setRecvError(xerrors.Errorf("%s: %w", env.Error, ErrJobAborted))
+			return
+		default:
+			j.setRecvError(ErrUnexpectedEnvelope)
+			return
+		}
+	}
+}
+
+// advanceStep records that the local graph is about to execute the next
+// superstep and delivers any relayed messages that were sent by other workers
+// while executing the previous superstep.
+func (j *workerJob) advanceStep(ctx context.Context, g *bspgraph.Graph) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.step = g.Superstep()
+	for _, env := range j.pendingRelays {
+		if err := g.DeliverMessage(ctx, env.DstID, env.Message); err != nil {
+			return xerrors.Errorf("deliver relayed message: %w", err)
+		}
+	}
+	j.pendingRelays = j.pendingRelays[:0]
+	return nil
+}
+
+// deliverRelay enqueues a relayed message to the local graph. Messages are
+// delivered right away if the sender was executing the same superstep as the
+// local graph; otherwise they are buffered until the local graph advances to
+// the superstep of the sender.
+func (j *workerJob) deliverRelay(ctx context.Context, env *envelope) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if env.Superstep != j.step {
+		j.pendingRelays = append(j.pendingRelays, env)
+		return nil
+	}
+	if err := j.g.DeliverMessage(ctx, env.DstID, env.Message); err != nil {
+		return xerrors.Errorf("deliver relayed message: %w", err)
+	}
+	return nil
+}
+
+func (j *workerJob) setRecvError(err error) {
+	j.mu.Lock()
+	j.recvErr = err
+	j.mu.Unlock()
+}
+
+func (j *workerJob) recvError() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.recvErr
+}
+
+// relayer implements bspgraph.Relayer by forwarding messages for vertices
+// outside the local partition to the master.
+type relayer struct {
+	c            *conn
+	g            *bspgraph.Graph
+	fromID, toID uuid.UUID
+}
+
+// Relay implements bspgraph.Relayer.
+func (r *relayer) Relay(ctx context.Context, dst string, msg message.Message) error {
+	dstID, err := uuid.Parse(dst)
+	if err != nil || partition.Contains(dstID, r.fromID, r.toID) {
+		return bspgraph.ErrDestinationIsLocal
+	}
+	return r.c.send(&envelope{
+		Type:      envRelay,
+		Superstep: r.g.Superstep(),
+		DstID:     dst,
+		Message:   msg,
+	})
+}
@@ -0,0 +1,326 @@
+package graphloader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"github.com/joshvoll/linkrus/internal/partition"
+	"golang.org/x/xerrors"
+)
+
+// LinkGraph is implemented by objects that can provide partitioned access to
+// the links and edges of a link graph.
+type LinkGraph interface {
+	// Links returns an iterator for the set of links whose IDs belong to
+	// the [fromID, toID) range and were retrieved before the provided
+	// timestamp.
+	Links(ctx context.Context, fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error)
+
+	// Edges returns an iterator for the set of edges whose source vertex
+	// IDs belong to the [fromID, toID) range and were updated before the
+	// provided timestamp.
+	Edges(ctx context.Context, fromID, toID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error)
+}
+
+// Config encapsulates the configuration options for creating a Loader.
+type Config struct {
+	// LinkGraph is the source of the links and edges to load. A valid
+	// LinkGraph instance is required for the config to be valid.
+	LinkGraph LinkGraph
+
+	// VertexValueFn, if specified, is invoked for each loaded link to
+	// obtain the initial value of the corresponding vertex. If not
+	// specified, vertices are initialized with a nil value.
+	VertexValueFn func(*graph.Link) interface{}
+
+	// EdgeValueFn, if specified, is invoked for each loaded edge to
+	// obtain the initial value of the corresponding bspgraph edge. If not
+	// specified, edges are initialized with a nil value.
+	EdgeValueFn func(*graph.Edge) interface{}
+
+	// IncludeNoFollowEdges controls whether edges flagged as nofollow are
+	// added to the graph. By default, they are skipped.
+	IncludeNoFollowEdges bool
+
+	// SkipRemoteEdges controls whether edges whose destination lies
+	// outside the loaded range are skipped. It must be set when the graph
+	// does not exchange messages with the graphs that own the remaining
+	// partitions of the link graph.
+	SkipRemoteEdges bool
+
+	// SkipSelfLinks controls whether edges from a link to itself are
+	// skipped.
+	SkipSelfLinks bool
+
+	// ProgressFn, if specified, is periodically invoked while loading
+	// with a snapshot of the load progress. Invocations are serialized.
+	ProgressFn func(Progress)
+
+	// ProgressInterval specifies how many links or edges must be read
+	// between two ProgressFn invocations. If not specified, a default
+	// value of 10000 will be used instead.
+	ProgressInterval int
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if cfg.LinkGraph == nil {
+		err = multierror.Append(err, xerrors.New("link graph not specified"))
+	}
+	if cfg.ProgressInterval <= 0 {
+		cfg.ProgressInterval = 10000
+	}
+	return err
+}
+
+// Progress describes the progress of a load operation.
+type Progress struct {
+	// The number of links that were added to the graph as vertices.
+	Links int
+
+	// The number of edges that were read from the link graph.
+	Edges int
+
+	// The number of edges that were not added to the graph because
+	// their source or destination was not loaded or because they were
+	// flagged as nofollow.
+	SkippedEdges int
+}
+
+// Loader populates bspgraph.Graph instances with the links and edges of a
+// link graph partition.
+type Loader struct {
+	cfg Config
+}
+
+// NewLoader returns a new Loader instance using the provided config options.
+func NewLoader(cfg Config) (*Loader, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("graph loader config validation failed: %w", err)
+	}
+	return &Loader{cfg: cfg}, nil
+}
+
+// Load adds a vertex to g for each link whose ID belongs to the [fromID, toID)
+// range and was retrieved before retrievedBefore. It also adds an edge for
+// each link graph edge whose source belongs to the same range and which was
+// updated before updatedBefore. Links and edges are streamed concurrently;
+// edges whose endpoints have not been loaded yet are buffered and retried
+// once all links have been read. If toID is partition.MaxUUID, the range
+// also includes toID.
+//
+// Edges whose source vertex was not loaded are skipped. The same applies to
+// edges whose destination belongs to the range but was not loaded and,
+// unless IncludeNoFollowEdges is set, to nofollow edges. Unless
+// SkipRemoteEdges is set, edges pointing outside the range are retained as
+// their destination is expected to be owned by a remote graph instance.
+//
+// Load must not be invoked while g is executing a superstep.
+func (l *Loader) Load(ctx context.Context, g *bspgraph.Graph, fromID, toID uuid.UUID, retrievedBefore, updatedBefore time.Time) (Progress, error) {
+	ctx, cancelFn := context.WithCancel(ctx)
+	defer cancelFn()
+
+	var (
+		wg       sync.WaitGroup
+		tracker  = &progressTracker{fn: l.cfg.ProgressFn, interval: l.cfg.ProgressInterval}
+		lg       = &lockedGraph{g: g}
+		deferred []pendingEdge
+		linkErr  error
+		edgeErr  error
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if linkErr = l.loadLinks(ctx, lg, fromID, toID, retrievedBefore, tracker); linkErr != nil {
+			cancelFn()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if deferred, edgeErr = l.loadEdges(ctx, lg, fromID, toID, updatedBefore, tracker); edgeErr != nil {
+			cancelFn()
+		}
+	}()
+	wg.Wait()
+
+	if linkErr != nil {
+		return tracker.snapshot(), xerrors.Errorf("load links: %w", linkErr)
+	} else if edgeErr != nil {
+		return tracker.snapshot(), xerrors.Errorf("load edges: %w", edgeErr)
+	}
+
+	// All links have been loaded; retry the edges that were read before
+	// their endpoints were added to the graph.
+	for _, e := range deferred {
+		added, err := lg.addEdge(e)
+		if err != nil {
+			return tracker.snapshot(), xerrors.Errorf("load edges: %w", err)
+		} else if !added {
+			tracker.skipEdge()
+		}
+	}
+
+	tracker.report()
+	return tracker.snapshot(), nil
+}
+
+func (l *Loader) loadLinks(ctx context.Context, lg *lockedGraph, fromID, toID uuid.UUID, retrievedBefore time.Time, tracker *progressTracker) error {
+	linkIt, err := l.cfg.LinkGraph.Links(ctx, fromID, toID, retrievedBefore)
+	if err != nil {
+		return err
+	}
+	for linkIt.Next() {
+		link := linkIt.Link()
+		var value interface{}
+		if l.cfg.VertexValueFn != nil {
+			value = l.cfg.VertexValueFn(link)
+		}
+		lg.addVertex(link.ID.String(), value)
+		tracker.addLink()
+	}
+	if err = linkIt.Error(); err != nil {
+		_ = linkIt.Close()
+		return err
+	}
+	return linkIt.Close()
+}
+
+// loadEdges adds the edges of the range to the graph as they are read from
+// the link graph. It returns the edges that could not be added because their
+// endpoints have not been loaded yet.
+func (l *Loader) loadEdges(ctx context.Context, lg *lockedGraph, fromID, toID uuid.UUID, updatedBefore time.Time, tracker *progressTracker) ([]pendingEdge, error) {
+	edgeIt, err := l.cfg.LinkGraph.Edges(ctx, fromID, toID, updatedBefore)
+	if err != nil {
+		return nil, err
+	}
+	var deferred []pendingEdge
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		tracker.addEdge()
+		dstInRange := partition.Contains(edge.Dst, fromID, toID)
+		if l.skipEdge(edge, dstInRange) {
+			tracker.skipEdge()
+			continue
+		}
+		e := pendingEdge{
+			src:        edge.Src.String(),
+			dst:        edge.Dst.String(),
+			dstInRange: dstInRange,
+		}
+		if l.cfg.EdgeValueFn != nil {
+			e.value = l.cfg.EdgeValueFn(edge)
+		}
+
+		added, err := lg.addEdge(e)
+		if err != nil {
+			_ = edgeIt.Close()
+			return nil, err
+		} else if !added {
+			deferred = append(deferred, e)
+		}
+	}
+	if err = edgeIt.Error(); err != nil {
+		_ = edgeIt.Close()
+		return nil, err
+	}
+	return deferred, edgeIt.Close()
+}
+
+// skipEdge returns true if edge must not be added to the graph regardless of
+// which links are loaded.
+func (l *Loader) skipEdge(edge *graph.Edge, dstInRange bool) bool {
+	switch {
+	case edge.NoFollow && !l.cfg.IncludeNoFollowEdges:
+		return true
+	case edge.Src == edge.Dst && l.cfg.SkipSelfLinks:
+		return true
+	default:
+		return !dstInRange && l.cfg.SkipRemoteEdges
+	}
+}
+
+// pendingEdge is an edge that has been read from the link graph but not yet
+// added to the bspgraph.
+type pendingEdge struct {
+	src, dst   string
+	dstInRange bool
+	value      interface{}
+}
+
+// lockedGraph serializes the access to a bspgraph.Graph that is being
+// populated by the link and edge streams.
+type lockedGraph struct {
+	mu sync.Mutex
+	g  *bspgraph.Graph
+}
+
+func (lg *lockedGraph) addVertex(id string, value interface{}) {
+	lg.mu.Lock()
+	lg.g.AddVertex(id, value)
+	lg.mu.Unlock()
+}
+
+// addEdge adds e to the graph if its source vertex and, for destinations
+// that belong to the loaded range, its destination vertex have been loaded.
+// It returns false if the edge was not added.
+func (lg *lockedGraph) addEdge(e pendingEdge) (bool, error) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	vertices := lg.g.Vertices()
+	if vertices[e.src] == nil || (e.dstInRange && vertices[e.dst] == nil) {
+		return false, nil
+	}
+	if err := lg.g.AddEdge(e.src, e.dst, e.value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// progressTracker keeps track of the load progress and periodically reports
+// it to a user-defined callback.
+type progressTracker struct {
+	mu       sync.Mutex
+	progress Progress
+	fn       func(Progress)
+	interval int
+	pending  int
+}
+
+func (t *progressTracker) addLink() { t.update(func(p *Progress) { p.Links++ }) }
+func (t *progressTracker) addEdge() { t.update(func(p *Progress) { p.Edges++ }) }
+
+func (t *progressTracker) skipEdge() {
+	t.mu.Lock()
+	t.progress.SkippedEdges++
+	t.mu.Unlock()
+}
+
+func (t *progressTracker) update(fn func(*Progress)) {
+	t.mu.Lock()
+	fn(&t.progress)
+	if t.pending++; t.pending >= t.interval {
+		t.pending = 0
+		if t.fn != nil {
+			t.fn(t.progress)
+		}
+	}
+	t.mu.Unlock()
+}
+
+func (t *progressTracker) report() {
+	if t.fn != nil {
+		t.fn(t.snapshot())
+	}
+}
+
+func (t *progressTracker) snapshot() Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.progress
+}
@@ -0,0 +1,231 @@
+package graphloader
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"github.com/joshvoll/linkrus/internal/linkgraph/store/memory"
+	"github.com/joshvoll/linkrus/internal/partition"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(LoaderTestSuite))
+
+// LoaderTestSuite verifies the graph loader and the vertex value sinks.
+type LoaderTestSuite struct {
+	lg     *memory.InMemoryGraph
+	g      *bspgraph.Graph
+	cutoff time.Time
+	links  map[string]uuid.UUID
+}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *LoaderTestSuite) SetUpTest(c *gc.C) {
+	ctx := context.Background()
+	s.lg = memory.NewInMemoryGraph()
+	s.cutoff = time.Now().Add(time.Hour)
+	s.links = make(map[string]uuid.UUID)
+
+	// C is retrieved after the cut-off time and will not be loaded.
+	for name, retrievedAt := range map[string]time.Time{
+		"A": time.Now(),
+		"B": time.Now(),
+		"C": s.cutoff.Add(time.Minute),
+	} {
+		link := &graph.Link{URL: "http://example.com/" + name, RetrievedAt: retrievedAt}
+		c.Assert(s.lg.UpsertLink(ctx, link), gc.IsNil)
+		s.links[name] = link.ID
+	}
+	for _, e := range [][2]string{{"A", "B"}, {"A", "C"}, {"B", "A"}} {
+		edge := &graph.Edge{Src: s.links[e[0]], Dst: s.links[e[1]]}
+		c.Assert(s.lg.UpsertEdge(ctx, edge), gc.IsNil)
+	}
+
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn: func(*bspgraph.Graph, *bspgraph.Vertex, message.Iterator) error { return nil },
+	})
+	c.Assert(err, gc.IsNil)
+	s.g = g
+}
+
+func (s *LoaderTestSuite) TearDownTest(c *gc.C) {
+	c.Assert(s.g.Close(), gc.IsNil)
+}
+
+func (s *LoaderTestSuite) TestLoadFullRange(c *gc.C) {
+	var reports []Progress
+	l, err := NewLoader(Config{
+		LinkGraph:        s.lg,
+		VertexValueFn:    func(l *graph.Link) interface{} { return l.URL },
+		ProgressFn:       func(p Progress) { reports = append(reports, p) },
+		ProgressInterval: 1,
+	})
+	c.Assert(err, gc.IsNil)
+
+	p, err := l.Load(context.Background(), s.g, uuid.Nil, partition.MaxUUID, s.cutoff, s.cutoff)
+	c.Assert(err, gc.IsNil)
+	c.Assert(p, gc.DeepEquals, Progress{Links: 2, Edges: 3, SkippedEdges: 1})
+	c.Assert(reports[len(reports)-1], gc.DeepEquals, p)
+
+	vertices := s.g.Vertices()
+	c.Assert(vertices, gc.HasLen, 2)
+	c.Assert(vertices[s.links["A"].String()].Value(), gc.Equals, "http://example.com/A")
+	c.Assert(s.edgeDsts(s.links["A"]), gc.DeepEquals, []string{s.links["B"].String()})
+	c.Assert(s.edgeDsts(s.links["B"]), gc.DeepEquals, []string{s.links["A"].String()})
+}
+
+func (s *LoaderTestSuite) TestLoadPartitionKeepsRemoteEdges(c *gc.C) {
+	l, err := NewLoader(Config{LinkGraph: s.lg})
+	c.Assert(err, gc.IsNil)
+
+	// Load a partition that only contains A; its edges point to vertices
+	// owned by other partitions and must be retained.
+	fromID := s.links["A"]
+	toID := nextUUID(fromID)
+	p, err := l.Load(context.Background(), s.g, fromID, toID, s.cutoff, s.cutoff)
+	c.Assert(err, gc.IsNil)
+	c.Assert(p, gc.DeepEquals, Progress{Links: 1, Edges: 2})
+
+	exp := []string{s.links["B"].String(), s.links["C"].String()}
+	sort.Strings(exp)
+	c.Assert(s.edgeDsts(s.links["A"]), gc.DeepEquals, exp)
+}
+
+func (s *LoaderTestSuite) TestSkipRemoteEdgesAndSelfLinks(c *gc.C) {
+	self := &graph.Edge{Src: s.links["A"], Dst: s.links["A"]}
+	c.Assert(s.lg.UpsertEdge(context.Background(), self), gc.IsNil)
+
+	l, err := NewLoader(Config{LinkGraph: s.lg, SkipRemoteEdges: true, SkipSelfLinks: true})
+	c.Assert(err, gc.IsNil)
+	fromID := s.links["A"]
+	p, err := l.Load(context.Background(), s.g, fromID, nextUUID(fromID), s.cutoff, s.cutoff)
+	c.Assert(err, gc.IsNil)
+	c.Assert(p, gc.DeepEquals, Progress{Links: 1, Edges: 3, SkippedEdges: 3})
+	c.Assert(s.edgeDsts(s.links["A"]), gc.HasLen, 0)
+}
+
+func (s *LoaderTestSuite) TestNoFollowEdges(c *gc.C) {
+	edge := &graph.Edge{Src: s.links["B"], Dst: s.links["A"], NoFollow: true}
+	c.Assert(s.lg.UpsertEdge(context.Background(), edge), gc.IsNil)
+
+	l, err := NewLoader(Config{LinkGraph: s.lg})
+	c.Assert(err, gc.IsNil)
+	p, err := l.Load(context.Background(), s.g, uuid.Nil, partition.MaxUUID, s.cutoff, s.cutoff)
+	c.Assert(err, gc.IsNil)
+	c.Assert(p, gc.DeepEquals, Progress{Links: 2, Edges: 3, SkippedEdges: 2})
+	c.Assert(s.edgeDsts(s.links["B"]), gc.HasLen, 0)
+
+	c.Assert(s.g.Reset(), gc.IsNil)
+	l, err = NewLoader(Config{LinkGraph: s.lg, IncludeNoFollowEdges: true})
+	c.Assert(err, gc.IsNil)
+	p, err = l.Load(context.Background(), s.g, uuid.Nil, partition.MaxUUID, s.cutoff, s.cutoff)
+	c.Assert(err, gc.IsNil)
+	c.Assert(p, gc.DeepEquals, Progress{Links: 2, Edges: 3, SkippedEdges: 1})
+	c.Assert(s.edgeDsts(s.links["B"]), gc.DeepEquals, []string{s.links["A"].String()})
+}
+
+func (s *LoaderTestSuite) TestEdgesReadBeforeLinks(c *gc.C) {
+	lg := &edgesFirstGraph{InMemoryGraph: s.lg, edgesDone: make(chan struct{})}
+	l, err := NewLoader(Config{LinkGraph: lg})
+	c.Assert(err, gc.IsNil)
+
+	p, err := l.Load(context.Background(), s.g, uuid.Nil, partition.MaxUUID, s.cutoff, s.cutoff)
+	c.Assert(err, gc.IsNil)
+	c.Assert(p, gc.DeepEquals, Progress{Links: 2, Edges: 3, SkippedEdges: 1})
+	c.Assert(s.edgeDsts(s.links["A"]), gc.DeepEquals, []string{s.links["B"].String()})
+	c.Assert(s.edgeDsts(s.links["B"]), gc.DeepEquals, []string{s.links["A"].String()})
+}
+
+func (s *LoaderTestSuite) TestMissingLinkGraph(c *gc.C) {
+	_, err := NewLoader(Config{})
+	c.Assert(err, gc.ErrorMatches, "(?s).*link graph not specified.*")
+}
+
+func (s *LoaderTestSuite) TestWriteVertexValues(c *gc.C) {
+	s.g.AddVertex(s.links["A"].String(), 0.25)
+	s.g.AddVertex(s.links["B"].String(), 0.75)
+
+	updater := make(scoreRecorder)
+	n, err := WriteVertexValues(context.Background(), s.g, NewScoreSink(updater))
+	c.Assert(err, gc.IsNil)
+	c.Assert(n, gc.Equals, 2)
+	c.Assert(updater, gc.DeepEquals, scoreRecorder{s.links["A"]: 0.25, s.links["B"]: 0.75})
+
+	s.g.AddVertex(s.links["A"].String(), "not-a-score")
+	_, err = WriteVertexValues(context.Background(), s.g, NewScoreSink(updater))
+	c.Assert(err, gc.ErrorMatches, ".*unsupported vertex value")
+}
+
+func (s *LoaderTestSuite) edgeDsts(id uuid.UUID) []string {
+	var dsts []string
+	for _, e := range s.g.Vertices()[id.String()].Edges() {
+		dsts = append(dsts, e.DstID())
+	}
+	sort.Strings(dsts)
+	return dsts
+}
+
+// nextUUID returns the UUID that immediately follows id.
+func nextUUID(id uuid.UUID) uuid.UUID {
+	for i := len(id) - 1; i >= 0; i-- {
+		if id[i]++; id[i] != 0 {
+			break
+		}
+	}
+	return id
+}
+
+// edgesFirstGraph is a LinkGraph whose link iterator does not yield any links
+// until all edges have been read.
+type edgesFirstGraph struct {
+	*memory.InMemoryGraph
+	edgesDone chan struct{}
+}
+
+func (g *edgesFirstGraph) Links(ctx context.Context, fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error) {
+	select {
+	case <-g.edgesDone:
+	case <-time.After(5 * time.Second):
+		return nil, xerrors.New("timed out waiting for the edges to be read")
+	}
+	return g.InMemoryGraph.Links(ctx, fromID, toID, retrievedBefore)
+}
+
+func (g *edgesFirstGraph) Edges(ctx context.Context, fromID, toID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error) {
+	it, err := g.InMemoryGraph.Edges(ctx, fromID, toID, updatedBefore)
+	if err != nil {
+		return nil, err
+	}
+	return &notifyingEdgeIterator{EdgeIterator: it, doneCh: g.edgesDone}, nil
+}
+
+// notifyingEdgeIterator closes doneCh once the wrapped iterator is exhausted.
+type notifyingEdgeIterator struct {
+	graph.EdgeIterator
+	doneCh chan struct{}
+}
+
+func (it *notifyingEdgeIterator) Next() bool {
+	if it.EdgeIterator.Next() {
+		return true
+	}
+	close(it.doneCh)
+	return false
+}
+
+type scoreRecorder map[uuid.UUID]float64
+
+func (r scoreRecorder) UpdateScore(_ context.Context, linkID uuid.UUID, score float64) error {
+	r[linkID] = score
+	return nil
+}
@@ -0,0 +1,74 @@
+package graphloader
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"golang.org/x/xerrors"
+)
+
+// ErrUnsupportedValue is returned by a Sink when it cannot handle the type of
+// a vertex value.
+var ErrUnsupportedValue = xerrors.New("unsupported vertex value")
+
+// Sink is implemented by objects that can persist the values of the vertices
+// of a graph that was populated by a Loader.
+type Sink interface {
+	// WriteVertexValue persists the value of the vertex that corresponds
+	// to the link with the specified ID.
+	WriteVertexValue(ctx context.Context, linkID uuid.UUID, value interface{}) error
+}
+
+// ScoreUpdater is implemented by objects that can update the score of the
+// documents that correspond to the links of a link graph (e.g. the text
+// indexer).
+type ScoreUpdater interface {
+	// UpdateScore updates the score for the document with the specified
+	// link ID.
+	UpdateScore(ctx context.Context, linkID uuid.UUID, score float64) error
+}
+
+// ScoreSink adapts a ScoreUpdater into a Sink that expects float64 vertex
+// values.
+type ScoreSink struct {
+	updater ScoreUpdater
+}
+
+// NewScoreSink returns a Sink that writes float64 vertex values to updater.
+func NewScoreSink(updater ScoreUpdater) *ScoreSink {
+	return &ScoreSink{updater: updater}
+}
+
+// WriteVertexValue implements Sink.
+func (s *ScoreSink) WriteVertexValue(ctx context.Context, linkID uuid.UUID, value interface{}) error {
+	score, ok := value.(float64)
+	if !ok {
+		return xerrors.Errorf("write score for link %v: %T: %w", linkID, value, ErrUnsupportedValue)
+	}
+	return s.updater.UpdateScore(ctx, linkID, score)
+}
+
+// WriteVertexValues writes the value of each vertex in g to the provided sink
+// and returns the number of written values. Vertex IDs are expected to be
+// link IDs as populated by a Loader.
+func WriteVertexValues(ctx context.Context, g *bspgraph.Graph, sink Sink) (int, error) {
+	var written int
+	for id, v := range g.Vertices() {
+		select {
+		case <-ctx.Done():
+			return written, ctx.Err()
+		default:
+		}
+
+		linkID, err := uuid.Parse(id)
+		if err != nil {
+			return written, xerrors.Errorf("write value for vertex %q: %w", id, err)
+		}
+		if err = sink.WriteVertexValue(ctx, linkID, v.Value()); err != nil {
+			return written, xerrors.Errorf("write value for vertex %q: %w", id, err)
+		}
+		written++
+	}
+	return written, nil
+}
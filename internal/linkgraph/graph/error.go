@@ -7,4 +7,6 @@ var (
 	ErrUnknownEdgeLinks = xerrors.New("Unknow source and/or destination for edge")
 	// ErrNotFound return an error if the link is not found
 	ErrNotFound = xerrors.New("Unkown link for specific ID")
+	// ErrConflict is return when restoring a link or edge that clashes with an existing one
+	ErrConflict = xerrors.New("restored record conflicts with an existing one")
 )
@@ -15,6 +15,21 @@ type Link struct {
 	URL string
 	// timestamp when the link was retrieve it
 	RetrievedAt time.Time
+
+	// The fields below describe the outcome of the last fetch attempt. When
+	// upserting an existing link, they are only updated if the provided
+	// RetrievedAt value is newer than the stored one.
+
+	// the HTTP status code of the last fetch (0 if never fetched)
+	StatusCode int
+	// the hex-encoded SHA-256 hash of the last fetched content
+	ContentHash string
+	// the final URL after following redirects (empty if not redirected)
+	RedirectURL string
+	// the number of consecutive failed fetch attempts
+	FailureCount int
+	// the error that caused the last fetch attempt to fail
+	LastError string
 }
 
 // LinkIterator describe the behavior for interactions from the linkd tabls
@@ -53,23 +68,79 @@ type Edge struct {
 	Dst uuid.UUID
 	// the tiemestamp for the events
 	UpdateAt time.Time
+
+	// The fields below annotate the edge with details about the link that
+	// was found in the source page. They are overwritten each time the edge
+	// is upserted.
+
+	// the text enclosed by the anchor element
+	AnchorText string
+	// true if the anchor element carries a rel="nofollow" attribute
+	NoFollow bool
+	// the zero-based ordinal position of the link within the source page
+	Position int
 }
 
 // Graph contain the core logic of the application
 type Graph interface {
 	// UpsertLink create a new link or update an existing one.
 	UpsertLink(ctx context.Context, link *Link) error
+	// UpsertLinks create or update a batch of links in a single operation.
+	// Links that share the same URL are resolved to the same link ID.
+	UpsertLinks(ctx context.Context, links []*Link) error
 	// FindLink look up and link base on the ide
 	FindLink(ctx context.Context, id uuid.UUID) (*Link, error)
+	// FindLinkByURL look up a link base on its URL. It return ErrNotFound
+	// if no link with the specified URL exists.
+	FindLinkByURL(ctx context.Context, url string) (*Link, error)
+	// LinksWithURLPrefix return an iterator, ordered by URL, for the set of
+	// links whose URL starts with the provided prefix (e.g. all the links
+	// under "https://example.com/").
+	LinksWithURLPrefix(ctx context.Context, prefix string) (LinkIterator, error)
+	// RemoveLink remove the link with the specified ID together with all
+	// the edges that originate from or point to it. It return ErrNotFound
+	// if the link does not exist.
+	RemoveLink(ctx context.Context, id uuid.UUID) error
+	// RemoveLinks remove a batch of links and their edges in a single
+	// operation. Unknown link IDs are ignored.
+	RemoveLinks(ctx context.Context, ids []uuid.UUID) error
+	// RemoveOrphanLinks remove the links in ids that have no incoming
+	// edges and were retrieved before the provided timestamp. Both
+	// conditions are checked atomically with the removal. Links that were
+	// never retrieved and unknown link IDs are ignored. It return the IDs
+	// of the removed links.
+	RemoveOrphanLinks(ctx context.Context, ids []uuid.UUID, retrievedBefore time.Time) ([]uuid.UUID, error)
 	// Links return all the link base on a iterator who ide belong to that particular link
 	// [fromID, toID] range can be retrieved
 	Links(ctx context.Context, fromID, toID uuid.UUID, retrievedBefore time.Time) (LinkIterator, error)
 	// UpsertEdge create a new edge or update a exsiting ne
 	UpsertEdge(ctx context.Context, edge *Edge) error
+	// UpsertEdges create or update a batch of edges in a single operation.
+	// If any edge refers to an unknown link, no edge is upserted.
+	UpsertEdges(ctx context.Context, edges []*Edge) error
 	// Edges return all the edges that are belong for the particular edage
 	// the source is on vertex id [fromID, toID]
 	// rnage is update before provide a timestamp
 	Edges(ctx context.Context, fromID, toID uuid.UUID, updatedBefore time.Time) (EdgeIterator, error)
+	// EdgesTo return an iterator for the set of edges that point to the
+	// dstID link and were updated before the provided timestamp.
+	EdgesTo(ctx context.Context, dstID uuid.UUID, updatedBefore time.Time) (EdgeIterator, error)
+	// InDegree return the number of edges that point to the specified link.
+	// It return ErrNotFound if the link does not exist.
+	InDegree(ctx context.Context, linkID uuid.UUID) (int, error)
+	// OutDegree return the number of edges that originate from the
+	// specified link. It return ErrNotFound if the link does not exist.
+	OutDegree(ctx context.Context, linkID uuid.UUID) (int, error)
 	// RemoveStaledges remove any edges from the origin specifications
 	RemoveStalEdges(ctx context.Context, fromID uuid.UUID, udpatedBefore time.Time) error
+	// RestoreLinks insert or replace a batch of links, keeping their IDs,
+	// timestamps and fetch metadata verbatim. Links are matched by ID. It
+	// return ErrConflict and restores nothing if any URL already belongs
+	// to a link with a different ID.
+	RestoreLinks(ctx context.Context, links []*Link) error
+	// RestoreEdges insert or replace a batch of edges, keeping their IDs,
+	// timestamps and annotations verbatim. Edges are matched by their
+	// source and destination. It return ErrUnknownEdgeLinks and restores
+	// nothing if any edge refers to an unknown link.
+	RestoreEdges(ctx context.Context, edges []*Edge) error
 }
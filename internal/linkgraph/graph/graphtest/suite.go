@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"golang.org/x/xerrors"
 	gc "gopkg.in/check.v1"
 )
 
@@ -75,3 +76,518 @@ func (s *SuiteBase) TestUpsertEdge(c *gc.C) {
 	c.Assert(edge.ID, gc.Not(gc.Equals), uuid.Nil, gc.Commentf("expected a edgeID to be assing to the new edge"))
 	c.Assert(edge.UpdateAt.IsZero(), gc.Equals, false, gc.Commentf("UpdateAt field not setup"))
 }
+
+// TestLinkFetchMetadata verifies that the fetch metadata of a link is only
+// replaced by more recent fetches.
+func (s *SuiteBase) TestLinkFetchMetadata(c *gc.C) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	original := &graph.Link{
+		URL:          "https://example.com/metadata",
+		RetrievedAt:  now.Add(-time.Hour),
+		StatusCode:   404,
+		FailureCount: 1,
+		LastError:    "unexpected HTTP status code 404",
+	}
+	c.Assert(s.g.UpsertLink(ctx, original), gc.IsNil)
+
+	stored, err := s.g.FindLink(ctx, original.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.StatusCode, gc.Equals, 404)
+	c.Assert(stored.FailureCount, gc.Equals, 1)
+	c.Assert(stored.LastError, gc.Equals, original.LastError)
+
+	// A more recent fetch replaces the metadata.
+	fetched := &graph.Link{
+		URL:         original.URL,
+		RetrievedAt: now,
+		StatusCode:  200,
+		ContentHash: "e3b0c44298fc1c149afbf4c8996fb924",
+		RedirectURL: "https://example.com/metadata/",
+	}
+	c.Assert(s.g.UpsertLink(ctx, fetched), gc.IsNil)
+	c.Assert(fetched.ID, gc.Equals, original.ID)
+
+	stored, err = s.g.FindLink(ctx, original.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.RetrievedAt.Equal(now), gc.Equals, true)
+	c.Assert(stored.StatusCode, gc.Equals, 200)
+	c.Assert(stored.ContentHash, gc.Equals, fetched.ContentHash)
+	c.Assert(stored.RedirectURL, gc.Equals, fetched.RedirectURL)
+	c.Assert(stored.FailureCount, gc.Equals, 0)
+	c.Assert(stored.LastError, gc.Equals, "")
+
+	// An older fetch must not overwrite the metadata and the stored values
+	// are reported back to the caller.
+	stale := &graph.Link{
+		URL:          original.URL,
+		RetrievedAt:  now.Add(-2 * time.Hour),
+		StatusCode:   500,
+		FailureCount: 3,
+		LastError:    "unexpected HTTP status code 500",
+	}
+	c.Assert(s.g.UpsertLinks(ctx, []*graph.Link{stale}), gc.IsNil)
+	c.Assert(stale.ID, gc.Equals, original.ID)
+	c.Assert(stale.StatusCode, gc.Equals, 200)
+	c.Assert(stale.RetrievedAt.Equal(now), gc.Equals, true)
+
+	stored, err = s.g.FindLinkByURL(ctx, original.URL)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.StatusCode, gc.Equals, 200)
+	c.Assert(stored.ContentHash, gc.Equals, fetched.ContentHash)
+	c.Assert(stored.FailureCount, gc.Equals, 0)
+}
+
+// TestUpsertLinks verifies the batch link upsert.
+func (s *SuiteBase) TestUpsertLinks(c *gc.C) {
+	ctx := context.Background()
+	existing := &graph.Link{
+		URL:         "https://example.com/existing",
+		RetrievedAt: time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+	}
+	c.Assert(s.g.UpsertLink(ctx, existing), gc.IsNil)
+
+	newer := existing.RetrievedAt.Add(30 * time.Minute)
+	batch := []*graph.Link{
+		{URL: "https://example.com/a"},
+		{URL: "https://example.com/existing", RetrievedAt: newer},
+		{URL: "https://example.com/b"},
+		{URL: "https://example.com/a"},
+	}
+	c.Assert(s.g.UpsertLinks(ctx, batch), gc.IsNil)
+
+	for _, link := range batch {
+		c.Assert(link.ID, gc.Not(gc.Equals), uuid.Nil, gc.Commentf("expected a linkID to be assigned to %q", link.URL))
+	}
+	c.Assert(batch[0].ID, gc.Equals, batch[3].ID, gc.Commentf("expected links with the same URL to share an ID"))
+	c.Assert(batch[0].ID, gc.Not(gc.Equals), batch[2].ID)
+	c.Assert(batch[1].ID, gc.Equals, existing.ID, gc.Commentf("expected existing link to be updated"))
+
+	stored, err := s.g.FindLink(ctx, existing.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.RetrievedAt.Equal(newer), gc.Equals, true, gc.Commentf("expected retrieved at timestamp to be updated"))
+}
+
+// TestEdgeAnnotations verifies that re-upserting an edge replaces its anchor
+// text, nofollow flag and position.
+func (s *SuiteBase) TestEdgeAnnotations(c *gc.C) {
+	ctx := context.Background()
+	src := &graph.Link{URL: "https://example.com/annotated"}
+	dst := &graph.Link{URL: "https://example.com/target"}
+	c.Assert(s.g.UpsertLinks(ctx, []*graph.Link{src, dst}), gc.IsNil)
+
+	edge := &graph.Edge{
+		Src:        src.ID,
+		Dst:        dst.ID,
+		AnchorText: "a target page",
+		Position:   3,
+	}
+	c.Assert(s.g.UpsertEdge(ctx, edge), gc.IsNil)
+
+	// Re-upserting the edge replaces its annotations.
+	updated := &graph.Edge{
+		Src:        src.ID,
+		Dst:        dst.ID,
+		AnchorText: "sponsored",
+		NoFollow:   true,
+		Position:   7,
+	}
+	c.Assert(s.g.UpsertEdges(ctx, []*graph.Edge{updated}), gc.IsNil)
+	c.Assert(updated.ID, gc.Equals, edge.ID)
+
+	outIt, err := s.g.Edges(ctx, src.ID, nextID(src.ID), time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	inIt, err := s.g.EdgesTo(ctx, dst.ID, time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+
+	for _, it := range []graph.EdgeIterator{outIt, inIt} {
+		c.Assert(it.Next(), gc.Equals, true)
+		stored := it.Edge()
+		c.Assert(it.Next(), gc.Equals, false)
+		c.Assert(it.Error(), gc.IsNil)
+		c.Assert(it.Close(), gc.IsNil)
+
+		c.Assert(stored.ID, gc.Equals, edge.ID)
+		c.Assert(stored.AnchorText, gc.Equals, "sponsored")
+		c.Assert(stored.NoFollow, gc.Equals, true)
+		c.Assert(stored.Position, gc.Equals, 7)
+	}
+}
+
+// TestUpsertEdges verifies the batch edge upsert.
+func (s *SuiteBase) TestUpsertEdges(c *gc.C) {
+	ctx := context.Background()
+	links := []*graph.Link{
+		{URL: "https://example.com/src"},
+		{URL: "https://example.com/dst1"},
+		{URL: "https://example.com/dst2"},
+	}
+	c.Assert(s.g.UpsertLinks(ctx, links), gc.IsNil)
+
+	existing := &graph.Edge{Src: links[0].ID, Dst: links[1].ID}
+	c.Assert(s.g.UpsertEdge(ctx, existing), gc.IsNil)
+
+	batch := []*graph.Edge{
+		{Src: links[0].ID, Dst: links[1].ID},
+		{Src: links[0].ID, Dst: links[2].ID},
+		{Src: links[0].ID, Dst: links[2].ID},
+	}
+	c.Assert(s.g.UpsertEdges(ctx, batch), gc.IsNil)
+	c.Assert(batch[0].ID, gc.Equals, existing.ID, gc.Commentf("expected existing edge to be updated"))
+	c.Assert(batch[1].ID, gc.Not(gc.Equals), uuid.Nil)
+	c.Assert(batch[1].ID, gc.Equals, batch[2].ID, gc.Commentf("expected duplicate edges to share an ID"))
+	for _, edge := range batch {
+		c.Assert(edge.UpdateAt.IsZero(), gc.Equals, false, gc.Commentf("UpdateAt field not set"))
+	}
+
+	// Upserting a batch that contains an edge with an unknown destination
+	// must fail without creating any of the batch edges.
+	src2 := &graph.Link{URL: "https://example.com/src2"}
+	c.Assert(s.g.UpsertLink(ctx, src2), gc.IsNil)
+	err := s.g.UpsertEdges(ctx, []*graph.Edge{
+		{Src: src2.ID, Dst: links[1].ID},
+		{Src: src2.ID, Dst: uuid.New()},
+	})
+	c.Assert(xerrors.Is(err, graph.ErrUnknownEdgeLinks), gc.Equals, true, gc.Commentf("got error: %v", err))
+
+	edgeIt, err := s.g.Edges(ctx, src2.ID, nextID(src2.ID), time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	c.Assert(edgeIt.Next(), gc.Equals, false, gc.Commentf("expected no edges to be created"))
+	c.Assert(edgeIt.Close(), gc.IsNil)
+}
+
+// nextID returns the UUID that immediately follows id.
+func nextID(id uuid.UUID) uuid.UUID {
+	for i := len(id) - 1; i >= 0; i-- {
+		if id[i]++; id[i] != 0 {
+			break
+		}
+	}
+	return id
+}
+
+// TestEdgesTo verifies the incoming edge lookups.
+func (s *SuiteBase) TestEdgesTo(c *gc.C) {
+	ctx := context.Background()
+	links := []*graph.Link{
+		{URL: "https://example.com/target"},
+		{URL: "https://example.com/src1"},
+		{URL: "https://example.com/src2"},
+		{URL: "https://example.com/other"},
+	}
+	c.Assert(s.g.UpsertLinks(ctx, links), gc.IsNil)
+	target := links[0].ID
+
+	c.Assert(s.g.UpsertEdges(ctx, []*graph.Edge{
+		{Src: links[1].ID, Dst: target},
+		{Src: links[2].ID, Dst: target},
+		{Src: links[1].ID, Dst: links[3].ID},
+		{Src: target, Dst: links[3].ID},
+	}), gc.IsNil)
+
+	edgeIt, err := s.g.EdgesTo(ctx, target, time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	srcs := make(map[uuid.UUID]bool)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		c.Assert(edge.Dst, gc.Equals, target)
+		srcs[edge.Src] = true
+	}
+	c.Assert(edgeIt.Error(), gc.IsNil)
+	c.Assert(edgeIt.Close(), gc.IsNil)
+	c.Assert(srcs, gc.DeepEquals, map[uuid.UUID]bool{links[1].ID: true, links[2].ID: true})
+
+	// Stale edges must no longer be reported as incoming edges.
+	c.Assert(s.g.RemoveStalEdges(ctx, links[2].ID, time.Now().Add(time.Minute)), gc.IsNil)
+	edgeIt, err = s.g.EdgesTo(ctx, target, time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	c.Assert(edgeIt.Next(), gc.Equals, true)
+	c.Assert(edgeIt.Edge().Src, gc.Equals, links[1].ID)
+	c.Assert(edgeIt.Next(), gc.Equals, false)
+	c.Assert(edgeIt.Close(), gc.IsNil)
+}
+
+// TestDegrees verifies the in/out degree lookups.
+func (s *SuiteBase) TestDegrees(c *gc.C) {
+	ctx := context.Background()
+	links := []*graph.Link{
+		{URL: "https://example.com/hub"},
+		{URL: "https://example.com/a"},
+		{URL: "https://example.com/b"},
+	}
+	c.Assert(s.g.UpsertLinks(ctx, links), gc.IsNil)
+	c.Assert(s.g.UpsertEdges(ctx, []*graph.Edge{
+		{Src: links[0].ID, Dst: links[1].ID},
+		{Src: links[0].ID, Dst: links[2].ID},
+		{Src: links[1].ID, Dst: links[0].ID},
+	}), gc.IsNil)
+
+	specs := []struct {
+		id            uuid.UUID
+		expIn, expOut int
+	}{
+		{id: links[0].ID, expIn: 1, expOut: 2},
+		{id: links[1].ID, expIn: 1, expOut: 1},
+		{id: links[2].ID, expIn: 1, expOut: 0},
+	}
+	for specIndex, spec := range specs {
+		in, err := s.g.InDegree(ctx, spec.id)
+		c.Assert(err, gc.IsNil)
+		c.Assert(in, gc.Equals, spec.expIn, gc.Commentf("[spec %d] in degree", specIndex))
+		out, err := s.g.OutDegree(ctx, spec.id)
+		c.Assert(err, gc.IsNil)
+		c.Assert(out, gc.Equals, spec.expOut, gc.Commentf("[spec %d] out degree", specIndex))
+	}
+
+	_, err := s.g.InDegree(ctx, uuid.New())
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+	_, err = s.g.OutDegree(ctx, uuid.New())
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+}
+
+// TestFindLinkByURL verifies the link lookups by URL.
+func (s *SuiteBase) TestFindLinkByURL(c *gc.C) {
+	ctx := context.Background()
+	original := &graph.Link{
+		URL:         "https://example.com/find-by-url",
+		RetrievedAt: time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+	}
+	c.Assert(s.g.UpsertLink(ctx, original), gc.IsNil)
+
+	link, err := s.g.FindLinkByURL(ctx, original.URL)
+	c.Assert(err, gc.IsNil)
+	c.Assert(link.ID, gc.Equals, original.ID)
+	c.Assert(link.URL, gc.Equals, original.URL)
+	c.Assert(link.RetrievedAt.Equal(original.RetrievedAt), gc.Equals, true)
+
+	_, err = s.g.FindLinkByURL(ctx, "https://example.com/missing")
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+}
+
+// TestLinksWithURLPrefix verifies the link iteration by URL prefix.
+func (s *SuiteBase) TestLinksWithURLPrefix(c *gc.C) {
+	ctx := context.Background()
+	urls := []string{
+		"https://example.com/b",
+		"https://example.com/",
+		"https://example.com/a/1",
+		"https://example.community/",
+		"https://other.com/",
+		"http://example.com/",
+	}
+	for _, url := range urls {
+		c.Assert(s.g.UpsertLink(ctx, &graph.Link{URL: url}), gc.IsNil)
+	}
+
+	specs := []struct {
+		prefix string
+		exp    []string
+	}{
+		{
+			prefix: "https://example.com/",
+			exp:    []string{"https://example.com/", "https://example.com/a/1", "https://example.com/b"},
+		},
+		{
+			prefix: "https://example.com",
+			exp:    []string{"https://example.com/", "https://example.com/a/1", "https://example.com/b", "https://example.community/"},
+		},
+		{
+			prefix: "ftp://",
+		},
+	}
+	for specIndex, spec := range specs {
+		linkIt, err := s.g.LinksWithURLPrefix(ctx, spec.prefix)
+		c.Assert(err, gc.IsNil)
+		var got []string
+		for linkIt.Next() {
+			link := linkIt.Link()
+			c.Assert(link.ID, gc.Not(gc.Equals), uuid.Nil)
+			got = append(got, link.URL)
+		}
+		c.Assert(linkIt.Error(), gc.IsNil)
+		c.Assert(linkIt.Close(), gc.IsNil)
+		c.Assert(got, gc.DeepEquals, spec.exp, gc.Commentf("[spec %d] prefix %q", specIndex, spec.prefix))
+	}
+}
+
+// TestRemoveLink verifies that removing a link also removes its edges and
+// index entries.
+func (s *SuiteBase) TestRemoveLink(c *gc.C) {
+	ctx := context.Background()
+	links := []*graph.Link{
+		{URL: "https://example.com/doomed"},
+		{URL: "https://example.com/a"},
+		{URL: "https://example.com/b"},
+	}
+	c.Assert(s.g.UpsertLinks(ctx, links), gc.IsNil)
+	doomed := links[0].ID
+	c.Assert(s.g.UpsertEdges(ctx, []*graph.Edge{
+		{Src: doomed, Dst: links[1].ID},
+		{Src: links[2].ID, Dst: doomed},
+		{Src: doomed, Dst: doomed},
+		{Src: links[1].ID, Dst: links[2].ID},
+	}), gc.IsNil)
+
+	c.Assert(s.g.RemoveLink(ctx, doomed), gc.IsNil)
+
+	_, err := s.g.FindLink(ctx, doomed)
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+	_, err = s.g.FindLinkByURL(ctx, links[0].URL)
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+
+	// Only the a -> b edge must survive.
+	for linkIndex, exp := range []struct{ in, out int }{{in: 0, out: 1}, {in: 1, out: 0}} {
+		id := links[linkIndex+1].ID
+		in, err := s.g.InDegree(ctx, id)
+		c.Assert(err, gc.IsNil)
+		c.Assert(in, gc.Equals, exp.in, gc.Commentf("in degree of link %d", linkIndex+1))
+		out, err := s.g.OutDegree(ctx, id)
+		c.Assert(err, gc.IsNil)
+		c.Assert(out, gc.Equals, exp.out, gc.Commentf("out degree of link %d", linkIndex+1))
+	}
+
+	c.Assert(xerrors.Is(s.g.RemoveLink(ctx, doomed), graph.ErrNotFound), gc.Equals, true)
+
+	// The URL can be reused after the link has been removed.
+	relinked := &graph.Link{URL: links[0].URL}
+	c.Assert(s.g.UpsertLink(ctx, relinked), gc.IsNil)
+	c.Assert(relinked.ID, gc.Not(gc.Equals), doomed)
+}
+
+// TestRemoveLinks verifies the batch link removal.
+func (s *SuiteBase) TestRemoveLinks(c *gc.C) {
+	ctx := context.Background()
+	links := []*graph.Link{
+		{URL: "https://example.com/1"},
+		{URL: "https://example.com/2"},
+		{URL: "https://example.com/3"},
+	}
+	c.Assert(s.g.UpsertLinks(ctx, links), gc.IsNil)
+	c.Assert(s.g.UpsertEdge(ctx, &graph.Edge{Src: links[0].ID, Dst: links[2].ID}), gc.IsNil)
+
+	c.Assert(s.g.RemoveLinks(ctx, []uuid.UUID{links[0].ID, links[1].ID, uuid.New()}), gc.IsNil)
+	for linkIndex, link := range links {
+		_, err := s.g.FindLink(ctx, link.ID)
+		c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, linkIndex != 2, gc.Commentf("link %d", linkIndex))
+	}
+	in, err := s.g.InDegree(ctx, links[2].ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(in, gc.Equals, 0)
+}
+
+// TestRemoveOrphanLinks verifies that only links without incoming edges that
+// were retrieved before the cutoff are removed.
+func (s *SuiteBase) TestRemoveOrphanLinks(c *gc.C) {
+	ctx := context.Background()
+	now := time.Now()
+	links := []*graph.Link{
+		{URL: "https://example.com/orphan", RetrievedAt: now.Add(-2 * time.Hour)},
+		{URL: "https://example.com/linked", RetrievedAt: now.Add(-2 * time.Hour)},
+		{URL: "https://example.com/recent", RetrievedAt: now},
+		{URL: "https://example.com/never-retrieved"},
+		{URL: "https://example.com/self", RetrievedAt: now.Add(-2 * time.Hour)},
+	}
+	c.Assert(s.g.UpsertLinks(ctx, links), gc.IsNil)
+	c.Assert(s.g.UpsertEdges(ctx, []*graph.Edge{
+		{Src: links[0].ID, Dst: links[1].ID},
+		{Src: links[4].ID, Dst: links[4].ID},
+	}), gc.IsNil)
+
+	ids := []uuid.UUID{uuid.New()}
+	for _, link := range links {
+		ids = append(ids, link.ID)
+	}
+	removed, err := s.g.RemoveOrphanLinks(ctx, ids, now.Add(-time.Hour))
+	c.Assert(err, gc.IsNil)
+	c.Assert(removed, gc.DeepEquals, []uuid.UUID{links[0].ID})
+
+	for linkIndex, link := range links {
+		_, err := s.g.FindLink(ctx, link.ID)
+		c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, linkIndex == 0, gc.Commentf("link %d", linkIndex))
+	}
+
+	// Removing the orphan also removed its outgoing edge so the link it
+	// pointed to is now an orphan too.
+	removed, err = s.g.RemoveOrphanLinks(ctx, ids, now.Add(-time.Hour))
+	c.Assert(err, gc.IsNil)
+	c.Assert(removed, gc.DeepEquals, []uuid.UUID{links[1].ID})
+}
+
+func (s *SuiteBase) TestRestoreLinks(c *gc.C) {
+	ctx := context.Background()
+	retrievedAt := time.Date(2019, 5, 4, 3, 2, 1, 0, time.UTC)
+	original := &graph.Link{
+		ID:          uuid.New(),
+		URL:         "https://example.com/restored",
+		RetrievedAt: retrievedAt,
+		StatusCode:  301,
+		RedirectURL: "https://example.com/restored/",
+	}
+	c.Assert(s.g.RestoreLinks(ctx, []*graph.Link{original}), gc.IsNil)
+
+	stored, err := s.g.FindLink(ctx, original.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.URL, gc.Equals, original.URL)
+	c.Assert(stored.RetrievedAt.Equal(retrievedAt), gc.Equals, true)
+	c.Assert(stored.StatusCode, gc.Equals, 301)
+	c.Assert(stored.RedirectURL, gc.Equals, original.RedirectURL)
+
+	// Restoring the same link again replaces it even if the stored
+	// retrieval timestamp is more recent.
+	older := *original
+	older.RetrievedAt = retrievedAt.Add(-time.Hour)
+	older.StatusCode = 200
+	c.Assert(s.g.RestoreLinks(ctx, []*graph.Link{&older}), gc.IsNil)
+	stored, err = s.g.FindLinkByURL(ctx, original.URL)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.ID, gc.Equals, original.ID)
+	c.Assert(stored.RetrievedAt.Equal(older.RetrievedAt), gc.Equals, true)
+	c.Assert(stored.StatusCode, gc.Equals, 200)
+
+	// Restoring a link whose URL belongs to a different link fails
+	// without restoring anything.
+	other := &graph.Link{ID: uuid.New(), URL: "https://example.com/other"}
+	conflicting := &graph.Link{ID: uuid.New(), URL: original.URL}
+	err = s.g.RestoreLinks(ctx, []*graph.Link{other, conflicting})
+	c.Assert(xerrors.Is(err, graph.ErrConflict), gc.Equals, true)
+	_, err = s.g.FindLink(ctx, other.ID)
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+}
+
+func (s *SuiteBase) TestRestoreEdges(c *gc.C) {
+	ctx := context.Background()
+	src := &graph.Link{ID: uuid.New(), URL: "https://example.com/restored-src"}
+	dst := &graph.Link{ID: uuid.New(), URL: "https://example.com/restored-dst"}
+	c.Assert(s.g.RestoreLinks(ctx, []*graph.Link{src, dst}), gc.IsNil)
+
+	// Restoring an edge replaces any existing edge between the same links.
+	c.Assert(s.g.UpsertEdge(ctx, &graph.Edge{Src: src.ID, Dst: dst.ID}), gc.IsNil)
+	updatedAt := time.Date(2019, 5, 4, 3, 2, 1, 0, time.UTC)
+	edge := &graph.Edge{
+		ID:         uuid.New(),
+		Src:        src.ID,
+		Dst:        dst.ID,
+		UpdateAt:   updatedAt,
+		AnchorText: "restored",
+		Position:   2,
+	}
+	c.Assert(s.g.RestoreEdges(ctx, []*graph.Edge{edge}), gc.IsNil)
+
+	it, err := s.g.EdgesTo(ctx, dst.ID, time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.Next(), gc.Equals, true)
+	stored := it.Edge()
+	c.Assert(it.Next(), gc.Equals, false)
+	c.Assert(it.Close(), gc.IsNil)
+	c.Assert(stored.ID, gc.Equals, edge.ID)
+	c.Assert(stored.UpdateAt.Equal(updatedAt), gc.Equals, true)
+	c.Assert(stored.AnchorText, gc.Equals, "restored")
+	c.Assert(stored.Position, gc.Equals, 2)
+
+	outDegree, err := s.g.OutDegree(ctx, src.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(outDegree, gc.Equals, 1)
+
+	err = s.g.RestoreEdges(ctx, []*graph.Edge{{ID: uuid.New(), Src: src.ID, Dst: uuid.New()}})
+	c.Assert(xerrors.Is(err, graph.ErrUnknownEdgeLinks), gc.Equals, true)
+}
@@ -0,0 +1,171 @@
+package linkgc
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"github.com/joshvoll/linkrus/internal/partition"
+	"golang.org/x/xerrors"
+)
+
+// Graph is implemented by objects that provide the link graph operations
+// that are required by the Collector.
+type Graph interface {
+	// Links returns an iterator for the set of links whose IDs belong to
+	// the [fromID, toID) range and were retrieved before the provided
+	// timestamp.
+	Links(ctx context.Context, fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error)
+
+	// RemoveOrphanLinks removes the links in ids that have no incoming
+	// edges and were retrieved before the provided timestamp and returns
+	// the IDs of the removed links.
+	RemoveOrphanLinks(ctx context.Context, ids []uuid.UUID, retrievedBefore time.Time) ([]uuid.UUID, error)
+}
+
+// Indexer is implemented by objects that can purge the documents of the
+// collected links from a text index.
+type Indexer interface {
+	// BulkDelete removes the documents for a batch of links.
+	BulkDelete(ctx context.Context, linkIDs []uuid.UUID) error
+}
+
+// Config encapsulates the configuration options for creating a Collector.
+type Config struct {
+	// Graph is the link graph to garbage-collect. A valid Graph instance
+	// is required for the config to be valid.
+	Graph Graph
+
+	// Indexer, if specified, is used to remove the documents of the
+	// collected links from the text index.
+	Indexer Indexer
+
+	// Seeds contains the URLs of the links that must never be collected
+	// even if no other link points to them.
+	Seeds []string
+
+	// RetentionPeriod specifies for how long a link without any incoming
+	// edges is retained after it was last retrieved. A positive value is
+	// required for the config to be valid.
+	RetentionPeriod time.Duration
+
+	// BatchSize specifies the maximum number of links to remove with a
+	// single RemoveOrphanLinks call. If not specified, a default value of 100
+	// will be used instead.
+	BatchSize int
+
+	// Clock is used to obtain the current time. If not specified, time.Now
+	// will be used instead.
+	Clock func() time.Time
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if cfg.Graph == nil {
+		err = multierror.Append(err, xerrors.New("graph not specified"))
+	}
+	if cfg.RetentionPeriod <= 0 {
+		err = multierror.Append(err, xerrors.New("retention period must be positive"))
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return err
+}
+
+// Result describes the outcome of a garbage collection pass.
+type Result struct {
+	// The number of links that were retrieved outside the retention
+	// window and were therefore examined.
+	Scanned int
+
+	// The number of links that were removed.
+	Removed int
+}
+
+// Collector removes orphaned links from a link graph. A link is considered
+// orphaned when no link points to it, it is not a seed and it was last
+// retrieved before the configured retention period. Links that were never
+// retrieved are not collected as the crawler has yet to visit them.
+type Collector struct {
+	cfg   Config
+	seeds map[string]struct{}
+}
+
+// NewCollector returns a new Collector instance using the provided config
+// options.
+func NewCollector(cfg Config) (*Collector, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("link collector config validation failed: %w", err)
+	}
+	seeds := make(map[string]struct{}, len(cfg.Seeds))
+	for _, url := range cfg.Seeds {
+		seeds[url] = struct{}{}
+	}
+	return &Collector{cfg: cfg, seeds: seeds}, nil
+}
+
+// Run executes a single garbage collection pass over the entire link graph.
+//
+// Whether a link is orphaned is checked by the graph when the link is
+// removed so links that gain an incoming edge while the pass is in progress
+// are retained. Removing a link also removes its outgoing edges which may in
+// turn orphan other links. Such links may only be collected by a subsequent
+// pass.
+func (c *Collector) Run(ctx context.Context) (Result, error) {
+	var (
+		res    Result
+		batch  = make([]uuid.UUID, 0, c.cfg.BatchSize)
+		cutoff = c.cfg.Clock().Add(-c.cfg.RetentionPeriod)
+	)
+
+	linkIt, err := c.cfg.Graph.Links(ctx, uuid.Nil, partition.MaxUUID, cutoff)
+	if err != nil {
+		return res, xerrors.Errorf("link gc: %w", err)
+	}
+	defer func() { _ = linkIt.Close() }()
+
+	for linkIt.Next() {
+		link := linkIt.Link()
+		res.Scanned++
+		if _, isSeed := c.seeds[link.URL]; isSeed || link.RetrievedAt.IsZero() {
+			continue
+		}
+
+		if batch = append(batch, link.ID); len(batch) == c.cfg.BatchSize {
+			if err = c.removeBatch(ctx, batch, cutoff, &res); err != nil {
+				return res, xerrors.Errorf("link gc: %w", err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if err = linkIt.Error(); err != nil {
+		return res, xerrors.Errorf("link gc: %w", err)
+	}
+
+	if len(batch) != 0 {
+		if err = c.removeBatch(ctx, batch, cutoff, &res); err != nil {
+			return res, xerrors.Errorf("link gc: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// removeBatch removes the orphaned links in batch from the graph and purges
+// their documents from the text index.
+func (c *Collector) removeBatch(ctx context.Context, batch []uuid.UUID, cutoff time.Time, res *Result) error {
+	removed, err := c.cfg.Graph.RemoveOrphanLinks(ctx, batch, cutoff)
+	if err != nil {
+		return err
+	}
+	res.Removed += len(removed)
+	if c.cfg.Indexer == nil || len(removed) == 0 {
+		return nil
+	}
+	return c.cfg.Indexer.BulkDelete(ctx, removed)
+}
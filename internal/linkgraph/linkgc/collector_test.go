@@ -0,0 +1,125 @@
+package linkgc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"github.com/joshvoll/linkrus/internal/linkgraph/store/memory"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CollectorTestSuite))
+
+// CollectorTestSuite verifies the orphaned link collector.
+type CollectorTestSuite struct{}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *CollectorTestSuite) TestRun(c *gc.C) {
+	ctx := context.Background()
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	g := memory.NewInMemoryGraph()
+
+	links := map[string]*graph.Link{
+		"seed":        {URL: "https://example.com/", RetrievedAt: old},
+		"linked":      {URL: "https://example.com/linked", RetrievedAt: old},
+		"orphan-old":  {URL: "https://example.com/orphan-old", RetrievedAt: old},
+		"orphan-old2": {URL: "https://example.com/orphan-old2", RetrievedAt: old},
+		"orphan-new":  {URL: "https://example.com/orphan-new", RetrievedAt: now},
+		"unretrieved": {URL: "https://example.com/unretrieved"},
+	}
+	for _, link := range links {
+		c.Assert(g.UpsertLink(ctx, link), gc.IsNil)
+	}
+	c.Assert(g.UpsertEdge(ctx, &graph.Edge{Src: links["seed"].ID, Dst: links["linked"].ID}), gc.IsNil)
+
+	idx := new(recordingIndexer)
+	collector, err := NewCollector(Config{
+		Graph:           g,
+		Indexer:         idx,
+		Seeds:           []string{"https://example.com/"},
+		RetentionPeriod: 24 * time.Hour,
+		BatchSize:       1,
+		Clock:           func() time.Time { return now },
+	})
+	c.Assert(err, gc.IsNil)
+
+	res, err := collector.Run(ctx)
+	c.Assert(err, gc.IsNil)
+	c.Assert(res, gc.DeepEquals, Result{Scanned: 5, Removed: 2})
+	c.Assert(idx.deleted, gc.HasLen, 2)
+
+	for name, link := range links {
+		_, err := g.FindLink(ctx, link.ID)
+		removed := name == "orphan-old" || name == "orphan-old2"
+		c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, removed, gc.Commentf("link %q", name))
+	}
+}
+
+func (s *CollectorTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := NewCollector(Config{})
+	c.Assert(err, gc.ErrorMatches, "(?s).*graph not specified.*retention period must be positive.*")
+}
+
+func (s *CollectorTestSuite) TestLinkThatGainsAnEdgeIsRetained(c *gc.C) {
+	ctx := context.Background()
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	g := memory.NewInMemoryGraph()
+
+	src := &graph.Link{URL: "https://example.com/", RetrievedAt: now}
+	orphan := &graph.Link{URL: "https://example.com/orphan", RetrievedAt: old}
+	c.Assert(g.UpsertLinks(ctx, []*graph.Link{src, orphan}), gc.IsNil)
+
+	// Link the orphan after the collector has selected it for removal.
+	racy := &racyGraph{
+		InMemoryGraph: g,
+		beforeRemove: func() {
+			c.Assert(g.UpsertEdge(ctx, &graph.Edge{Src: src.ID, Dst: orphan.ID}), gc.IsNil)
+		},
+	}
+	idx := new(recordingIndexer)
+	collector, err := NewCollector(Config{
+		Graph:           racy,
+		Indexer:         idx,
+		RetentionPeriod: 24 * time.Hour,
+		Clock:           func() time.Time { return now },
+	})
+	c.Assert(err, gc.IsNil)
+
+	res, err := collector.Run(ctx)
+	c.Assert(err, gc.IsNil)
+	c.Assert(res, gc.DeepEquals, Result{Scanned: 1, Removed: 0})
+	c.Assert(idx.deleted, gc.HasLen, 0)
+
+	_, err = g.FindLink(ctx, orphan.ID)
+	c.Assert(err, gc.IsNil)
+}
+
+// racyGraph invokes beforeRemove before removing orphaned links.
+type racyGraph struct {
+	*memory.InMemoryGraph
+	beforeRemove func()
+}
+
+func (g *racyGraph) RemoveOrphanLinks(ctx context.Context, ids []uuid.UUID, retrievedBefore time.Time) ([]uuid.UUID, error) {
+	g.beforeRemove()
+	return g.InMemoryGraph.RemoveOrphanLinks(ctx, ids, retrievedBefore)
+}
+
+// recordingIndexer records the link IDs whose documents were deleted.
+type recordingIndexer struct {
+	deleted []uuid.UUID
+}
+
+func (idx *recordingIndexer) BulkDelete(_ context.Context, linkIDs []uuid.UUID) error {
+	idx.deleted = append(idx.deleted, linkIDs...)
+	return nil
+}
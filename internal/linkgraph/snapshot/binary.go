@@ -0,0 +1,295 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"golang.org/x/xerrors"
+)
+
+// ErrInvalidSnapshot is returned by readers when the snapshot header is
+// missing or specifies an unsupported format version.
+var ErrInvalidSnapshot = xerrors.New("invalid snapshot")
+
+const (
+	binaryFormatVersion = 1
+
+	binaryLinkRecord = 1
+	binaryEdgeRecord = 2
+
+	// maxBinaryStringLen guards against allocating huge buffers when
+	// decoding corrupted snapshots.
+	maxBinaryStringLen = 1 << 24
+)
+
+// binaryMagic prefixes all binary snapshots.
+var binaryMagic = []byte("LGSNAP")
+
+// BinaryWriter encodes links and edges using a compact binary format. Each
+// snapshot starts with a magic header and a format version followed by a
+// sequence of records. Each record is prefixed by a byte that specifies its
+// type; integers are encoded as varints and strings are length-prefixed.
+type BinaryWriter struct {
+	w   *bufio.Writer
+	buf []byte
+	err error
+}
+
+// NewBinaryWriter returns a BinaryWriter that writes a binary snapshot to w.
+func NewBinaryWriter(w io.Writer) *BinaryWriter {
+	bw := &BinaryWriter{w: bufio.NewWriter(w)}
+	bw.buf = append(bw.buf, binaryMagic...)
+	bw.buf = append(bw.buf, binaryFormatVersion)
+	// Any write error is reported by the next call to the writer.
+	_ = bw.flushRecord()
+	return bw
+}
+
+// WriteLink implements Writer.
+func (w *BinaryWriter) WriteLink(link *graph.Link) error {
+	w.buf = append(w.buf[:0], binaryLinkRecord)
+	w.buf = append(w.buf, link.ID[:]...)
+	w.appendString(link.URL)
+	w.appendTime(link.RetrievedAt)
+	w.appendVarint(int64(link.StatusCode))
+	w.appendString(link.ContentHash)
+	w.appendString(link.RedirectURL)
+	w.appendVarint(int64(link.FailureCount))
+	w.appendString(link.LastError)
+	return w.flushRecord()
+}
+
+// WriteEdge implements Writer.
+func (w *BinaryWriter) WriteEdge(edge *graph.Edge) error {
+	w.buf = append(w.buf[:0], binaryEdgeRecord)
+	w.buf = append(w.buf, edge.ID[:]...)
+	w.buf = append(w.buf, edge.Src[:]...)
+	w.buf = append(w.buf, edge.Dst[:]...)
+	w.appendTime(edge.UpdateAt)
+	w.appendString(edge.AnchorText)
+	if edge.NoFollow {
+		w.buf = append(w.buf, 1)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+	w.appendVarint(int64(edge.Position))
+	return w.flushRecord()
+}
+
+// Close implements Writer.
+func (w *BinaryWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func (w *BinaryWriter) appendVarint(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	w.buf = append(w.buf, tmp[:n]...)
+}
+
+func (w *BinaryWriter) appendUvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	w.buf = append(w.buf, tmp[:n]...)
+}
+
+func (w *BinaryWriter) appendString(s string) {
+	w.appendUvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// appendTime encodes t as the number of seconds since the Unix epoch followed
+// by the nanosecond offset. The zero time is encoded as a single 0 byte so it
+// can be decoded back to the zero time.
+func (w *BinaryWriter) appendTime(t time.Time) {
+	if t.IsZero() {
+		w.buf = append(w.buf, 0)
+		return
+	}
+	w.buf = append(w.buf, 1)
+	w.appendVarint(t.Unix())
+	w.appendUvarint(uint64(t.Nanosecond()))
+}
+
+func (w *BinaryWriter) flushRecord() error {
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := w.w.Write(w.buf); err != nil {
+		w.err = xerrors.Errorf("write binary snapshot: %w", err)
+	}
+	return w.err
+}
+
+// BinaryReader decodes snapshots produced by BinaryWriter.
+type BinaryReader struct {
+	r          *bufio.Reader
+	readHeader bool
+}
+
+// NewBinaryReader returns a BinaryReader that reads a binary snapshot from r.
+func NewBinaryReader(r io.Reader) *BinaryReader {
+	return &BinaryReader{r: bufio.NewReader(r)}
+}
+
+// Read implements Reader.
+func (r *BinaryReader) Read() (Record, error) {
+	if !r.readHeader {
+		if err := r.checkHeader(); err != nil {
+			return Record{}, err
+		}
+		r.readHeader = true
+	}
+
+	recType, err := r.r.ReadByte()
+	if err == io.EOF {
+		return Record{}, io.EOF
+	} else if err != nil {
+		return Record{}, xerrors.Errorf("read binary snapshot: %w", err)
+	}
+
+	var rec Record
+	switch recType {
+	case binaryLinkRecord:
+		rec.Link, err = r.readLink()
+	case binaryEdgeRecord:
+		rec.Edge, err = r.readEdge()
+	default:
+		return Record{}, xerrors.Errorf("read binary snapshot: record type %d: %w", recType, ErrUnsupportedRecord)
+	}
+	if err != nil {
+		// A record that is cut short indicates a truncated snapshot.
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Record{}, xerrors.Errorf("read binary snapshot: %w", err)
+	}
+	return rec, nil
+}
+
+func (r *BinaryReader) checkHeader() error {
+	header := make([]byte, len(binaryMagic)+1)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return xerrors.Errorf("read binary snapshot header: %w", ErrInvalidSnapshot)
+	}
+	if !bytes.Equal(header[:len(binaryMagic)], binaryMagic) {
+		return xerrors.Errorf("read binary snapshot header: bad magic: %w", ErrInvalidSnapshot)
+	}
+	if v := header[len(binaryMagic)]; v != binaryFormatVersion {
+		return xerrors.Errorf("read binary snapshot header: unsupported version %d: %w", v, ErrInvalidSnapshot)
+	}
+	return nil
+}
+
+func (r *BinaryReader) readLink() (*graph.Link, error) {
+	var (
+		link graph.Link
+		err  error
+	)
+	if link.ID, err = r.readUUID(); err != nil {
+		return nil, err
+	}
+	if link.URL, err = r.readString(); err != nil {
+		return nil, err
+	}
+	if link.RetrievedAt, err = r.readTime(); err != nil {
+		return nil, err
+	}
+	statusCode, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	link.StatusCode = int(statusCode)
+	if link.ContentHash, err = r.readString(); err != nil {
+		return nil, err
+	}
+	if link.RedirectURL, err = r.readString(); err != nil {
+		return nil, err
+	}
+	failureCount, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	link.FailureCount = int(failureCount)
+	if link.LastError, err = r.readString(); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *BinaryReader) readEdge() (*graph.Edge, error) {
+	var (
+		edge graph.Edge
+		err  error
+	)
+	if edge.ID, err = r.readUUID(); err != nil {
+		return nil, err
+	}
+	if edge.Src, err = r.readUUID(); err != nil {
+		return nil, err
+	}
+	if edge.Dst, err = r.readUUID(); err != nil {
+		return nil, err
+	}
+	if edge.UpdateAt, err = r.readTime(); err != nil {
+		return nil, err
+	}
+	if edge.AnchorText, err = r.readString(); err != nil {
+		return nil, err
+	}
+	noFollow, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	edge.NoFollow = noFollow != 0
+	position, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	edge.Position = int(position)
+	return &edge, nil
+}
+
+func (r *BinaryReader) readUUID() (uuid.UUID, error) {
+	var id uuid.UUID
+	_, err := io.ReadFull(r.r, id[:])
+	return id, err
+}
+
+func (r *BinaryReader) readString() (string, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return "", err
+	} else if n > maxBinaryStringLen {
+		return "", xerrors.Errorf("string length %d exceeds limit: %w", n, ErrInvalidSnapshot)
+	}
+	buf := make([]byte, n)
+	if _, err = io.ReadFull(r.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (r *BinaryReader) readTime() (time.Time, error) {
+	set, err := r.r.ReadByte()
+	if err != nil || set == 0 {
+		return time.Time{}, err
+	}
+	sec, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	nsec, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, int64(nsec)).UTC(), nil
+}
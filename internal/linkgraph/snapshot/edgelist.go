@@ -0,0 +1,46 @@
+package snapshot
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"golang.org/x/xerrors"
+)
+
+// EdgeListWriter exports the graph as a plain edge list that can be loaded
+// by most graph analysis tools. Each line contains the source and
+// destination link IDs of an edge separated by a tab. Links are not written
+// to the output, so a link without edges does not appear in the list.
+//
+// Edge lists do not carry enough information to restore a graph and are
+// therefore export-only.
+type EdgeListWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewEdgeListWriter returns an EdgeListWriter that writes an edge list to w.
+func NewEdgeListWriter(w io.Writer) *EdgeListWriter {
+	return &EdgeListWriter{w: bufio.NewWriter(w)}
+}
+
+// WriteLink implements Writer. It is a no-op.
+func (w *EdgeListWriter) WriteLink(*graph.Link) error { return nil }
+
+// WriteEdge implements Writer.
+func (w *EdgeListWriter) WriteEdge(edge *graph.Edge) error {
+	w.buf = append(w.buf[:0], edge.Src.String()...)
+	w.buf = append(w.buf, '\t')
+	w.buf = append(w.buf, edge.Dst.String()...)
+	w.buf = append(w.buf, '\n')
+	if _, err := w.w.Write(w.buf); err != nil {
+		return xerrors.Errorf("write edge list: %w", err)
+	}
+	return nil
+}
+
+// Close implements Writer.
+func (w *EdgeListWriter) Close() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,184 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"golang.org/x/xerrors"
+)
+
+const (
+	jsonLinkRecord = "link"
+	jsonEdgeRecord = "edge"
+
+	// maxJSONLineLen specifies the maximum length of a single JSON Lines
+	// record.
+	maxJSONLineLen = 16 * 1024 * 1024
+)
+
+// jsonRecord is the JSON representation of a link or edge. The Type field
+// specifies which of the remaining fields are populated.
+type jsonRecord struct {
+	Type string    `json:"type"`
+	ID   uuid.UUID `json:"id"`
+
+	// Link fields.
+	URL          string     `json:"url,omitempty"`
+	RetrievedAt  *time.Time `json:"retrieved_at,omitempty"`
+	StatusCode   int        `json:"status_code,omitempty"`
+	ContentHash  string     `json:"content_hash,omitempty"`
+	RedirectURL  string     `json:"redirect_url,omitempty"`
+	FailureCount int        `json:"failure_count,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+
+	// Edge fields.
+	Src        *uuid.UUID `json:"src,omitempty"`
+	Dst        *uuid.UUID `json:"dst,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	AnchorText string     `json:"anchor_text,omitempty"`
+	NoFollow   bool       `json:"nofollow,omitempty"`
+	Position   int        `json:"position,omitempty"`
+}
+
+// JSONLWriter encodes links and edges as JSON Lines; one JSON object per
+// line. Timestamps are encoded in RFC 3339 format with nanosecond precision.
+type JSONLWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLWriter returns a JSONLWriter that writes a JSON Lines snapshot to w.
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	return &JSONLWriter{w: bw, enc: enc}
+}
+
+// WriteLink implements Writer.
+func (w *JSONLWriter) WriteLink(link *graph.Link) error {
+	rec := jsonRecord{
+		Type:         jsonLinkRecord,
+		ID:           link.ID,
+		URL:          link.URL,
+		RetrievedAt:  timePtr(link.RetrievedAt),
+		StatusCode:   link.StatusCode,
+		ContentHash:  link.ContentHash,
+		RedirectURL:  link.RedirectURL,
+		FailureCount: link.FailureCount,
+		LastError:    link.LastError,
+	}
+	if err := w.enc.Encode(rec); err != nil {
+		return xerrors.Errorf("write JSON Lines snapshot: %w", err)
+	}
+	return nil
+}
+
+// WriteEdge implements Writer.
+func (w *JSONLWriter) WriteEdge(edge *graph.Edge) error {
+	src, dst := edge.Src, edge.Dst
+	rec := jsonRecord{
+		Type:       jsonEdgeRecord,
+		ID:         edge.ID,
+		Src:        &src,
+		Dst:        &dst,
+		UpdatedAt:  timePtr(edge.UpdateAt),
+		AnchorText: edge.AnchorText,
+		NoFollow:   edge.NoFollow,
+		Position:   edge.Position,
+	}
+	if err := w.enc.Encode(rec); err != nil {
+		return xerrors.Errorf("write JSON Lines snapshot: %w", err)
+	}
+	return nil
+}
+
+// Close implements Writer.
+func (w *JSONLWriter) Close() error {
+	return w.w.Flush()
+}
+
+// JSONLReader decodes snapshots produced by JSONLWriter. Empty lines are
+// ignored.
+type JSONLReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewJSONLReader returns a JSONLReader that reads a JSON Lines snapshot from r.
+func NewJSONLReader(r io.Reader) *JSONLReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxJSONLineLen)
+	return &JSONLReader{scanner: scanner}
+}
+
+// Read implements Reader.
+func (r *JSONLReader) Read() (Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := r.scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+
+		var rec jsonRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return Record{}, xerrors.Errorf("read JSON Lines snapshot: line %d: %w", r.line, err)
+		}
+		return rec.toRecord(r.line)
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, xerrors.Errorf("read JSON Lines snapshot: %w", err)
+	}
+	return Record{}, io.EOF
+}
+
+func (rec jsonRecord) toRecord(line int) (Record, error) {
+	switch rec.Type {
+	case jsonLinkRecord:
+		return Record{Link: &graph.Link{
+			ID:           rec.ID,
+			URL:          rec.URL,
+			RetrievedAt:  timeVal(rec.RetrievedAt),
+			StatusCode:   rec.StatusCode,
+			ContentHash:  rec.ContentHash,
+			RedirectURL:  rec.RedirectURL,
+			FailureCount: rec.FailureCount,
+			LastError:    rec.LastError,
+		}}, nil
+	case jsonEdgeRecord:
+		if rec.Src == nil || rec.Dst == nil {
+			return Record{}, xerrors.Errorf("read JSON Lines snapshot: line %d: edge without src or dst: %w", line, ErrInvalidSnapshot)
+		}
+		return Record{Edge: &graph.Edge{
+			ID:         rec.ID,
+			Src:        *rec.Src,
+			Dst:        *rec.Dst,
+			UpdateAt:   timeVal(rec.UpdatedAt),
+			AnchorText: rec.AnchorText,
+			NoFollow:   rec.NoFollow,
+			Position:   rec.Position,
+		}}, nil
+	default:
+		return Record{}, xerrors.Errorf("read JSON Lines snapshot: line %d: record type %q: %w", line, rec.Type, ErrUnsupportedRecord)
+	}
+}
+
+// timePtr returns nil for the zero time so it is omitted from the output.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func timeVal(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
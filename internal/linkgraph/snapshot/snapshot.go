@@ -0,0 +1,250 @@
+// Package snapshot exports link graphs to and imports them from portable
+// snapshot formats.
+package snapshot
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"github.com/joshvoll/linkrus/internal/partition"
+	"golang.org/x/xerrors"
+)
+
+// ErrUnsupportedRecord is returned by readers when they encounter a record
+// of an unknown type.
+var ErrUnsupportedRecord = xerrors.New("unsupported snapshot record")
+
+// Source is implemented by link graphs that can be exported.
+type Source interface {
+	// Links returns an iterator for the set of links whose IDs belong to
+	// the [fromID, toID) range and were retrieved before the provided
+	// timestamp.
+	Links(ctx context.Context, fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error)
+
+	// Edges returns an iterator for the set of edges whose source vertex
+	// IDs belong to the [fromID, toID) range and were updated before the
+	// provided timestamp.
+	Edges(ctx context.Context, fromID, toID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error)
+}
+
+// Target is implemented by link graphs that snapshots can be imported into.
+type Target interface {
+	// RestoreLinks inserts or replaces a batch of links keeping their
+	// IDs, timestamps and metadata.
+	RestoreLinks(ctx context.Context, links []*graph.Link) error
+
+	// RestoreEdges inserts or replaces a batch of edges keeping their
+	// IDs, timestamps and annotations.
+	RestoreEdges(ctx context.Context, edges []*graph.Edge) error
+}
+
+// Writer is implemented by snapshot format encoders.
+type Writer interface {
+	// WriteLink appends a link to the snapshot.
+	WriteLink(link *graph.Link) error
+
+	// WriteEdge appends an edge to the snapshot.
+	WriteEdge(edge *graph.Edge) error
+
+	// Close flushes any buffered data and completes the snapshot. It does
+	// not close the underlying io.Writer.
+	Close() error
+}
+
+// Record is a single entry in a snapshot. Exactly one of its fields is set.
+type Record struct {
+	Link *graph.Link
+	Edge *graph.Edge
+}
+
+// Reader is implemented by snapshot format decoders.
+type Reader interface {
+	// Read returns the next record in the snapshot or io.EOF once all
+	// records have been read.
+	Read() (Record, error)
+}
+
+// Stats describes the number of links and edges that were exported or
+// imported.
+type Stats struct {
+	Links int
+	Edges int
+}
+
+// maxTimestamp is used as the upper bound for the link and edge timestamps
+// so that all links and edges are exported.
+var maxTimestamp = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// Export streams all links followed by all edges of src to w. The UUID space
+// is split into numPartitions partitions which are exported one after the
+// other using the partitioned Links and Edges iterators. If numPartitions is
+// less than 1, a single partition will be used.
+func Export(ctx context.Context, src Source, w Writer, numPartitions int) (Stats, error) {
+	var stats Stats
+	if numPartitions < 1 {
+		numPartitions = 1
+	}
+	r, err := partition.NewFullRange(numPartitions)
+	if err != nil {
+		return stats, xerrors.Errorf("export: %w", err)
+	}
+
+	for p := 0; p < numPartitions; p++ {
+		fromID, toID, err := r.PartitionExtents(p)
+		if err != nil {
+			return stats, xerrors.Errorf("export: %w", err)
+		}
+		if err = exportLinks(ctx, src, w, fromID, toID, &stats); err != nil {
+			return stats, xerrors.Errorf("export links: %w", err)
+		}
+	}
+	for p := 0; p < numPartitions; p++ {
+		fromID, toID, err := r.PartitionExtents(p)
+		if err != nil {
+			return stats, xerrors.Errorf("export: %w", err)
+		}
+		if err = exportEdges(ctx, src, w, fromID, toID, &stats); err != nil {
+			return stats, xerrors.Errorf("export edges: %w", err)
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		return stats, xerrors.Errorf("export: %w", err)
+	}
+	return stats, nil
+}
+
+func exportLinks(ctx context.Context, src Source, w Writer, fromID, toID uuid.UUID, stats *Stats) error {
+	linkIt, err := src.Links(ctx, fromID, toID, maxTimestamp)
+	if err != nil {
+		return err
+	}
+	for linkIt.Next() {
+		if err = w.WriteLink(linkIt.Link()); err != nil {
+			_ = linkIt.Close()
+			return err
+		}
+		stats.Links++
+	}
+	if err = linkIt.Error(); err != nil {
+		_ = linkIt.Close()
+		return err
+	}
+	return linkIt.Close()
+}
+
+func exportEdges(ctx context.Context, src Source, w Writer, fromID, toID uuid.UUID, stats *Stats) error {
+	edgeIt, err := src.Edges(ctx, fromID, toID, maxTimestamp)
+	if err != nil {
+		return err
+	}
+	for edgeIt.Next() {
+		if err = w.WriteEdge(edgeIt.Edge()); err != nil {
+			_ = edgeIt.Close()
+			return err
+		}
+		stats.Edges++
+	}
+	if err = edgeIt.Error(); err != nil {
+		_ = edgeIt.Close()
+		return err
+	}
+	return edgeIt.Close()
+}
+
+// Import reads all records from r and restores them into dst in batches of
+// up to batchSize links or edges. Any pending links are restored before the
+// next batch of edges. Snapshots are not required to list the links before
+// the edges that reference them: batches of edges that refer to links that
+// have not been restored yet are retried once all records have been read. If
+// batchSize is less than 1, a default value of 500 will be used instead.
+func Import(ctx context.Context, dst Target, r Reader, batchSize int) (Stats, error) {
+	var (
+		stats    Stats
+		links    []*graph.Link
+		edges    []*graph.Edge
+		deferred []*graph.Edge
+	)
+	if batchSize < 1 {
+		batchSize = 500
+	}
+
+	flushLinks := func() error {
+		if len(links) == 0 {
+			return nil
+		}
+		if err := dst.RestoreLinks(ctx, links); err != nil {
+			return err
+		}
+		stats.Links += len(links)
+		links = links[:0]
+		return nil
+	}
+	restoreEdges := func(batch []*graph.Edge) error {
+		if err := dst.RestoreEdges(ctx, batch); err != nil {
+			return err
+		}
+		stats.Edges += len(batch)
+		return nil
+	}
+	flushEdges := func() error {
+		if len(edges) == 0 {
+			return nil
+		}
+		if err := flushLinks(); err != nil {
+			return err
+		}
+		// RestoreEdges restores nothing if any edge refers to an unknown
+		// link so the whole batch can be retried later.
+		if err := restoreEdges(edges); xerrors.Is(err, graph.ErrUnknownEdgeLinks) {
+			deferred = append(deferred, edges...)
+		} else if err != nil {
+			return err
+		}
+		edges = edges[:0]
+		return nil
+	}
+
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return stats, xerrors.Errorf("import: %w", err)
+		}
+
+		switch {
+		case rec.Link != nil:
+			if links = append(links, rec.Link); len(links) >= batchSize {
+				err = flushLinks()
+			}
+		case rec.Edge != nil:
+			if edges = append(edges, rec.Edge); len(edges) >= batchSize {
+				err = flushEdges()
+			}
+		}
+		if err != nil {
+			return stats, xerrors.Errorf("import: %w", err)
+		}
+	}
+
+	if err := flushLinks(); err != nil {
+		return stats, xerrors.Errorf("import: %w", err)
+	}
+	if err := flushEdges(); err != nil {
+		return stats, xerrors.Errorf("import: %w", err)
+	}
+	for start := 0; start < len(deferred); start += batchSize {
+		end := start + batchSize
+		if end > len(deferred) {
+			end = len(deferred)
+		}
+		if err := restoreEdges(deferred[start:end]); err != nil {
+			return stats, xerrors.Errorf("import: %w", err)
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,234 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"github.com/joshvoll/linkrus/internal/linkgraph/store/memory"
+	"github.com/joshvoll/linkrus/internal/partition"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(SnapshotTestSuite))
+
+// SnapshotTestSuite verifies the snapshot formats and the export/import
+// round-trip between link graphs.
+type SnapshotTestSuite struct {
+	src   *memory.InMemoryGraph
+	links []*graph.Link
+	edges []*graph.Edge
+}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *SnapshotTestSuite) SetUpTest(c *gc.C) {
+	ctx := context.Background()
+	now := time.Date(2020, time.March, 1, 12, 30, 45, 123456789, time.UTC)
+	s.src = memory.NewInMemoryGraph()
+	s.links = []*graph.Link{
+		{URL: "https://example.com/", RetrievedAt: now, StatusCode: 200, ContentHash: "abcd"},
+		{URL: "https://example.com/a?x=1&y=<2>", RetrievedAt: now.Add(-time.Hour), StatusCode: 301, RedirectURL: "https://example.com/b"},
+		{URL: "https://example.com/broken", FailureCount: 3, LastError: "connection \"reset\""},
+	}
+	for _, link := range s.links {
+		c.Assert(s.src.UpsertLink(ctx, link), gc.IsNil)
+	}
+	s.edges = []*graph.Edge{
+		{Src: s.links[0].ID, Dst: s.links[1].ID, AnchorText: "Fish & chips", Position: 42},
+		{Src: s.links[0].ID, Dst: s.links[2].ID, NoFollow: true, Position: 7},
+		{Src: s.links[1].ID, Dst: s.links[0].ID},
+	}
+	for _, edge := range s.edges {
+		c.Assert(s.src.UpsertEdge(ctx, edge), gc.IsNil)
+	}
+}
+
+func (s *SnapshotTestSuite) TestBinaryRoundTrip(c *gc.C) {
+	var buf bytes.Buffer
+	s.assertRoundTrip(c, NewBinaryWriter(&buf), func() Reader { return NewBinaryReader(&buf) })
+}
+
+func (s *SnapshotTestSuite) TestJSONLRoundTrip(c *gc.C) {
+	var buf bytes.Buffer
+	s.assertRoundTrip(c, NewJSONLWriter(&buf), func() Reader { return NewJSONLReader(&buf) })
+}
+
+func (s *SnapshotTestSuite) assertRoundTrip(c *gc.C, w Writer, newReader func() Reader) {
+	ctx := context.Background()
+	stats, err := Export(ctx, s.src, w, 4)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stats, gc.DeepEquals, Stats{Links: len(s.links), Edges: len(s.edges)})
+
+	dst := memory.NewInMemoryGraph()
+	stats, err = Import(ctx, dst, newReader(), 2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stats, gc.DeepEquals, Stats{Links: len(s.links), Edges: len(s.edges)})
+
+	for _, exp := range s.links {
+		got, err := dst.FindLink(ctx, exp.ID)
+		c.Assert(err, gc.IsNil)
+		c.Assert(got.RetrievedAt.Equal(exp.RetrievedAt), gc.Equals, true, gc.Commentf("link %s", exp.URL))
+		got.RetrievedAt = exp.RetrievedAt
+		c.Assert(got, gc.DeepEquals, exp)
+	}
+
+	edgeIt, err := dst.Edges(ctx, uuid.Nil, partition.MaxUUID, maxTimestamp)
+	c.Assert(err, gc.IsNil)
+	gotEdges := make(map[[2]string]*graph.Edge)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		gotEdges[[2]string{edge.Src.String(), edge.Dst.String()}] = edge
+	}
+	c.Assert(edgeIt.Error(), gc.IsNil)
+	c.Assert(edgeIt.Close(), gc.IsNil)
+	c.Assert(gotEdges, gc.HasLen, len(s.edges))
+	for _, exp := range s.edges {
+		got := gotEdges[[2]string{exp.Src.String(), exp.Dst.String()}]
+		c.Assert(got, gc.NotNil)
+		c.Assert(got.UpdateAt.Equal(exp.UpdateAt), gc.Equals, true)
+		got.UpdateAt = exp.UpdateAt
+		c.Assert(got, gc.DeepEquals, exp)
+	}
+}
+
+func (s *SnapshotTestSuite) TestImportEdgesBeforeLinks(c *gc.C) {
+	ctx := context.Background()
+	var records []Record
+	for _, edge := range s.edges {
+		records = append(records, Record{Edge: edge})
+	}
+	for _, link := range s.links {
+		records = append(records, Record{Link: link})
+	}
+
+	dst := memory.NewInMemoryGraph()
+	stats, err := Import(ctx, dst, &recordReader{records: records}, 2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stats, gc.DeepEquals, Stats{Links: len(s.links), Edges: len(s.edges)})
+
+	in, err := dst.InDegree(ctx, s.links[0].ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(in, gc.Equals, 1)
+}
+
+func (s *SnapshotTestSuite) TestImportEdgeWithUnknownLink(c *gc.C) {
+	records := []Record{
+		{Link: s.links[0]},
+		{Edge: &graph.Edge{Src: s.links[0].ID, Dst: uuid.New()}},
+	}
+	_, err := Import(context.Background(), memory.NewInMemoryGraph(), &recordReader{records: records}, 2)
+	c.Assert(xerrors.Is(err, graph.ErrUnknownEdgeLinks), gc.Equals, true)
+}
+
+func (s *SnapshotTestSuite) TestBinaryReaderErrors(c *gc.C) {
+	_, err := NewBinaryReader(strings.NewReader("not a snapshot")).Read()
+	c.Assert(xerrors.Is(err, ErrInvalidSnapshot), gc.Equals, true)
+
+	var buf bytes.Buffer
+	w := NewBinaryWriter(&buf)
+	c.Assert(w.WriteLink(s.links[0]), gc.IsNil)
+	c.Assert(w.Close(), gc.IsNil)
+
+	truncated := buf.Bytes()[:buf.Len()-3]
+	_, err = NewBinaryReader(bytes.NewReader(truncated)).Read()
+	c.Assert(xerrors.Is(err, io.ErrUnexpectedEOF), gc.Equals, true)
+}
+
+func (s *SnapshotTestSuite) TestJSONLReaderErrors(c *gc.C) {
+	_, err := NewJSONLReader(strings.NewReader(`{"type":"vertex"}`)).Read()
+	c.Assert(xerrors.Is(err, ErrUnsupportedRecord), gc.Equals, true)
+
+	_, err = NewJSONLReader(strings.NewReader("\n{\"type\":\"edge\"}\n")).Read()
+	c.Assert(err, gc.ErrorMatches, ".*line 2: edge without src or dst.*")
+}
+
+func (s *SnapshotTestSuite) TestEdgeListExport(c *gc.C) {
+	var buf bytes.Buffer
+	_, err := Export(context.Background(), s.src, NewEdgeListWriter(&buf), 1)
+	c.Assert(err, gc.IsNil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	c.Assert(lines, gc.HasLen, len(s.edges))
+	for _, edge := range s.edges {
+		c.Assert(lines, hasItem, edge.Src.String()+"\t"+edge.Dst.String())
+	}
+}
+
+func (s *SnapshotTestSuite) TestGraphMLExport(c *gc.C) {
+	var buf bytes.Buffer
+	_, err := Export(context.Background(), s.src, NewGraphMLWriter(&buf), 2)
+	c.Assert(err, gc.IsNil)
+
+	var doc struct {
+		Graph struct {
+			Nodes []struct {
+				ID   string `xml:"id,attr"`
+				Data []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	c.Assert(xml.Unmarshal(buf.Bytes(), &doc), gc.IsNil)
+	c.Assert(doc.Graph.Nodes, gc.HasLen, len(s.links))
+	c.Assert(doc.Graph.Edges, gc.HasLen, len(s.edges))
+
+	urls := make(map[string]string)
+	for _, node := range doc.Graph.Nodes {
+		for _, data := range node.Data {
+			if data.Key == "url" {
+				urls[node.ID] = data.Value
+			}
+		}
+	}
+	for _, link := range s.links {
+		c.Assert(urls[link.ID.String()], gc.Equals, link.URL)
+	}
+}
+
+// hasItem checks whether a []string contains the expected value.
+var hasItem gc.Checker = &hasItemChecker{
+	&gc.CheckerInfo{Name: "hasItem", Params: []string{"obtained", "expected"}},
+}
+
+type hasItemChecker struct {
+	*gc.CheckerInfo
+}
+
+func (checker *hasItemChecker) Check(params []interface{}, _ []string) (bool, string) {
+	for _, item := range params[0].([]string) {
+		if item == params[1].(string) {
+			return true, ""
+		}
+	}
+	return false, ""
+}
+
+// recordReader is a Reader that returns a fixed list of records.
+type recordReader struct {
+	records []Record
+}
+
+func (r *recordReader) Read() (Record, error) {
+	if len(r.records) == 0 {
+		return Record{}, io.EOF
+	}
+	rec := r.records[0]
+	r.records = r.records[1:]
+	return rec, nil
+}
@@ -0,0 +1,553 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	bbolt "go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// linksBucket maps link IDs to encoded link records.
+	linksBucket = []byte("links")
+
+	// linkURLsBucket is a secondary index that maps link URLs to link IDs.
+	linkURLsBucket = []byte("link_urls")
+
+	// edgesBucket maps the concatenation of the source and destination
+	// link IDs to encoded edge records. As keys are prefixed by the
+	// source ID, it doubles as a source ID index for edge range scans.
+	edgesBucket = []byte("edges")
+
+	// edgesByDstBucket is a secondary index for looking up incoming
+	// edges. It maps the concatenation of the destination and source link
+	// IDs to a copy of the encoded edge record.
+	edgesByDstBucket = []byte("edges_by_dst")
+)
+
+// BoltDBGraph implements a graph.Graph that persists links and edges to an
+// embedded bbolt database. All writes are performed in fsync'ed transactions
+// so the graph contents survive process crashes.
+type BoltDBGraph struct {
+	db *bbolt.DB
+}
+
+// NewBoltDBGraph opens (or creates) the bbolt database at the specified path
+// and returns a BoltDBGraph instance backed by it.
+func NewBoltDBGraph(path string) (*BoltDBGraph, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, xerrors.Errorf("open bolt graph: %w", err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{linksBucket, linkURLsBucket, edgesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if tx.Bucket(edgesByDstBucket) == nil {
+			return buildEdgesByDstIndex(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, xerrors.Errorf("open bolt graph: %w", err)
+	}
+	return &BoltDBGraph{db: db}, nil
+}
+
+// buildEdgesByDstIndex creates and populates the edges by destination index
+// for databases that were created before the index was introduced.
+func buildEdgesByDstIndex(tx *bbolt.Tx) error {
+	index, err := tx.CreateBucket(edgesByDstBucket)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(edgesBucket).ForEach(func(k, v []byte) error {
+		return index.Put(edgeByDstKey(k[:16], k[16:]), v)
+	})
+}
+
+// Close releases the underlying database.
+func (s *BoltDBGraph) Close() error {
+	return s.db.Close()
+}
+
+// UpsertLink creates a new link or updates an existing one.
+func (s *BoltDBGraph) UpsertLink(ctx context.Context, link *graph.Link) error {
+	if err := s.db.Update(func(tx *bbolt.Tx) error { return upsertLink(tx, link) }); err != nil {
+		return xerrors.Errorf("upsert link: %w", err)
+	}
+	return nil
+}
+
+// UpsertLinks creates or updates a batch of links within a single
+// transaction.
+func (s *BoltDBGraph) UpsertLinks(ctx context.Context, links []*graph.Link) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		for _, link := range links {
+			if err := upsertLink(tx, link); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("upsert links: %w", err)
+	}
+	return nil
+}
+
+// upsertLink inserts or updates link using the URL index to detect existing
+// links. When updating a link, the most recent retrieval timestamp is kept.
+func upsertLink(tx *bbolt.Tx, link *graph.Link) error {
+	links, urls := tx.Bucket(linksBucket), tx.Bucket(linkURLsBucket)
+	if idBytes := urls.Get([]byte(link.URL)); idBytes != nil {
+		existing, err := decodeLink(idBytes, links.Get(idBytes))
+		if err != nil {
+			return err
+		}
+		// Only overwrite the stored fetch metadata if the link was
+		// retrieved more recently; otherwise report the stored values
+		// back to the caller.
+		if !link.RetrievedAt.After(existing.RetrievedAt) {
+			*link = *existing
+			return nil
+		}
+		link.ID = existing.ID
+	} else {
+		for {
+			link.ID = uuid.New()
+			if links.Get(link.ID[:]) == nil {
+				break
+			}
+		}
+		if err := urls.Put([]byte(link.URL), link.ID[:]); err != nil {
+			return err
+		}
+	}
+	link.RetrievedAt = link.RetrievedAt.UTC()
+
+	data, err := encodeLink(link)
+	if err != nil {
+		return err
+	}
+	return links.Put(link.ID[:], data)
+}
+
+// FindLink looks up a link by its ID.
+func (s *BoltDBGraph) FindLink(ctx context.Context, id uuid.UUID) (*graph.Link, error) {
+	var link *graph.Link
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(linksBucket).Get(id[:])
+		if data == nil {
+			return graph.ErrNotFound
+		}
+		var err error
+		link, err = decodeLink(id[:], data)
+		return err
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("find link: %w", err)
+	}
+	return link, nil
+}
+
+// RemoveLink removes a link together with its URL index entry and all the
+// edges that originate from or point to it.
+func (s *BoltDBGraph) RemoveLink(ctx context.Context, id uuid.UUID) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(linksBucket).Get(id[:]) == nil {
+			return graph.ErrNotFound
+		}
+		return removeLink(tx, id)
+	})
+	if err != nil {
+		return xerrors.Errorf("remove link: %w", err)
+	}
+	return nil
+}
+
+// RemoveLinks removes a batch of links and their edges within a single
+// transaction.
+func (s *BoltDBGraph) RemoveLinks(ctx context.Context, ids []uuid.UUID) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		for _, id := range ids {
+			if tx.Bucket(linksBucket).Get(id[:]) == nil {
+				continue
+			}
+			if err := removeLink(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("remove links: %w", err)
+	}
+	return nil
+}
+
+// RemoveOrphanLinks removes the links in ids that have no incoming edges and
+// were retrieved before the provided timestamp within a single transaction.
+func (s *BoltDBGraph) RemoveOrphanLinks(ctx context.Context, ids []uuid.UUID, retrievedBefore time.Time) ([]uuid.UUID, error) {
+	var removed []uuid.UUID
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		removed = removed[:0]
+		for _, id := range ids {
+			data := tx.Bucket(linksBucket).Get(id[:])
+			if data == nil {
+				continue
+			}
+			link, err := decodeLink(id[:], data)
+			if err != nil {
+				return err
+			}
+			if link.RetrievedAt.IsZero() || !link.RetrievedAt.Before(retrievedBefore) {
+				continue
+			}
+			if k, _ := tx.Bucket(edgesByDstBucket).Cursor().Seek(id[:]); k != nil && bytes.HasPrefix(k, id[:]) {
+				continue
+			}
+			removed = append(removed, id)
+		}
+
+		// Select all orphans before removing any of them so that links
+		// orphaned by the removal are left for the next call.
+		for _, id := range removed {
+			if err := removeLink(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("remove orphan links: %w", err)
+	}
+	return removed, nil
+}
+
+// removeLink purges the link with the specified ID, its URL index entry and
+// all its incoming and outgoing edges.
+func removeLink(tx *bbolt.Tx, id uuid.UUID) error {
+	links := tx.Bucket(linksBucket)
+	link, err := decodeLink(id[:], links.Get(id[:]))
+	if err != nil {
+		return err
+	}
+	if err = tx.Bucket(linkURLsBucket).Delete([]byte(link.URL)); err != nil {
+		return err
+	}
+	if err = links.Delete(id[:]); err != nil {
+		return err
+	}
+
+	// Collect the keys of the outgoing and incoming edges before deleting
+	// them as deleting keys while iterating a cursor may skip entries.
+	var edgeKeys [][]byte
+	for _, bucket := range [][]byte{edgesBucket, edgesByDstBucket} {
+		c := tx.Bucket(bucket).Cursor()
+		for k, _ := c.Seek(id[:]); k != nil && bytes.HasPrefix(k, id[:]); k, _ = c.Next() {
+			key := append([]byte(nil), k...)
+			if bytes.Equal(bucket, edgesByDstBucket) {
+				// Convert index keys into edges bucket keys.
+				key = edgeByDstKey(key[:16], key[16:])
+			}
+			edgeKeys = append(edgeKeys, key)
+		}
+	}
+	edges, index := tx.Bucket(edgesBucket), tx.Bucket(edgesByDstBucket)
+	for _, k := range edgeKeys {
+		if err = edges.Delete(k); err != nil {
+			return err
+		}
+		if err = index.Delete(edgeByDstKey(k[:16], k[16:])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindLinkByURL looks up a link by its URL using the URL index.
+func (s *BoltDBGraph) FindLinkByURL(ctx context.Context, url string) (*graph.Link, error) {
+	var link *graph.Link
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		idBytes := tx.Bucket(linkURLsBucket).Get([]byte(url))
+		if idBytes == nil {
+			return graph.ErrNotFound
+		}
+		var err error
+		link, err = decodeLink(idBytes, tx.Bucket(linksBucket).Get(idBytes))
+		return err
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("find link by url: %w", err)
+	}
+	return link, nil
+}
+
+// LinksWithURLPrefix returns an iterator for the set of links whose URL
+// starts with prefix, ordered by URL.
+func (s *BoltDBGraph) LinksWithURLPrefix(ctx context.Context, prefix string) (graph.LinkIterator, error) {
+	return &linkIterator{
+		db:      s.db,
+		scanner: newPrefixScanner(s.db, linkURLsBucket, []byte(prefix)),
+		byURL:   true,
+	}, nil
+}
+
+// Links returns an iterator for the set of links whose IDs belong to the
+// [fromID, toID) range and were retrieved before the provided timestamp.
+func (s *BoltDBGraph) Links(ctx context.Context, fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error) {
+	return &linkIterator{
+		db:              s.db,
+		scanner:         newRangeScanner(s.db, linksBucket, fromID[:], toID[:]),
+		retrievedBefore: retrievedBefore,
+	}, nil
+}
+
+// UpsertEdge creates a new edge or updates an existing one.
+func (s *BoltDBGraph) UpsertEdge(ctx context.Context, edge *graph.Edge) error {
+	if err := s.db.Update(func(tx *bbolt.Tx) error { return upsertEdge(tx, edge) }); err != nil {
+		return xerrors.Errorf("upsert edge: %w", err)
+	}
+	return nil
+}
+
+// UpsertEdges creates or updates a batch of edges within a single
+// transaction. If any edge refers to an unknown link, the transaction is
+// rolled back and no edge is upserted.
+func (s *BoltDBGraph) UpsertEdges(ctx context.Context, edges []*graph.Edge) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		for _, edge := range edges {
+			if err := upsertEdge(tx, edge); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("upsert edges: %w", err)
+	}
+	return nil
+}
+
+// upsertEdge inserts edge or refreshes the update timestamp of an existing
+// edge with the same endpoints.
+func upsertEdge(tx *bbolt.Tx, edge *graph.Edge) error {
+	links := tx.Bucket(linksBucket)
+	if links.Get(edge.Src[:]) == nil || links.Get(edge.Dst[:]) == nil {
+		return graph.ErrUnknownEdgeLinks
+	}
+
+	edges := tx.Bucket(edgesBucket)
+	key := edgeKey(edge.Src, edge.Dst)
+	if data := edges.Get(key); data != nil {
+		existing, err := decodeEdge(key, data)
+		if err != nil {
+			return err
+		}
+		edge.ID = existing.ID
+	} else {
+		edge.ID = uuid.New()
+	}
+	edge.UpdateAt = time.Now().UTC()
+
+	data, err := encodeEdge(edge)
+	if err != nil {
+		return err
+	}
+	if err = edges.Put(key, data); err != nil {
+		return err
+	}
+	return tx.Bucket(edgesByDstBucket).Put(edgeByDstKey(edge.Src[:], edge.Dst[:]), data)
+}
+
+// Edges returns an iterator for the set of edges whose source vertex IDs
+// belong to the [fromID, toID) range and were updated before the provided
+// timestamp.
+func (s *BoltDBGraph) Edges(ctx context.Context, fromID, toID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error) {
+	return &edgeIterator{
+		scanner:       newRangeScanner(s.db, edgesBucket, fromID[:], toID[:]),
+		updatedBefore: updatedBefore,
+	}, nil
+}
+
+// EdgesTo returns an iterator for the set of edges that point to dstID and
+// were updated before the provided timestamp.
+func (s *BoltDBGraph) EdgesTo(ctx context.Context, dstID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error) {
+	return &edgeIterator{
+		scanner:       newPrefixScanner(s.db, edgesByDstBucket, dstID[:]),
+		updatedBefore: updatedBefore,
+		byDst:         true,
+	}, nil
+}
+
+// InDegree returns the number of edges that point to the specified link.
+func (s *BoltDBGraph) InDegree(ctx context.Context, linkID uuid.UUID) (int, error) {
+	degree, err := s.countWithPrefix(edgesByDstBucket, linkID)
+	if err != nil {
+		return 0, xerrors.Errorf("in degree: %w", err)
+	}
+	return degree, nil
+}
+
+// OutDegree returns the number of edges that originate from the specified
+// link.
+func (s *BoltDBGraph) OutDegree(ctx context.Context, linkID uuid.UUID) (int, error) {
+	degree, err := s.countWithPrefix(edgesBucket, linkID)
+	if err != nil {
+		return 0, xerrors.Errorf("out degree: %w", err)
+	}
+	return degree, nil
+}
+
+// countWithPrefix counts the keys in bucket that are prefixed by linkID. It
+// returns graph.ErrNotFound if linkID does not refer to a known link.
+func (s *BoltDBGraph) countWithPrefix(bucket []byte, linkID uuid.UUID) (int, error) {
+	var count int
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket(linksBucket).Get(linkID[:]) == nil {
+			return graph.ErrNotFound
+		}
+		c := tx.Bucket(bucket).Cursor()
+		for k, _ := c.Seek(linkID[:]); k != nil && bytes.HasPrefix(k, linkID[:]); k, _ = c.Next() {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// RemoveStalEdges removes any edge that originates from the specified link
+// ID and was updated before the specified timestamp.
+func (s *BoltDBGraph) RemoveStalEdges(ctx context.Context, fromID uuid.UUID, updatedBefore time.Time) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		edges := tx.Bucket(edgesBucket)
+
+		// Deleting keys while iterating a bbolt cursor may skip entries
+		// so collect the stale keys first.
+		var staleKeys [][]byte
+		c := edges.Cursor()
+		for k, v := c.Seek(fromID[:]); k != nil && bytes.HasPrefix(k, fromID[:]); k, v = c.Next() {
+			edge, err := decodeEdge(k, v)
+			if err != nil {
+				return err
+			}
+			if edge.UpdateAt.Before(updatedBefore) {
+				staleKeys = append(staleKeys, append([]byte(nil), k...))
+			}
+		}
+		index := tx.Bucket(edgesByDstBucket)
+		for _, k := range staleKeys {
+			if err := edges.Delete(k); err != nil {
+				return err
+			}
+			if err := index.Delete(edgeByDstKey(k[:16], k[16:])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("remove stale edges: %w", err)
+	}
+	return nil
+}
+
+// edgeByDstKey returns the edges by destination index key for an edge from
+// src to dst.
+func edgeByDstKey(src, dst []byte) []byte {
+	key := make([]byte, 0, len(src)+len(dst))
+	key = append(key, dst...)
+	return append(key, src...)
+}
+
+// edgeKey returns the edges bucket key for an edge from src to dst.
+func edgeKey(src, dst uuid.UUID) []byte {
+	key := make([]byte, 0, 2*len(src))
+	key = append(key, src[:]...)
+	return append(key, dst[:]...)
+}
+
+// RestoreLinks inserts or replaces a batch of links keeping their IDs,
+// timestamps and fetch metadata. If any URL already belongs to a link with a
+// different ID, the transaction is rolled back and no link is restored.
+func (s *BoltDBGraph) RestoreLinks(ctx context.Context, links []*graph.Link) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		linkBucket, urls := tx.Bucket(linksBucket), tx.Bucket(linkURLsBucket)
+		for _, link := range links {
+			if idBytes := urls.Get([]byte(link.URL)); idBytes != nil && !bytes.Equal(idBytes, link.ID[:]) {
+				return graph.ErrConflict
+			}
+			if data := linkBucket.Get(link.ID[:]); data != nil {
+				existing, err := decodeLink(link.ID[:], data)
+				if err != nil {
+					return err
+				}
+				if existing.URL != link.URL {
+					if err = urls.Delete([]byte(existing.URL)); err != nil {
+						return err
+					}
+				}
+			}
+
+			restored := *link
+			restored.RetrievedAt = restored.RetrievedAt.UTC()
+			data, err := encodeLink(&restored)
+			if err != nil {
+				return err
+			}
+			if err = linkBucket.Put(link.ID[:], data); err != nil {
+				return err
+			}
+			if err = urls.Put([]byte(link.URL), link.ID[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("restore links: %w", err)
+	}
+	return nil
+}
+
+// RestoreEdges inserts or replaces a batch of edges keeping their IDs,
+// timestamps and annotations. If any edge refers to an unknown link, the
+// transaction is rolled back and no edge is restored. As edges are keyed by
+// their endpoints, edge IDs are not checked for uniqueness.
+func (s *BoltDBGraph) RestoreEdges(ctx context.Context, edges []*graph.Edge) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		links := tx.Bucket(linksBucket)
+		edgeBucket, index := tx.Bucket(edgesBucket), tx.Bucket(edgesByDstBucket)
+		for _, edge := range edges {
+			if links.Get(edge.Src[:]) == nil || links.Get(edge.Dst[:]) == nil {
+				return graph.ErrUnknownEdgeLinks
+			}
+
+			restored := *edge
+			restored.UpdateAt = restored.UpdateAt.UTC()
+			data, err := encodeEdge(&restored)
+			if err != nil {
+				return err
+			}
+			if err = edgeBucket.Put(edgeKey(edge.Src, edge.Dst), data); err != nil {
+				return err
+			}
+			if err = index.Put(edgeByDstKey(edge.Src[:], edge.Dst[:]), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("restore edges: %w", err)
+	}
+	return nil
+}
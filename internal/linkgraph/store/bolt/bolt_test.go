@@ -0,0 +1,99 @@
+package bolt
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph/graphtest"
+	gc "gopkg.in/check.v1"
+)
+
+var (
+	_ = gc.Suite(new(BoltDBGraphTestSuite))
+
+	maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+)
+
+// BoltDBGraphTestSuite runs the shared graph test-suite against a bbolt
+// database stored in a temporary folder.
+type BoltDBGraphTestSuite struct {
+	graphtest.SuiteBase
+	dir string
+	g   *BoltDBGraph
+}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *BoltDBGraphTestSuite) SetUpTest(c *gc.C) {
+	dir, err := ioutil.TempDir("", "bolt-graph-test")
+	c.Assert(err, gc.IsNil)
+	s.dir = dir
+
+	g, err := NewBoltDBGraph(filepath.Join(dir, "graph.db"))
+	c.Assert(err, gc.IsNil)
+	s.SetGraph(g)
+	s.g = g
+}
+
+func (s *BoltDBGraphTestSuite) TearDownTest(c *gc.C) {
+	c.Assert(s.g.Close(), gc.IsNil)
+	c.Assert(os.RemoveAll(s.dir), gc.IsNil)
+}
+
+func (s *BoltDBGraphTestSuite) TestDataSurvivesReopen(c *gc.C) {
+	s.SuiteBase.TestUpsertLink(c)
+	c.Assert(s.g.Close(), gc.IsNil)
+
+	g, err := NewBoltDBGraph(filepath.Join(s.dir, "graph.db"))
+	c.Assert(err, gc.IsNil)
+	s.g = g
+	s.SetGraph(g)
+
+	it, err := g.Links(context.Background(), uuid.Nil, maxUUID, time.Now())
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.Next(), gc.Equals, true, gc.Commentf("expected link to be persisted"))
+	c.Assert(it.Close(), gc.IsNil)
+}
+
+func (s *BoltDBGraphTestSuite) TestDecodeLegacyLinkRecord(c *gc.C) {
+	retrievedAt := time.Now().UTC().Truncate(time.Second)
+	ts, err := retrievedAt.MarshalBinary()
+	c.Assert(err, gc.IsNil)
+
+	id := uuid.New()
+	data := append([]byte{byte(len(ts))}, ts...)
+	data = append(data, "https://example.com"...)
+
+	link, err := decodeLink(id[:], data)
+	c.Assert(err, gc.IsNil)
+	c.Assert(link.ID, gc.Equals, id)
+	c.Assert(link.URL, gc.Equals, "https://example.com")
+	c.Assert(link.RetrievedAt.Equal(retrievedAt), gc.Equals, true)
+	c.Assert(link.StatusCode, gc.Equals, 0)
+
+	_, err = decodeLink(id[:], []byte{linkRecordV1, 200})
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *BoltDBGraphTestSuite) TestDecodeLegacyEdgeRecord(c *gc.C) {
+	updatedAt := time.Now().UTC().Truncate(time.Second)
+	ts, err := updatedAt.MarshalBinary()
+	c.Assert(err, gc.IsNil)
+
+	id, src, dst := uuid.New(), uuid.New(), uuid.New()
+	edge, err := decodeEdge(append(src[:], dst[:]...), append(id[:], ts...))
+	c.Assert(err, gc.IsNil)
+	c.Assert(edge.ID, gc.Equals, id)
+	c.Assert(edge.Src, gc.Equals, src)
+	c.Assert(edge.Dst, gc.Equals, dst)
+	c.Assert(edge.UpdateAt.Equal(updatedAt), gc.Equals, true)
+	c.Assert(edge.AnchorText, gc.Equals, "")
+	c.Assert(edge.NoFollow, gc.Equals, false)
+}
@@ -0,0 +1,216 @@
+package bolt
+
+import (
+	"encoding/binary"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"golang.org/x/xerrors"
+)
+
+// errCorruptRecord is returned when a stored record cannot be decoded.
+var errCorruptRecord = xerrors.New("corrupt record")
+
+// linkRecordV1 marks link records that carry fetch metadata. Records
+// written by earlier versions start with the length of the binary timestamp
+// which is always smaller than this marker.
+const linkRecordV1 = 0xff
+
+// encodeLink serializes a link record as a version marker followed by a
+// length-prefixed binary timestamp, the fetch status code, the URL, the
+// content hash, the redirect URL, the failure count and the last error. The
+// link ID is used as the record key.
+func encodeLink(link *graph.Link) ([]byte, error) {
+	ts, err := link.RetrievedAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, 2+len(ts)+len(link.URL)+len(link.ContentHash)+len(link.RedirectURL)+len(link.LastError)+6*binary.MaxVarintLen64)
+	data = append(data, linkRecordV1, byte(len(ts)))
+	data = append(data, ts...)
+	data = appendVarint(data, int64(link.StatusCode))
+	data = appendString(data, link.URL)
+	data = appendString(data, link.ContentHash)
+	data = appendString(data, link.RedirectURL)
+	data = appendVarint(data, int64(link.FailureCount))
+	return appendString(data, link.LastError), nil
+}
+
+// decodeLink deserializes the link record stored under key.
+func decodeLink(key, data []byte) (*graph.Link, error) {
+	id, err := uuid.FromBytes(key)
+	if err != nil || len(data) == 0 {
+		return nil, xerrors.Errorf("decode link: %w", errCorruptRecord)
+	}
+	if data[0] != linkRecordV1 {
+		return decodeLegacyLink(id, data)
+	}
+
+	r := recordReader{data: data[1:]}
+	link := &graph.Link{ID: id}
+	ts := r.bytes(int(r.byte()))
+	link.StatusCode = int(r.varint())
+	link.URL = r.string()
+	link.ContentHash = r.string()
+	link.RedirectURL = r.string()
+	link.FailureCount = int(r.varint())
+	link.LastError = r.string()
+	if r.err != nil {
+		return nil, xerrors.Errorf("decode link: %w", r.err)
+	}
+	if err = link.RetrievedAt.UnmarshalBinary(ts); err != nil {
+		return nil, xerrors.Errorf("decode link: %w", err)
+	}
+	link.RetrievedAt = link.RetrievedAt.UTC()
+	return link, nil
+}
+
+// decodeLegacyLink deserializes a link record that was written before fetch
+// metadata was tracked. Such records consist of a length-prefixed binary
+// timestamp followed by the link URL.
+func decodeLegacyLink(id uuid.UUID, data []byte) (*graph.Link, error) {
+	if len(data) < 1+int(data[0]) {
+		return nil, xerrors.Errorf("decode link: %w", errCorruptRecord)
+	}
+	link := &graph.Link{ID: id, URL: string(data[1+data[0]:])}
+	if err := link.RetrievedAt.UnmarshalBinary(data[1 : 1+data[0]]); err != nil {
+		return nil, xerrors.Errorf("decode link: %w", err)
+	}
+	link.RetrievedAt = link.RetrievedAt.UTC()
+	return link, nil
+}
+
+func appendVarint(data []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(data, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+func appendString(data []byte, v string) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	data = append(data, buf[:binary.PutUvarint(buf[:], uint64(len(v)))]...)
+	return append(data, v...)
+}
+
+// recordReader decodes the fields of a serialized record. Once a read fails,
+// all subsequent reads return zero values and err is set.
+type recordReader struct {
+	data []byte
+	err  error
+}
+
+func (r *recordReader) byte() byte {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *recordReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data) {
+		r.err = errCorruptRecord
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *recordReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = errCorruptRecord
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *recordReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	l, n := binary.Uvarint(r.data)
+	if n <= 0 || l > uint64(len(r.data)-n) {
+		r.err = errCorruptRecord
+		return ""
+	}
+	r.data = r.data[n:]
+	return string(r.bytes(int(l)))
+}
+
+// edgeRecordV1 marks edge records that carry annotations. In records
+// written by earlier versions, the edge ID is followed by a binary timestamp
+// whose leading version byte is always smaller than this marker.
+const edgeRecordV1 = 0xff
+
+// encodeEdge serializes an edge record as the edge ID followed by a version
+// marker, a length-prefixed binary update timestamp, the nofollow flag, the
+// link position and the anchor text. The source and destination IDs are used
+// as the record key.
+func encodeEdge(edge *graph.Edge) ([]byte, error) {
+	ts, err := edge.UpdateAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, len(edge.ID)+3+len(ts)+len(edge.AnchorText)+2*binary.MaxVarintLen64)
+	data = append(data, edge.ID[:]...)
+	data = append(data, edgeRecordV1, byte(len(ts)))
+	data = append(data, ts...)
+	var noFollow byte
+	if edge.NoFollow {
+		noFollow = 1
+	}
+	data = append(data, noFollow)
+	data = appendVarint(data, int64(edge.Position))
+	return appendString(data, edge.AnchorText), nil
+}
+
+// decodeEdge deserializes the edge record stored in the edges bucket under
+// key.
+func decodeEdge(key, data []byte) (*graph.Edge, error) {
+	if len(key) != 2*len(uuid.UUID{}) {
+		return nil, xerrors.Errorf("decode edge: %w", errCorruptRecord)
+	}
+	return decodeEdgeRecord(key[:16], key[16:], data)
+}
+
+// decodeEdgeByDst deserializes the edge record stored in the edges by
+// destination index under key.
+func decodeEdgeByDst(key, data []byte) (*graph.Edge, error) {
+	if len(key) != 2*len(uuid.UUID{}) {
+		return nil, xerrors.Errorf("decode edge: %w", errCorruptRecord)
+	}
+	return decodeEdgeRecord(key[16:], key[:16], data)
+}
+
+func decodeEdgeRecord(src, dst, data []byte) (*graph.Edge, error) {
+	if len(data) < len(uuid.UUID{}) {
+		return nil, xerrors.Errorf("decode edge: %w", errCorruptRecord)
+	}
+	edge := new(graph.Edge)
+	copy(edge.Src[:], src)
+	copy(edge.Dst[:], dst)
+	copy(edge.ID[:], data[:16])
+
+	// Records without a version marker only contain the update timestamp.
+	ts := data[16:]
+	if len(ts) != 0 && ts[0] == edgeRecordV1 {
+		r := recordReader{data: ts[1:]}
+		ts = r.bytes(int(r.byte()))
+		edge.NoFollow = r.byte() != 0
+		edge.Position = int(r.varint())
+		edge.AnchorText = r.string()
+		if r.err != nil {
+			return nil, xerrors.Errorf("decode edge: %w", r.err)
+		}
+	}
+	if err := edge.UpdateAt.UnmarshalBinary(ts); err != nil {
+		return nil, xerrors.Errorf("decode edge: %w", err)
+	}
+	edge.UpdateAt = edge.UpdateAt.UTC()
+	return edge, nil
+}
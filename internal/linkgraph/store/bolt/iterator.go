@@ -0,0 +1,207 @@
+package bolt
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// scanBatchSize is the number of records that a rangeScanner reads from the
+// database in a single read transaction.
+const scanBatchSize = 512
+
+// kvPair is a key/value pair copied out of a bbolt transaction.
+type kvPair struct {
+	key, value []byte
+}
+
+// rangeScanner reads the records of a bucket whose keys belong to a
+// contiguous key range in batches. Each batch is read in a separate
+// short-lived transaction so that open iterators do not block writers from
+// growing the database file.
+type rangeScanner struct {
+	db      *bbolt.DB
+	bucket  []byte
+	nextKey []byte
+	inRange func(key []byte) bool
+	done    bool
+}
+
+// newRangeScanner returns a scanner for the keys in the [fromKey, toKey)
+// range.
+func newRangeScanner(db *bbolt.DB, bucket, fromKey, toKey []byte) *rangeScanner {
+	toKey = append([]byte(nil), toKey...)
+	return &rangeScanner{
+		db:      db,
+		bucket:  bucket,
+		nextKey: append([]byte(nil), fromKey...),
+		inRange: func(key []byte) bool { return bytes.Compare(key, toKey) < 0 },
+	}
+}
+
+// newPrefixScanner returns a scanner for the keys that start with prefix.
+func newPrefixScanner(db *bbolt.DB, bucket, prefix []byte) *rangeScanner {
+	prefix = append([]byte(nil), prefix...)
+	return &rangeScanner{
+		db:      db,
+		bucket:  bucket,
+		nextKey: append([]byte(nil), prefix...),
+		inRange: func(key []byte) bool { return bytes.HasPrefix(key, prefix) },
+	}
+}
+
+// nextBatch returns the next batch of records or an empty batch once the
+// range has been exhausted.
+func (s *rangeScanner) nextBatch() ([]kvPair, error) {
+	if s.done {
+		return nil, nil
+	}
+	var batch []kvPair
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+		k, v := c.Seek(s.nextKey)
+		for ; k != nil && s.inRange(k) && len(batch) < scanBatchSize; k, v = c.Next() {
+			batch = append(batch, kvPair{
+				key:   append([]byte(nil), k...),
+				value: append([]byte(nil), v...),
+			})
+		}
+		if k == nil || !s.inRange(k) {
+			s.done = true
+		} else {
+			s.nextKey = append(s.nextKey[:0], k...)
+		}
+		return nil
+	})
+	return batch, err
+}
+
+// linkIterator implements graph.LinkIterator. It can iterate either the
+// links bucket or the URL index. In the latter case, the retrieval
+// timestamp filter is not applied.
+type linkIterator struct {
+	db              *bbolt.DB
+	scanner         *rangeScanner
+	retrievedBefore time.Time
+	byURL           bool
+
+	batch       []kvPair
+	lastErr     error
+	latchedLink *graph.Link
+}
+
+// Next implements graph.LinkIterator.
+func (i *linkIterator) Next() bool {
+	for i.lastErr == nil {
+		if len(i.batch) == 0 {
+			if i.batch, i.lastErr = i.scanner.nextBatch(); i.lastErr == nil && i.byURL {
+				i.lastErr = i.resolveURLBatch()
+			}
+			if i.lastErr != nil || len(i.batch) == 0 {
+				return false
+			}
+		}
+		pair := i.batch[0]
+		i.batch = i.batch[1:]
+
+		link, err := decodeLink(pair.key, pair.value)
+		if err != nil {
+			i.lastErr = err
+			return false
+		}
+		if i.byURL || link.RetrievedAt.Before(i.retrievedBefore) {
+			i.latchedLink = link
+			return true
+		}
+	}
+	return false
+}
+
+// resolveURLBatch replaces the URL index entries in the current batch with
+// the link records they point to.
+func (i *linkIterator) resolveURLBatch() error {
+	return i.db.View(func(tx *bbolt.Tx) error {
+		links := tx.Bucket(linksBucket)
+		resolved := i.batch[:0]
+		for _, pair := range i.batch {
+			id := pair.value
+			if data := links.Get(id); data != nil {
+				resolved = append(resolved, kvPair{key: id, value: append([]byte(nil), data...)})
+			}
+		}
+		i.batch = resolved
+		return nil
+	})
+}
+
+// Error implements graph.LinkIterator.
+func (i *linkIterator) Error() error {
+	return i.lastErr
+}
+
+// Close implements graph.LinkIterator.
+func (i *linkIterator) Close() error {
+	return nil
+}
+
+// Link implements graph.LinkIterator.
+func (i *linkIterator) Link() *graph.Link {
+	return i.latchedLink
+}
+
+// edgeIterator implements graph.EdgeIterator. It can iterate either the
+// edges bucket or the edges by destination index.
+type edgeIterator struct {
+	scanner       *rangeScanner
+	updatedBefore time.Time
+	byDst         bool
+
+	batch       []kvPair
+	lastErr     error
+	latchedEdge *graph.Edge
+}
+
+// Next implements graph.EdgeIterator.
+func (i *edgeIterator) Next() bool {
+	for i.lastErr == nil {
+		if len(i.batch) == 0 {
+			if i.batch, i.lastErr = i.scanner.nextBatch(); i.lastErr != nil || len(i.batch) == 0 {
+				return false
+			}
+		}
+		pair := i.batch[0]
+		i.batch = i.batch[1:]
+
+		decode := decodeEdge
+		if i.byDst {
+			decode = decodeEdgeByDst
+		}
+		edge, err := decode(pair.key, pair.value)
+		if err != nil {
+			i.lastErr = err
+			return false
+		}
+		if edge.UpdateAt.Before(i.updatedBefore) {
+			i.latchedEdge = edge
+			return true
+		}
+	}
+	return false
+}
+
+// Error implements graph.EdgeIterator.
+func (i *edgeIterator) Error() error {
+	return i.lastErr
+}
+
+// Close implements graph.EdgeIterator.
+func (i *edgeIterator) Close() error {
+	return nil
+}
+
+// Edge implements graph.EdgeIterator.
+func (i *edgeIterator) Edge() *graph.Edge {
+	return i.latchedEdge
+}
@@ -3,44 +3,227 @@ package cdb
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
 	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
 	"github.com/lib/pq"
 	"golang.org/x/xerrors"
 )
 
+const (
+	// linkColumns lists the links table columns in the order expected by
+	// scanLink.
+	linkColumns = "id, url, retrieved_at, status_code, content_hash, redirect_url, failure_count, last_error"
+
+	// linkInsertColumns lists the columns that are populated when
+	// upserting a link.
+	linkInsertColumns = "url, retrieved_at, status_code, content_hash, redirect_url, failure_count, last_error"
+
+	// linkUpsertConflictClause keeps the most recent retrieval timestamp
+	// and only updates the fetch metadata when it comes from a more recent
+	// fetch than the stored one.
+	linkUpsertConflictClause = `
+	    ON CONFLICT (url) DO UPDATE SET
+	        status_code=CASE WHEN excluded.retrieved_at > links.retrieved_at THEN excluded.status_code ELSE links.status_code END,
+	        content_hash=CASE WHEN excluded.retrieved_at > links.retrieved_at THEN excluded.content_hash ELSE links.content_hash END,
+	        redirect_url=CASE WHEN excluded.retrieved_at > links.retrieved_at THEN excluded.redirect_url ELSE links.redirect_url END,
+	        failure_count=CASE WHEN excluded.retrieved_at > links.retrieved_at THEN excluded.failure_count ELSE links.failure_count END,
+	        last_error=CASE WHEN excluded.retrieved_at > links.retrieved_at THEN excluded.last_error ELSE links.last_error END,
+	        retrieved_at=GREATEST(links.retrieved_at, excluded.retrieved_at)
+	    RETURNING ` + linkColumns
+
+	// linkArgsPerRow is the number of query arguments per upserted link.
+	linkArgsPerRow = 7
+
+	// edgeColumns lists the edges table columns in the order expected by
+	// scanEdge.
+	edgeColumns = "id, src, dst, updated_at, anchor_text, nofollow, link_position"
+
+	// edgeInsertColumns lists the columns that are populated when
+	// upserting an edge.
+	edgeInsertColumns = "src, dst, anchor_text, nofollow, link_position, updated_at"
+
+	// edgeUpsertConflictClause refreshes the timestamp and annotations of
+	// existing edges.
+	edgeUpsertConflictClause = `
+	    ON CONFLICT (src, dst) DO UPDATE SET
+	        anchor_text=excluded.anchor_text,
+	        nofollow=excluded.nofollow,
+	        link_position=excluded.link_position,
+	        updated_at=NOW()
+	    RETURNING ` + edgeColumns
+
+	// edgeArgsPerRow is the number of query arguments per upserted edge.
+	edgeArgsPerRow = 5
+
+	// restoreEdgeArgsPerRow is the number of query arguments per restored
+	// edge, one for each column in edgeColumns.
+	restoreEdgeArgsPerRow = 7
+)
+
 var (
-	upsertLinkQuery = `
-	    INSERT INTO links (url, retrieved_at) VALUES ($1,$2)
-	    ON CONFLICT (url) DO UPDATE SET retrieved_at=GREATEST(links.retrieved_at, $2)
-	    RETURNING id, retrieved_at`
-	findLinkQuery        = "SELECT url, retrieved_at FROM links WHERE id = $1"
-	linkInPartitionQuery = "SELECT id, url, retrieved_at FROM links WHERE id >= $1 AND id < $2 AND retrieved_at < $3"
-
-	upsertEdgeQuery = `
-	    INSERT INTO edges (src, dst, updated_at) VALUES ($1, $2, NOW())
-	    ON CONFLICT (src, dst) DO UPDATE SET updated_at=NOW()
-	    RETURNING id, updated_at`
-	edgesInPartitionQuery = "SELECT id, src, dst, updated_at FROM edges WHERE src >= $1 AND src < $2 AND updated_at < $3"
+	upsertLinkQuery      = "INSERT INTO links (" + linkInsertColumns + ") VALUES ($1,$2,$3,$4,$5,$6,$7)" + linkUpsertConflictClause
+	findLinkQuery        = "SELECT " + linkColumns + " FROM links WHERE id = $1"
+	removeLinkQuery      = "DELETE FROM links WHERE id = $1"
+	removeLinksQuery     = "DELETE FROM links WHERE id = ANY($1::UUID[])"
+	findLinkByURLQuery   = "SELECT " + linkColumns + " FROM links WHERE url = $1"
+	linksInURLRangeQuery = "SELECT " + linkColumns + " FROM links WHERE url >= $1 AND url < $2 ORDER BY url"
+	linkInPartitionQuery = "SELECT " + linkColumns + " FROM links WHERE id >= $1 AND id < $2 AND retrieved_at < $3"
+
+	removeOrphanLinksQuery = "DELETE FROM links WHERE id = ANY($1::UUID[]) AND retrieved_at > $2 AND retrieved_at < $3 AND NOT EXISTS (SELECT 1 FROM edges WHERE edges.dst = links.id) RETURNING id"
+
+	upsertEdgeQuery       = "INSERT INTO edges (" + edgeInsertColumns + ") VALUES ($1, $2, $3, $4, $5, NOW())" + edgeUpsertConflictClause
+	edgesInPartitionQuery = "SELECT " + edgeColumns + " FROM edges WHERE src >= $1 AND src < $2 AND updated_at < $3"
 	removeStaleEdgesQuery = "DELETE FROM edges WHERE src=$1 AND updated_at < $2"
+	edgesToQuery          = "SELECT " + edgeColumns + " FROM edges WHERE dst = $1 AND updated_at < $2"
+	inDegreeQuery         = "SELECT (SELECT count(*) FROM edges WHERE dst = $1) FROM links WHERE id = $1"
+	outDegreeQuery        = "SELECT (SELECT count(*) FROM edges WHERE src = $1) FROM links WHERE id = $1"
+
+	upsertLinksQueryPrefix = "INSERT INTO links (" + linkInsertColumns + ") VALUES "
+
+	upsertEdgesQueryPrefix = "INSERT INTO edges (" + edgeInsertColumns + ") VALUES "
+
+	restoreLinksQueryPrefix = "INSERT INTO links (id, " + linkInsertColumns + ") VALUES "
+	restoreLinksQuerySuffix = `
+	    ON CONFLICT (id) DO UPDATE SET
+	        url=excluded.url,
+	        retrieved_at=excluded.retrieved_at,
+	        status_code=excluded.status_code,
+	        content_hash=excluded.content_hash,
+	        redirect_url=excluded.redirect_url,
+	        failure_count=excluded.failure_count,
+	        last_error=excluded.last_error`
+
+	restoreEdgesQueryPrefix = "INSERT INTO edges (" + edgeColumns + ") VALUES "
+	restoreEdgesQuerySuffix = `
+	    ON CONFLICT (src, dst) DO UPDATE SET
+	        id=excluded.id,
+	        updated_at=excluded.updated_at,
+	        anchor_text=excluded.anchor_text,
+	        nofollow=excluded.nofollow,
+	        link_position=excluded.link_position`
 )
 
+// maxBatchRows is the maximum number of rows that are upserted by a single
+// multi-row INSERT statement.
+const maxBatchRows = 500
+
 // CockroachDBGraph struct definition implemente the graph persistence layer
 type CockroachDBGraph struct {
-	db *sql.DB
+	db  *sql.DB
+	cfg Config
 }
 
-// NewCockroachDBGraph return the cockroach db instance client with the dns specific
-func NewCockroachDBGraph(dns string) (*CockroachDBGraph, error) {
-	db, err := sql.Open("postgres", dns)
+// Config encapsulates the configuration options for creating a
+// CockroachDBGraph.
+type Config struct {
+	// The data source name for connecting to the database.
+	DSN string
+
+	// ApplyMigrations, if set, applies any pending schema migrations
+	// before NewCockroachDBGraph returns.
+	ApplyMigrations bool
+
+	// The maximum number of open connections to the database. If not
+	// specified, the number of open connections is not limited.
+	MaxOpenConns int
+
+	// The maximum number of idle connections that are retained by the
+	// connection pool. If not specified, the database/sql default is used.
+	// A negative value disables the retention of idle connections.
+	MaxIdleConns int
+
+	// The maximum amount of time a connection may be reused. If not
+	// specified, connections are reused forever.
+	ConnMaxLifetime time.Duration
+
+	// StatementTimeout bounds the execution time of each graph operation.
+	// For operations that execute multiple statements within a transaction
+	// the timeout applies to each attempt to run the whole transaction
+	// while for operations that return an iterator it applies to the time
+	// until the iterator is closed. If not specified, operations are only
+	// bounded by the context passed to them.
+	StatementTimeout time.Duration
+
+	// MaxRetries specifies how many times an operation that failed with a
+	// retryable serialization error (SQLSTATE 40001) is retried. If not
+	// specified, a default value of 5 will be used instead. A negative
+	// value disables retries.
+	MaxRetries int
+
+	// The delay before the first retry. The delay is doubled after each
+	// retry until it reaches MaxRetryBackoff. If not specified, a default
+	// value of 50ms will be used instead.
+	MinRetryBackoff time.Duration
+
+	// The maximum delay between retries. If not specified, a default value
+	// of 2s will be used instead.
+	MaxRetryBackoff time.Duration
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if cfg.DSN == "" {
+		err = multierror.Append(err, xerrors.New("data source name not specified"))
+	}
+	if cfg.ConnMaxLifetime < 0 {
+		err = multierror.Append(err, xerrors.New("connection max lifetime must not be negative"))
+	}
+	if cfg.StatementTimeout < 0 {
+		err = multierror.Append(err, xerrors.New("statement timeout must not be negative"))
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.MinRetryBackoff <= 0 {
+		cfg.MinRetryBackoff = 50 * time.Millisecond
+	}
+	if cfg.MaxRetryBackoff <= 0 {
+		cfg.MaxRetryBackoff = 2 * time.Second
+	}
+	if cfg.MaxRetryBackoff < cfg.MinRetryBackoff {
+		err = multierror.Append(err, xerrors.New("max retry backoff must not be smaller than min retry backoff"))
+	}
+	return err
+}
+
+// NewCockroachDBGraph return the cockroach db instance client using the
+// provided config. It verifies that the database is reachable before
+// returning.
+func NewCockroachDBGraph(cfg Config) (*CockroachDBGraph, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("cockroachdb graph config validation failed: %w", err)
+	}
+	db, err := sql.Open("postgres", cfg.DSN)
 	if err != nil {
 		return nil, err
 	}
-	return &CockroachDBGraph{
-		db: db,
-	}, nil
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	s := &CockroachDBGraph{
+		db:  db,
+		cfg: cfg,
+	}
+	if err = s.exec(context.Background(), db.PingContext); err != nil {
+		_ = db.Close()
+		return nil, xerrors.Errorf("ping database: %w", err)
+	}
+	if cfg.ApplyMigrations {
+		if err = NewMigrator(db).Up(context.Background()); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return s, nil
 }
 
 // Close just close the cockroach db instance
@@ -50,74 +233,541 @@ func (s *CockroachDBGraph) Close() error {
 
 // UpsertLink create a new link or update an existing one base on the graph.Graph interface using cockroach db
 func (s *CockroachDBGraph) UpsertLink(ctx context.Context, link *graph.Link) error {
-	if err := s.db.QueryRowContext(ctx, upsertLinkQuery, link.URL, link.RetrievedAt).Scan(&link.ID, &link.RetrievedAt); err != nil {
+	return s.exec(ctx, func(ctx context.Context) error {
+		return upsertLink(ctx, s.db, link)
+	})
+}
+
+func upsertLink(ctx context.Context, q queryer, link *graph.Link) error {
+	if err := scanLink(q.QueryRowContext(ctx, upsertLinkQuery, linkArgs(link)...), link); err != nil {
 		return xerrors.Errorf("upsert link: %w ", err)
 	}
+	return nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanLink populates link from a row whose columns match linkColumns.
+func scanLink(row rowScanner, link *graph.Link) error {
+	err := row.Scan(
+		&link.ID, &link.URL, &link.RetrievedAt, &link.StatusCode, &link.ContentHash,
+		&link.RedirectURL, &link.FailureCount, &link.LastError,
+	)
+	if err != nil {
+		return err
+	}
 	link.RetrievedAt = link.RetrievedAt.UTC()
 	return nil
 }
 
+// linkArgs returns the query arguments for upserting link in the order
+// specified by linkInsertColumns.
+func linkArgs(link *graph.Link) []interface{} {
+	return []interface{}{
+		link.URL, link.RetrievedAt.UTC(), link.StatusCode, link.ContentHash,
+		link.RedirectURL, link.FailureCount, link.LastError,
+	}
+}
+
+// UpsertLinks creates or updates a batch of links using multi-row INSERT
+// statements that are executed within a single transaction.
+func (s *CockroachDBGraph) UpsertLinks(ctx context.Context, links []*graph.Link) error {
+	return s.InTx(ctx, func(tx *Tx) error {
+		return tx.UpsertLinks(ctx, links)
+	})
+}
+
+func upsertLinks(ctx context.Context, tx *sql.Tx, links []*graph.Link) error {
+	// A multi-row upsert cannot affect the same row twice so links that
+	// share a URL are collapsed into the one with the most recent
+	// retrieval timestamp.
+	var (
+		urls       []string
+		latest     = make(map[string]*graph.Link)
+		linksByURL = make(map[string][]*graph.Link)
+	)
+	for _, link := range links {
+		cur, exists := latest[link.URL]
+		if !exists {
+			urls = append(urls, link.URL)
+		}
+		if !exists || link.RetrievedAt.After(cur.RetrievedAt) {
+			latest[link.URL] = link
+		}
+		linksByURL[link.URL] = append(linksByURL[link.URL], link)
+	}
+
+	for start := 0; start < len(urls); start += maxBatchRows {
+		batch := urls[start:minInt(start+maxBatchRows, len(urls))]
+		args := make([]interface{}, 0, linkArgsPerRow*len(batch))
+		for _, url := range batch {
+			args = append(args, linkArgs(latest[url])...)
+		}
+		query := upsertLinksQueryPrefix + valuePlaceholders(len(batch), linkArgsPerRow, "") + linkUpsertConflictClause
+		if err := scanUpsertedLinks(ctx, tx, query, args, linksByURL); err != nil {
+			return xerrors.Errorf("upsert links: %w", err)
+		}
+	}
+	return nil
+}
+
+// scanUpsertedLinks executes a multi-row link upsert query and copies the
+// returned link records to the links with the matching URL.
+func scanUpsertedLinks(ctx context.Context, tx *sql.Tx, query string, args []interface{}, linksByURL map[string][]*graph.Link) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		stored := new(graph.Link)
+		if err = scanLink(rows, stored); err != nil {
+			return err
+		}
+		for _, link := range linksByURL[stored.URL] {
+			*link = *stored
+		}
+	}
+	return rows.Err()
+}
+
 // FindLink look up a link base on the ID. and return the struct of the Link.
 func (s *CockroachDBGraph) FindLink(ctx context.Context, id uuid.UUID) (*graph.Link, error) {
-	link := &graph.Link{
-		ID: id,
-	}
-	if err := s.db.QueryRowContext(ctx, findLinkQuery, id).Scan(&link.URL, &link.RetrievedAt); err != nil {
+	link := new(graph.Link)
+	err := s.exec(ctx, func(ctx context.Context) error {
+		return scanLink(s.db.QueryRowContext(ctx, findLinkQuery, id), link)
+	})
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, xerrors.Errorf("find link : %w ", graph.ErrNotFound)
 		}
 		return nil, xerrors.Errorf("find link: %w ", err)
 	}
-	link.RetrievedAt = link.RetrievedAt.UTC()
 	return link, nil
 }
 
+// RemoveLink removes a link. Its incoming and outgoing edges are removed by
+// the ON DELETE CASCADE constraints of the edges table.
+func (s *CockroachDBGraph) RemoveLink(ctx context.Context, id uuid.UUID) error {
+	var res sql.Result
+	err := s.exec(ctx, func(ctx context.Context) (err error) {
+		res, err = s.db.ExecContext(ctx, removeLinkQuery, id)
+		return err
+	})
+	if err != nil {
+		return xerrors.Errorf("remove link: %w", err)
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return xerrors.Errorf("remove link: %w", err)
+	} else if count == 0 {
+		return xerrors.Errorf("remove link: %w", graph.ErrNotFound)
+	}
+	return nil
+}
+
+// RemoveLinks removes a batch of links using a single DELETE statement.
+func (s *CockroachDBGraph) RemoveLinks(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	idList := make([]string, len(ids))
+	for i, id := range ids {
+		idList[i] = id.String()
+	}
+	err := s.exec(ctx, func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, removeLinksQuery, pq.Array(idList))
+		return err
+	})
+	if err != nil {
+		return xerrors.Errorf("remove links: %w", err)
+	}
+	return nil
+}
+
+// RemoveOrphanLinks removes the links in ids that have no incoming edges and
+// were retrieved before the provided timestamp using a single DELETE
+// statement. Links that were never retrieved carry the zero timestamp.
+func (s *CockroachDBGraph) RemoveOrphanLinks(ctx context.Context, ids []uuid.UUID, retrievedBefore time.Time) ([]uuid.UUID, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	idList := make([]string, len(ids))
+	for i, id := range ids {
+		idList[i] = id.String()
+	}
+	var removed []uuid.UUID
+	err := s.exec(ctx, func(ctx context.Context) error {
+		removed = removed[:0]
+		rows, err := s.db.QueryContext(ctx, removeOrphanLinksQuery, pq.Array(idList), time.Time{}, retrievedBefore.UTC())
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var id uuid.UUID
+			if err = rows.Scan(&id); err != nil {
+				return err
+			}
+			removed = append(removed, id)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("remove orphan links: %w", err)
+	}
+	return removed, nil
+}
+
+// FindLinkByURL looks up a link by its URL.
+func (s *CockroachDBGraph) FindLinkByURL(ctx context.Context, url string) (*graph.Link, error) {
+	link := new(graph.Link)
+	err := s.exec(ctx, func(ctx context.Context) error {
+		return scanLink(s.db.QueryRowContext(ctx, findLinkByURLQuery, url), link)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = graph.ErrNotFound
+		}
+		return nil, xerrors.Errorf("find link by url: %w", err)
+	}
+	return link, nil
+}
+
+// LinksWithURLPrefix returns an iterator for the set of links whose URL
+// starts with prefix, ordered by URL. The lookup is translated into a range
+// scan over the unique URL index whose upper bound is the prefix followed by
+// the largest valid code point.
+func (s *CockroachDBGraph) LinksWithURLPrefix(ctx context.Context, prefix string) (graph.LinkIterator, error) {
+	rows, cancel, err := s.query(ctx, linksInURLRangeQuery, prefix, prefix+string(utf8.MaxRune))
+	if err != nil {
+		return nil, xerrors.Errorf("links with url prefix: %w", err)
+	}
+	return &linkIterator{
+		rows:   rows,
+		cancel: cancel,
+	}, nil
+}
+
 // Links return all the link base on an Iterator, who belong to a particular link
 // [fromID, toID] range can be retrieved
 func (s *CockroachDBGraph) Links(ctx context.Context, fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error) {
-	rows, err := s.db.QueryContext(ctx, linkInPartitionQuery, fromID, toID, retrievedBefore.UTC())
+	rows, cancel, err := s.query(ctx, linkInPartitionQuery, fromID, toID, retrievedBefore.UTC())
 	if err != nil {
 		return nil, xerrors.Errorf("Error Links: %w ", err)
 	}
 	return &linkIterator{
-		rows: rows,
+		rows:   rows,
+		cancel: cancel,
 	}, nil
 }
 
 // UpsertEdge create a new edge or update an existing one.
 func (s *CockroachDBGraph) UpsertEdge(ctx context.Context, edge *graph.Edge) error {
-	if err := s.db.QueryRowContext(ctx, upsertEdgeQuery, edge.Src, edge.Dst).Scan(&edge.ID, &edge.UpdateAt); err != nil {
+	return s.exec(ctx, func(ctx context.Context) error {
+		return upsertEdge(ctx, s.db, edge)
+	})
+}
+
+func upsertEdge(ctx context.Context, q queryer, edge *graph.Edge) error {
+	if err := scanEdge(q.QueryRowContext(ctx, upsertEdgeQuery, edgeArgs(edge)...), edge); err != nil {
 		if isForeignKeyViolation(err) {
 			err = graph.ErrUnknownEdgeLinks
 		}
 		return xerrors.Errorf("Upsert Error: %w ", err)
 	}
+	return nil
+}
+
+// scanEdge populates edge from a row whose columns match edgeColumns.
+func scanEdge(row rowScanner, edge *graph.Edge) error {
+	err := row.Scan(
+		&edge.ID, &edge.Src, &edge.Dst, &edge.UpdateAt, &edge.AnchorText,
+		&edge.NoFollow, &edge.Position,
+	)
+	if err != nil {
+		return err
+	}
 	edge.UpdateAt = edge.UpdateAt.UTC()
 	return nil
 }
 
+// edgeArgs returns the query arguments for upserting edge in the order
+// specified by edgeInsertColumns, excluding the update timestamp which is
+// always set by the database.
+func edgeArgs(edge *graph.Edge) []interface{} {
+	return []interface{}{edge.Src, edge.Dst, edge.AnchorText, edge.NoFollow, edge.Position}
+}
+
+// UpsertEdges creates or updates a batch of edges using multi-row INSERT
+// statements that are executed within a single transaction. If any edge
+// refers to an unknown link, no edge is upserted.
+func (s *CockroachDBGraph) UpsertEdges(ctx context.Context, edges []*graph.Edge) error {
+	return s.InTx(ctx, func(tx *Tx) error {
+		return tx.UpsertEdges(ctx, edges)
+	})
+}
+
+func upsertEdges(ctx context.Context, tx *sql.Tx, edges []*graph.Edge) error {
+	// Collapse duplicate edges as a multi-row upsert cannot affect the
+	// same row twice. The annotations of the last duplicate win.
+	var (
+		keys       []edgeKey
+		latest     = make(map[edgeKey]*graph.Edge)
+		edgesByKey = make(map[edgeKey][]*graph.Edge)
+	)
+	for _, edge := range edges {
+		key := edgeKey{src: edge.Src, dst: edge.Dst}
+		if _, exists := edgesByKey[key]; !exists {
+			keys = append(keys, key)
+		}
+		latest[key] = edge
+		edgesByKey[key] = append(edgesByKey[key], edge)
+	}
+
+	for start := 0; start < len(keys); start += maxBatchRows {
+		batch := keys[start:minInt(start+maxBatchRows, len(keys))]
+		args := make([]interface{}, 0, edgeArgsPerRow*len(batch))
+		for _, key := range batch {
+			args = append(args, edgeArgs(latest[key])...)
+		}
+		query := upsertEdgesQueryPrefix + valuePlaceholders(len(batch), edgeArgsPerRow, "NOW()") + edgeUpsertConflictClause
+		if err := scanUpsertedEdges(ctx, tx, query, args, edgesByKey); err != nil {
+			if isForeignKeyViolation(err) {
+				err = graph.ErrUnknownEdgeLinks
+			}
+			return xerrors.Errorf("upsert edges: %w", err)
+		}
+	}
+	return nil
+}
+
+// edgeKey uniquely identifies an edge by its endpoints.
+type edgeKey struct {
+	src, dst uuid.UUID
+}
+
+// scanUpsertedEdges executes a multi-row edge upsert query and copies the
+// returned edge records to the edges with the matching endpoints.
+func scanUpsertedEdges(ctx context.Context, tx *sql.Tx, query string, args []interface{}, edgesByKey map[edgeKey][]*graph.Edge) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		stored := new(graph.Edge)
+		if err = scanEdge(rows, stored); err != nil {
+			return err
+		}
+		for _, edge := range edgesByKey[edgeKey{src: stored.Src, dst: stored.Dst}] {
+			*edge = *stored
+		}
+	}
+	return rows.Err()
+}
+
 // Edges return all the edges that are belong to a particular eedge.
 // the source is on a vertex id [fromID, toID]
 // range is update before provide timestamp
 func (s *CockroachDBGraph) Edges(ctx context.Context, fromID, toID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error) {
-	rows, err := s.db.QueryContext(ctx, edgesInPartitionQuery, fromID, toID, updatedBefore.UTC())
+	rows, cancel, err := s.query(ctx, edgesInPartitionQuery, fromID, toID, updatedBefore.UTC())
 	if err != nil {
 		return nil, xerrors.Errorf("Error edges: %w ", err)
 	}
 	return &edgeIterator{
-		rows: rows,
+		rows:   rows,
+		cancel: cancel,
+	}, nil
+}
+
+// EdgesTo returns an iterator for the set of edges that point to dstID and
+// were updated before the provided timestamp.
+func (s *CockroachDBGraph) EdgesTo(ctx context.Context, dstID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error) {
+	rows, cancel, err := s.query(ctx, edgesToQuery, dstID, updatedBefore.UTC())
+	if err != nil {
+		return nil, xerrors.Errorf("edges to: %w", err)
+	}
+	return &edgeIterator{
+		rows:   rows,
+		cancel: cancel,
 	}, nil
 }
 
+// InDegree returns the number of edges that point to the specified link.
+func (s *CockroachDBGraph) InDegree(ctx context.Context, linkID uuid.UUID) (int, error) {
+	return s.degree(ctx, inDegreeQuery, linkID, "in degree")
+}
+
+// OutDegree returns the number of edges that originate from the specified
+// link.
+func (s *CockroachDBGraph) OutDegree(ctx context.Context, linkID uuid.UUID) (int, error) {
+	return s.degree(ctx, outDegreeQuery, linkID, "out degree")
+}
+
+func (s *CockroachDBGraph) degree(ctx context.Context, query string, linkID uuid.UUID, op string) (int, error) {
+	var degree int
+	err := s.exec(ctx, func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, query, linkID).Scan(&degree)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = graph.ErrNotFound
+		}
+		return 0, xerrors.Errorf("%s: %w", op, err)
+	}
+	return degree, nil
+}
+
 // RemoveStalEdges remove any edges from the origin specification
 func (s *CockroachDBGraph) RemoveStalEdges(ctx context.Context, fromID uuid.UUID, updatedBefore time.Time) error {
-	_, err := s.db.Exec(removeStaleEdgesQuery, fromID, updatedBefore.UTC())
-	if err != nil {
+	return s.exec(ctx, func(ctx context.Context) error {
+		return removeStaleEdges(ctx, s.db, fromID, updatedBefore)
+	})
+}
+
+func removeStaleEdges(ctx context.Context, q queryer, fromID uuid.UUID, updatedBefore time.Time) error {
+	if _, err := q.ExecContext(ctx, removeStaleEdgesQuery, fromID, updatedBefore.UTC()); err != nil {
 		return xerrors.Errorf("remove stale edges: %w ", err)
 	}
 	return nil
 }
 
+// RestoreLinks inserts or replaces a batch of links keeping their IDs,
+// timestamps and fetch metadata. All links are restored within a single
+// transaction; if any URL already belongs to a link with a different ID, the
+// unique URL constraint aborts the transaction.
+func (s *CockroachDBGraph) RestoreLinks(ctx context.Context, links []*graph.Link) error {
+	// A multi-row upsert cannot affect the same row twice so only the last
+	// link for each ID is restored.
+	var (
+		ids    []uuid.UUID
+		latest = make(map[uuid.UUID]*graph.Link)
+	)
+	for _, link := range links {
+		if _, exists := latest[link.ID]; !exists {
+			ids = append(ids, link.ID)
+		}
+		latest[link.ID] = link
+	}
+
+	err := s.InTx(ctx, func(tx *Tx) error {
+		for start := 0; start < len(ids); start += maxBatchRows {
+			batch := ids[start:minInt(start+maxBatchRows, len(ids))]
+			args := make([]interface{}, 0, (linkArgsPerRow+1)*len(batch))
+			for _, id := range batch {
+				args = append(args, id)
+				args = append(args, linkArgs(latest[id])...)
+			}
+			query := restoreLinksQueryPrefix + valuePlaceholders(len(batch), linkArgsPerRow+1, "") + restoreLinksQuerySuffix
+			if _, err := tx.tx.ExecContext(ctx, query, args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			err = graph.ErrConflict
+		}
+		return xerrors.Errorf("restore links: %w", err)
+	}
+	return nil
+}
+
+// RestoreEdges inserts or replaces a batch of edges keeping their IDs,
+// timestamps and annotations. All edges are restored within a single
+// transaction.
+func (s *CockroachDBGraph) RestoreEdges(ctx context.Context, edges []*graph.Edge) error {
+	// A multi-row upsert cannot affect the same row twice so only the last
+	// edge for each pair of links is restored.
+	var (
+		keys   []edgeKey
+		latest = make(map[edgeKey]*graph.Edge)
+	)
+	for _, edge := range edges {
+		key := edgeKey{src: edge.Src, dst: edge.Dst}
+		if _, exists := latest[key]; !exists {
+			keys = append(keys, key)
+		}
+		latest[key] = edge
+	}
+
+	err := s.InTx(ctx, func(tx *Tx) error {
+		for start := 0; start < len(keys); start += maxBatchRows {
+			batch := keys[start:minInt(start+maxBatchRows, len(keys))]
+			args := make([]interface{}, 0, restoreEdgeArgsPerRow*len(batch))
+			for _, key := range batch {
+				edge := latest[key]
+				args = append(args, edge.ID, edge.Src, edge.Dst, edge.UpdateAt.UTC(), edge.AnchorText, edge.NoFollow, edge.Position)
+			}
+			query := restoreEdgesQueryPrefix + valuePlaceholders(len(batch), restoreEdgeArgsPerRow, "") + restoreEdgesQuerySuffix
+			if _, err := tx.tx.ExecContext(ctx, query, args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			err = graph.ErrUnknownEdgeLinks
+		} else if isUniqueViolation(err) {
+			err = graph.ErrConflict
+		}
+		return xerrors.Errorf("restore edges: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn inside a transaction which is committed if fn succeeds and
+// rolled back otherwise.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// valuePlaceholders returns the VALUES clause for a multi-row INSERT with
+// numRows rows of argsPerRow positional arguments each. If extra is not
+// empty, it is appended as an additional column expression to every row.
+func valuePlaceholders(numRows, argsPerRow int, extra string) string {
+	var b strings.Builder
+	for row := 0; row < numRows; row++ {
+		if row != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('(')
+		for arg := 0; arg < argsPerRow; arg++ {
+			if arg != 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "$%d", row*argsPerRow+arg+1)
+		}
+		if extra != "" {
+			b.WriteByte(',')
+			b.WriteString(extra)
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// minInt returns the smaller of a and b.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 // isForeignKeyViolatione returns true if there is a foreign key violation constrain violation
 func isForeignKeyViolation(err error) bool {
 	pqErr, valid := err.(*pq.Error)
@@ -126,3 +776,13 @@ func isForeignKeyViolation(err error) bool {
 	}
 	return pqErr.Code.Name() == "foreign_key_violation"
 }
+
+// isUniqueViolation returns true if err was caused by a unique constraint
+// violation.
+func isUniqueViolation(err error) bool {
+	pqErr, valid := err.(*pq.Error)
+	if !valid {
+		return false
+	}
+	return pqErr.Code.Name() == "unique_violation"
+}
@@ -25,10 +25,9 @@ func Test(t *testing.T) {
 func (s *CockroachDBGraphTestSuite) SetUpSuite(c *gc.C) {
 	dsn := os.Getenv("CDB_DSN")
 	if dsn == "" {
-		//c.Skip("Missing CDB_DSN envvar; skipping cockroachdb-backed graph test suite")
-		fmt.Println("cannot find CDB_DSN")
+		c.Skip("Missing CDB_DSN envvar; skipping cockroachdb-backed graph test suite")
 	}
-	g, err := NewCockroachDBGraph(dsn)
+	g, err := NewCockroachDBGraph(Config{DSN: dsn, ApplyMigrations: true})
 	c.Assert(err, gc.IsNil)
 	s.SetGraph(g)
 	s.db = g.db
@@ -1,6 +1,7 @@
 package cdb
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
@@ -9,26 +10,31 @@ import (
 // linkIterator implements the graph.LinkIterator interface
 type linkIterator struct {
 	rows        *sql.Rows
+	cancel      context.CancelFunc
 	lastErr     error
 	latchedLink *graph.Link
 }
 
 // Next implements Next() from graph.LinkIterator
 func (i *linkIterator) Next() bool {
-	if i.lastErr != nil || !i.rows.Next() {
+	if i.lastErr != nil {
+		return false
+	}
+	if !i.rows.Next() {
+		i.lastErr = i.rows.Err()
 		return false
 	}
 	link := new(graph.Link)
-	if i.lastErr = i.rows.Scan(&link.ID, &link.URL, &link.RetrievedAt); i.lastErr != nil {
+	if i.lastErr = scanLink(i.rows, link); i.lastErr != nil {
 		return false
 	}
-	link.RetrievedAt = link.RetrievedAt.UTC()
 	i.latchedLink = link
 	return true
 }
 
 // Close implements Close from graph.LinkIterator
 func (i *linkIterator) Close() error {
+	defer i.cancel()
 	if err := i.rows.Close(); err != nil {
 		return err
 	}
@@ -48,20 +54,24 @@ func (i *linkIterator) Link() *graph.Link {
 // edgeIterator implementes the graph.EdgeIterator from interface
 type edgeIterator struct {
 	rows        *sql.Rows
+	cancel      context.CancelFunc
 	lastErr     error
 	latchedEdge *graph.Edge
 }
 
 // Next implements Next() from graph.EdgeIterator
 func (e *edgeIterator) Next() bool {
-	if e.lastErr != nil || !e.rows.Next() {
+	if e.lastErr != nil {
+		return false
+	}
+	if !e.rows.Next() {
+		e.lastErr = e.rows.Err()
 		return false
 	}
 	edge := new(graph.Edge)
-	if e.lastErr = e.rows.Scan(&edge.ID, &edge.Src, &edge.Dst, &edge.UpdateAt); e.lastErr != nil {
+	if e.lastErr = scanEdge(e.rows, edge); e.lastErr != nil {
 		return false
 	}
-	edge.UpdateAt = edge.UpdateAt.UTC()
 	e.latchedEdge = edge
 	return true
 
@@ -69,6 +79,7 @@ func (e *edgeIterator) Next() bool {
 
 // Close implementes close from graph.EdgeIterator
 func (e *edgeIterator) Close() error {
+	defer e.cancel()
 	if err := e.rows.Close(); err != nil {
 		return err
 	}
@@ -0,0 +1,134 @@
+package cdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(IteratorTestSuite))
+
+func init() {
+	sql.Register("cdb-stub", stubDriver{})
+}
+
+// IteratorTestSuite verifies the link and edge iterators against a stub
+// database driver that streams an endless result set.
+type IteratorTestSuite struct {
+	g *CockroachDBGraph
+}
+
+func (s *IteratorTestSuite) SetUpTest(c *gc.C) {
+	db, err := sql.Open("cdb-stub", "")
+	c.Assert(err, gc.IsNil)
+	cfg := Config{DSN: "postgresql://localhost"}
+	c.Assert(cfg.validate(), gc.IsNil)
+	s.g = &CockroachDBGraph{db: db, cfg: cfg}
+}
+
+func (s *IteratorTestSuite) TearDownTest(c *gc.C) {
+	c.Assert(s.g.db.Close(), gc.IsNil)
+}
+
+func (s *IteratorTestSuite) TestLinkIteratorContextCancelledMidScan(c *gc.C) {
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	it, err := s.g.Links(ctx, uuid.Nil, uuid.Nil, time.Now())
+	c.Assert(err, gc.IsNil)
+	s.assertCancelledMidScan(c, it, cancelFn)
+}
+
+func (s *IteratorTestSuite) TestEdgeIteratorContextCancelledMidScan(c *gc.C) {
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	it, err := s.g.Edges(ctx, uuid.Nil, uuid.Nil, time.Now())
+	c.Assert(err, gc.IsNil)
+	s.assertCancelledMidScan(c, it, cancelFn)
+}
+
+func (s *IteratorTestSuite) assertCancelledMidScan(c *gc.C, it graph.Iterator, cancelFn context.CancelFunc) {
+	c.Assert(it.Next(), gc.Equals, true)
+	c.Assert(it.Error(), gc.IsNil)
+
+	// The result set is endless so the iterator can only stop once the
+	// cancellation has been picked up by the query.
+	cancelFn()
+	for it.Next() {
+	}
+	c.Assert(xerrors.Is(it.Error(), context.Canceled), gc.Equals, true, gc.Commentf("got error: %v", it.Error()))
+	_ = it.Close()
+}
+
+// stubDriver is a database/sql driver whose queries return an endless stream
+// of rows that can be scanned as links or edges, depending on the queried
+// table. The stream ends with the query context error once the context is
+// cancelled.
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+type stubConn struct{}
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return stubStmt{}, nil }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return nil, xerrors.New("not supported") }
+
+func (stubConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	return &stubRows{ctx: ctx, edges: strings.Contains(query, "FROM edges")}, nil
+}
+
+type stubStmt struct{}
+
+func (stubStmt) Close() error  { return nil }
+func (stubStmt) NumInput() int { return -1 }
+
+func (stubStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, xerrors.New("not supported")
+}
+
+func (stubStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, xerrors.New("not supported")
+}
+
+type stubRows struct {
+	ctx    context.Context
+	edges  bool
+	closed bool
+}
+
+func (r *stubRows) Columns() []string {
+	if r.edges {
+		return strings.Split(edgeColumns, ", ")
+	}
+	return strings.Split(linkColumns, ", ")
+}
+
+func (r *stubRows) Close() error {
+	r.closed = true
+	return nil
+}
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.closed {
+		return io.EOF
+	} else if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	now := time.Now()
+	if r.edges {
+		copy(dest, []driver.Value{uuid.New().String(), uuid.New().String(), uuid.New().String(), now, "anchor", false, int64(0)})
+		return nil
+	}
+	copy(dest, []driver.Value{uuid.New().String(), "https://example.com", now, int64(200), "", "", int64(0), ""})
+	return nil
+}
@@ -0,0 +1,253 @@
+package cdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// ErrUnknownSchemaVersion is returned when attempting to migrate to a schema
+// version that does not exist.
+var ErrUnknownSchemaVersion = xerrors.New("unknown schema version")
+
+// Migration describes a versioned change to the link graph schema.
+type Migration struct {
+	// Version uniquely identifies the migration. Migrations are applied in
+	// ascending and reverted in descending version order.
+	Version int
+
+	// A short description of the schema change.
+	Name string
+
+	// The statements for applying and reverting the schema change. The
+	// statements must work with both CockroachDB and Postgres.
+	Up   string
+	Down string
+}
+
+// migrations lists the link graph schema migrations in ascending version
+// order. Released migrations must never be modified; schema changes must be
+// introduced by appending a new migration to the list.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_links_and_edges",
+		Up: `
+		CREATE TABLE IF NOT EXISTS links (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			url TEXT UNIQUE,
+			retrieved_at TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS edges (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			src UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+			dst UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+			updated_at TIMESTAMP,
+			CONSTRAINT edge_links UNIQUE(src,dst)
+		);`,
+		Down: `
+		DROP TABLE IF EXISTS edges;
+		DROP TABLE IF EXISTS links;`,
+	},
+	{
+		Version: 2,
+		Name:    "index_edges_by_dst",
+		Up:      `CREATE INDEX IF NOT EXISTS edges_dst_idx ON edges (dst);`,
+		Down:    `DROP INDEX IF EXISTS edges_dst_idx;`,
+	},
+	{
+		Version: 3,
+		Name:    "link_fetch_metadata",
+		Up: `
+		ALTER TABLE links ADD COLUMN IF NOT EXISTS status_code INT NOT NULL DEFAULT 0;
+		ALTER TABLE links ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '';
+		ALTER TABLE links ADD COLUMN IF NOT EXISTS redirect_url TEXT NOT NULL DEFAULT '';
+		ALTER TABLE links ADD COLUMN IF NOT EXISTS failure_count INT NOT NULL DEFAULT 0;
+		ALTER TABLE links ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';`,
+		Down: `
+		ALTER TABLE links DROP COLUMN IF EXISTS last_error;
+		ALTER TABLE links DROP COLUMN IF EXISTS failure_count;
+		ALTER TABLE links DROP COLUMN IF EXISTS redirect_url;
+		ALTER TABLE links DROP COLUMN IF EXISTS content_hash;
+		ALTER TABLE links DROP COLUMN IF EXISTS status_code;`,
+	},
+	{
+		Version: 4,
+		Name:    "edge_annotations",
+		Up: `
+		ALTER TABLE edges ADD COLUMN IF NOT EXISTS anchor_text TEXT NOT NULL DEFAULT '';
+		ALTER TABLE edges ADD COLUMN IF NOT EXISTS nofollow BOOL NOT NULL DEFAULT false;
+		ALTER TABLE edges ADD COLUMN IF NOT EXISTS link_position INT NOT NULL DEFAULT 0;`,
+		Down: `
+		ALTER TABLE edges DROP COLUMN IF EXISTS link_position;
+		ALTER TABLE edges DROP COLUMN IF EXISTS nofollow;
+		ALTER TABLE edges DROP COLUMN IF EXISTS anchor_text;`,
+	},
+}
+
+// LatestSchemaVersion returns the version of the most recent migration.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+const (
+	// staleMigrationLockAge specifies how long a migration lock may be
+	// held before it is considered abandoned and can be taken over by
+	// another migrator.
+	staleMigrationLockAge = 10 * time.Minute
+
+	// defaultLockPollInterval specifies how often a migrator checks
+	// whether a lock held by another migrator has been released.
+	defaultLockPollInterval = 500 * time.Millisecond
+)
+
+var (
+	createMigrationTablesQuery = `
+	    CREATE TABLE IF NOT EXISTS schema_migrations (
+	        version INT PRIMARY KEY,
+	        name TEXT NOT NULL,
+	        applied_at TIMESTAMP NOT NULL
+	    );
+	    CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+	        id INT PRIMARY KEY,
+	        owner TEXT NOT NULL,
+	        acquired_at TIMESTAMP NOT NULL
+	    );`
+	schemaVersionQuery   = "SELECT COALESCE(MAX(version), 0) FROM schema_migrations"
+	insertMigrationQuery = "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)"
+	deleteMigrationQuery = "DELETE FROM schema_migrations WHERE version = $1"
+
+	// The lock is a single row that can only be taken over once it
+	// becomes stale.
+	acquireMigrationLockQuery = `
+	    INSERT INTO schema_migrations_lock (id, owner, acquired_at) VALUES (1, $1, $2)
+	    ON CONFLICT (id) DO UPDATE SET owner=excluded.owner, acquired_at=excluded.acquired_at
+	    WHERE schema_migrations_lock.acquired_at < $3
+	    RETURNING owner`
+	releaseMigrationLockQuery = "DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = $1"
+)
+
+// Migrator applies and reverts the link graph schema migrations. The
+// applied migrations are tracked in the schema_migrations table.
+//
+// Migrators serialize their work via an advisory lock that is stored in the
+// schema_migrations_lock table, so it is safe for multiple services to
+// migrate the same database concurrently. The lock is implemented on top of
+// a regular table as CockroachDB does not support Postgres advisory locks.
+type Migrator struct {
+	db           *sql.DB
+	pollInterval time.Duration
+}
+
+// NewMigrator returns a Migrator for the database accessible via db.
+func NewMigrator(db *sql.DB) *Migrator {
+	return &Migrator{
+		db:           db,
+		pollInterval: defaultLockPollInterval,
+	}
+}
+
+// Version returns the currently applied schema version or 0 if no migration
+// has been applied yet.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	if _, err := m.db.ExecContext(ctx, createMigrationTablesQuery); err != nil {
+		return 0, xerrors.Errorf("schema version: %w", err)
+	}
+	var version int
+	if err := m.db.QueryRowContext(ctx, schemaVersionQuery).Scan(&version); err != nil {
+		return 0, xerrors.Errorf("schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.MigrateTo(ctx, LatestSchemaVersion())
+}
+
+// MigrateTo applies or reverts migrations until the schema reaches the
+// specified version. Migrating to version 0 reverts all migrations. Each
+// migration is applied within its own transaction.
+func (m *Migrator) MigrateTo(ctx context.Context, version int) error {
+	if version < 0 || version > LatestSchemaVersion() {
+		return xerrors.Errorf("migrate to version %d: %w", version, ErrUnknownSchemaVersion)
+	}
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return xerrors.Errorf("migrate to version %d: %w", version, err)
+	}
+	if current == version {
+		return nil
+	}
+
+	owner := uuid.New().String()
+	if err = m.acquireLock(ctx, owner); err != nil {
+		return xerrors.Errorf("migrate to version %d: acquire lock: %w", version, err)
+	}
+	defer func() { _, _ = m.db.ExecContext(context.Background(), releaseMigrationLockQuery, owner) }()
+
+	// Another migrator may have changed the schema while we were waiting
+	// for the lock.
+	if current, err = m.Version(ctx); err != nil {
+		return xerrors.Errorf("migrate to version %d: %w", version, err)
+	}
+
+	for _, mig := range migrations {
+		if mig.Version <= current || mig.Version > version {
+			continue
+		}
+		err = inTx(ctx, m.db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, insertMigrationQuery, mig.Version, mig.Name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return xerrors.Errorf("apply migration %d (%s): %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mig := migrations[i]
+		if mig.Version > current || mig.Version <= version {
+			continue
+		}
+		err = inTx(ctx, m.db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, deleteMigrationQuery, mig.Version)
+			return err
+		})
+		if err != nil {
+			return xerrors.Errorf("revert migration %d (%s): %w", mig.Version, mig.Name, err)
+		}
+	}
+	return nil
+}
+
+// acquireLock blocks until the migration lock is acquired on behalf of owner
+// or the context expires.
+func (m *Migrator) acquireLock(ctx context.Context, owner string) error {
+	for {
+		now := time.Now().UTC()
+		var lockOwner string
+		err := m.db.QueryRowContext(ctx, acquireMigrationLockQuery, owner, now, now.Add(-staleMigrationLockAge)).Scan(&lockOwner)
+		if err == nil {
+			return nil
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.pollInterval):
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package cdb
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(MigratorTestSuite))
+
+// MigratorTestSuite verifies the schema migrations and the migration runner.
+type MigratorTestSuite struct {
+	db *sql.DB
+}
+
+func (s *MigratorTestSuite) SetUpSuite(c *gc.C) {
+	dsn := os.Getenv("CDB_DSN")
+	if dsn == "" {
+		return
+	}
+	db, err := sql.Open("postgres", dsn)
+	c.Assert(err, gc.IsNil)
+	s.db = db
+}
+
+func (s *MigratorTestSuite) TearDownSuite(c *gc.C) {
+	if s.db != nil {
+		c.Assert(NewMigrator(s.db).Up(context.Background()), gc.IsNil)
+		c.Assert(s.db.Close(), gc.IsNil)
+	}
+}
+
+func (s *MigratorTestSuite) TestMigrationVersionsAreSequential(c *gc.C) {
+	for i, mig := range migrations {
+		c.Assert(mig.Version, gc.Equals, i+1)
+		c.Assert(mig.Name, gc.Not(gc.Equals), "")
+		c.Assert(mig.Up, gc.Not(gc.Equals), "")
+		c.Assert(mig.Down, gc.Not(gc.Equals), "")
+	}
+}
+
+func (s *MigratorTestSuite) TestMigrateToUnknownVersion(c *gc.C) {
+	err := NewMigrator(nil).MigrateTo(context.Background(), LatestSchemaVersion()+1)
+	c.Assert(xerrors.Is(err, ErrUnknownSchemaVersion), gc.Equals, true)
+}
+
+func (s *MigratorTestSuite) TestUpAndDown(c *gc.C) {
+	s.requireDB(c)
+	ctx := context.Background()
+	m := NewMigrator(s.db)
+
+	c.Assert(m.MigrateTo(ctx, 0), gc.IsNil)
+	version, err := m.Version(ctx)
+	c.Assert(err, gc.IsNil)
+	c.Assert(version, gc.Equals, 0)
+
+	c.Assert(m.MigrateTo(ctx, 2), gc.IsNil)
+	version, err = m.Version(ctx)
+	c.Assert(err, gc.IsNil)
+	c.Assert(version, gc.Equals, 2)
+
+	c.Assert(m.Up(ctx), gc.IsNil)
+	version, err = m.Version(ctx)
+	c.Assert(err, gc.IsNil)
+	c.Assert(version, gc.Equals, LatestSchemaVersion())
+}
+
+func (s *MigratorTestSuite) TestConcurrentUp(c *gc.C) {
+	s.requireDB(c)
+	ctx := context.Background()
+	c.Assert(NewMigrator(s.db).MigrateTo(ctx, 0), gc.IsNil)
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- NewMigrator(s.db).Up(ctx)
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		c.Assert(err, gc.IsNil)
+	}
+
+	var applied int
+	c.Assert(s.db.QueryRow("SELECT count(*) FROM schema_migrations").Scan(&applied), gc.IsNil)
+	c.Assert(applied, gc.Equals, len(migrations))
+}
+
+func (s *MigratorTestSuite) requireDB(c *gc.C) {
+	if s.db == nil {
+		c.Skip("Missing CDB_DSN envvar; skipping migration tests")
+	}
+}
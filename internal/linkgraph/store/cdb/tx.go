@@ -0,0 +1,150 @@
+package cdb
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Tx executes link graph updates within a database transaction. Tx instances
+// are only valid for the duration of the function passed to InTx.
+type Tx struct {
+	tx *sql.Tx
+}
+
+// UpsertLink creates a new link or updates an existing one.
+func (t *Tx) UpsertLink(ctx context.Context, link *graph.Link) error {
+	return upsertLink(ctx, t.tx, link)
+}
+
+// UpsertLinks creates or updates a batch of links.
+func (t *Tx) UpsertLinks(ctx context.Context, links []*graph.Link) error {
+	return upsertLinks(ctx, t.tx, links)
+}
+
+// UpsertEdge creates a new edge or updates an existing one.
+func (t *Tx) UpsertEdge(ctx context.Context, edge *graph.Edge) error {
+	return upsertEdge(ctx, t.tx, edge)
+}
+
+// UpsertEdges creates or updates a batch of edges.
+func (t *Tx) UpsertEdges(ctx context.Context, edges []*graph.Edge) error {
+	return upsertEdges(ctx, t.tx, edges)
+}
+
+// RemoveStalEdges removes any edge that originates from the specified link ID
+// and was updated before the specified timestamp.
+func (t *Tx) RemoveStalEdges(ctx context.Context, fromID uuid.UUID, updatedBefore time.Time) error {
+	return removeStaleEdges(ctx, t.tx, fromID, updatedBefore)
+}
+
+// InTx invokes fn within a database transaction that is committed if fn
+// returns a nil error and rolled back otherwise. This allows callers to
+// atomically apply the link and edge updates for a crawled page:
+//
+//	err := g.InTx(ctx, func(tx *cdb.Tx) error {
+//	    if err := tx.UpsertLinks(ctx, links); err != nil {
+//	        return err
+//	    }
+//	    if err := tx.UpsertEdges(ctx, edges); err != nil {
+//	        return err
+//	    }
+//	    return tx.RemoveStalEdges(ctx, srcID, updatedBefore)
+//	})
+//
+// If the transaction fails with a retryable serialization error, the whole
+// transaction is retried. As a result, fn may be invoked multiple times and
+// must not have any side-effects other than the updates it applies via tx.
+func (s *CockroachDBGraph) InTx(ctx context.Context, fn func(tx *Tx) error) error {
+	return s.exec(ctx, func(ctx context.Context) error {
+		return inTx(ctx, s.db, func(tx *sql.Tx) error {
+			return fn(&Tx{tx: tx})
+		})
+	})
+}
+
+// exec invokes fn with a context that is bounded by the configured statement
+// timeout and retries it while it fails with a retryable error.
+func (s *CockroachDBGraph) exec(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.retry(ctx, func() error {
+		opCtx, cancel := s.withTimeout(ctx)
+		defer cancel()
+		return fn(opCtx)
+	})
+}
+
+// query executes a query that returns rows, retrying it while it fails with
+// a retryable error. The returned cancel function must be invoked once the
+// rows are closed to release the resources associated with the statement
+// timeout.
+func (s *CockroachDBGraph) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, context.CancelFunc, error) {
+	var (
+		rows   *sql.Rows
+		cancel context.CancelFunc
+	)
+	err := s.retry(ctx, func() error {
+		qCtx, qCancel := s.withTimeout(ctx)
+		r, err := s.db.QueryContext(qCtx, query, args...)
+		if err != nil {
+			qCancel()
+			return err
+		}
+		rows, cancel = r, qCancel
+		return nil
+	})
+	return rows, cancel, err
+}
+
+// withTimeout returns a copy of ctx that expires once the configured
+// statement timeout elapses.
+func (s *CockroachDBGraph) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.StatementTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.cfg.StatementTimeout)
+}
+
+// retry invokes fn until it succeeds, fails with an error that cannot be
+// retried, the configured number of retries is exhausted or ctx expires.
+// Retries are delayed using exponential backoff with jitter.
+func (s *CockroachDBGraph) retry(ctx context.Context, fn func() error) error {
+	backoff := s.cfg.MinRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryable(err) || attempt >= s.cfg.MaxRetries {
+			return err
+		}
+
+		// Sleep for a random duration in the [backoff/2, backoff] range.
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		if backoff *= 2; backoff > s.cfg.MaxRetryBackoff {
+			backoff = s.cfg.MaxRetryBackoff
+		}
+	}
+}
+
+// isRetryable returns true if err was caused by a serialization failure
+// (SQLSTATE 40001) which CockroachDB expects clients to handle by retrying
+// the transaction.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	return xerrors.As(err, &pqErr) && pqErr.Code == "40001"
+}
@@ -0,0 +1,83 @@
+package cdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(RetryTestSuite))
+
+// RetryTestSuite verifies the config defaults and the retry logic without
+// requiring a database.
+type RetryTestSuite struct{}
+
+func (s *RetryTestSuite) TestConfigDefaults(c *gc.C) {
+	cfg := Config{DSN: "postgresql://localhost"}
+	c.Assert(cfg.validate(), gc.IsNil)
+	c.Assert(cfg.MaxRetries, gc.Equals, 5)
+	c.Assert(cfg.MinRetryBackoff, gc.Equals, 50*time.Millisecond)
+	c.Assert(cfg.MaxRetryBackoff, gc.Equals, 2*time.Second)
+
+	cfg = Config{StatementTimeout: -time.Second}
+	c.Assert(cfg.validate(), gc.ErrorMatches, "(?s).*data source name not specified.*statement timeout must not be negative.*")
+}
+
+func (s *RetryTestSuite) TestRetryableErrors(c *gc.C) {
+	g := s.graph(3)
+	var attempts int
+	err := g.exec(context.Background(), func(context.Context) error {
+		if attempts++; attempts < 3 {
+			return xerrors.Errorf("upsert links: %w", &pq.Error{Code: "40001"})
+		}
+		return nil
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(attempts, gc.Equals, 3)
+}
+
+func (s *RetryTestSuite) TestRetriesExhausted(c *gc.C) {
+	g := s.graph(2)
+	var attempts int
+	err := g.exec(context.Background(), func(context.Context) error {
+		attempts++
+		return &pq.Error{Code: "40001"}
+	})
+	c.Assert(isRetryable(err), gc.Equals, true)
+	c.Assert(attempts, gc.Equals, 3)
+}
+
+func (s *RetryTestSuite) TestNonRetryableErrors(c *gc.C) {
+	g := s.graph(3)
+	var attempts int
+	err := g.exec(context.Background(), func(context.Context) error {
+		attempts++
+		return &pq.Error{Code: "23503"}
+	})
+	c.Assert(err, gc.NotNil)
+	c.Assert(attempts, gc.Equals, 1)
+}
+
+func (s *RetryTestSuite) TestStatementTimeout(c *gc.C) {
+	g := s.graph(0)
+	g.cfg.StatementTimeout = time.Millisecond
+	err := g.exec(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.Assert(err, gc.Equals, context.DeadlineExceeded)
+}
+
+func (s *RetryTestSuite) graph(maxRetries int) *CockroachDBGraph {
+	cfg := Config{
+		DSN:             "postgresql://localhost",
+		MaxRetries:      maxRetries,
+		MinRetryBackoff: time.Millisecond,
+		MaxRetryBackoff: 2 * time.Millisecond,
+	}
+	_ = cfg.validate()
+	return &CockroachDBGraph{cfg: cfg}
+}
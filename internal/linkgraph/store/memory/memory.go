@@ -3,6 +3,8 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,18 +23,20 @@ type InMemoryGraph struct {
 	links map[uuid.UUID]*graph.Link
 	edges map[uuid.UUID]*graph.Edge
 
-	linkURLIndex map[string]*graph.Link
-	linkEdgeMap  map[uuid.UUID]edgeList
+	linkURLIndex  map[string]*graph.Link
+	linkEdgeMap   map[uuid.UUID]edgeList
+	linkInEdgeMap map[uuid.UUID]edgeList
 }
 
 // NewInMemoryGraph  create a new in-memory link graph
 func NewInMemoryGraph() *InMemoryGraph {
 	fmt.Println("hello from memory")
 	return &InMemoryGraph{
-		links:        make(map[uuid.UUID]*graph.Link),
-		edges:        make(map[uuid.UUID]*graph.Edge),
-		linkURLIndex: make(map[string]*graph.Link),
-		linkEdgeMap:  make(map[uuid.UUID]edgeList),
+		links:         make(map[uuid.UUID]*graph.Link),
+		edges:         make(map[uuid.UUID]*graph.Edge),
+		linkURLIndex:  make(map[string]*graph.Link),
+		linkEdgeMap:   make(map[uuid.UUID]edgeList),
+		linkInEdgeMap: make(map[uuid.UUID]edgeList),
 	}
 }
 
@@ -42,13 +46,32 @@ func NewInMemoryGraph() *InMemoryGraph {
 func (s *InMemoryGraph) UpsertLink(ctx context.Context, link *graph.Link) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.upsertLink(link)
+	return nil
+}
+
+// UpsertLinks creates or updates a batch of links while holding the lock
+// only once.
+func (s *InMemoryGraph) UpsertLinks(ctx context.Context, links []*graph.Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, link := range links {
+		s.upsertLink(link)
+	}
+	return nil
+}
+
+// upsertLink implements the link upsert logic. Callers must hold the lock.
+func (s *InMemoryGraph) upsertLink(link *graph.Link) {
 	if existing := s.linkURLIndex[link.URL]; existing != nil {
+		// Only a more recent fetch may update the link metadata.
 		link.ID = existing.ID
-		origTs := existing.RetrievedAt
-		*existing = *link
-		if origTs.After(existing.RetrievedAt) {
-			existing.RetrievedAt = origTs
+		if link.RetrievedAt.After(existing.RetrievedAt) {
+			*existing = *link
+		} else {
+			*link = *existing
 		}
+		return
 	}
 	for {
 		link.ID = uuid.New()
@@ -60,7 +83,6 @@ func (s *InMemoryGraph) UpsertLink(ctx context.Context, link *graph.Link) error
 	*lCopy = *link
 	s.linkURLIndex[lCopy.URL] = lCopy
 	s.links[lCopy.ID] = lCopy
-	return nil
 }
 
 // FindLink find the links for specific id.
@@ -77,6 +99,102 @@ func (s *InMemoryGraph) FindLink(ctx context.Context, id uuid.UUID) (*graph.Link
 
 }
 
+// RemoveLink removes a link together with its incoming and outgoing edges.
+func (s *InMemoryGraph) RemoveLink(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.links[id] == nil {
+		return xerrors.Errorf("remove link: %w", graph.ErrNotFound)
+	}
+	s.removeLink(id)
+	return nil
+}
+
+// RemoveLinks removes a batch of links and their edges while holding the lock
+// only once.
+func (s *InMemoryGraph) RemoveLinks(ctx context.Context, ids []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if s.links[id] != nil {
+			s.removeLink(id)
+		}
+	}
+	return nil
+}
+
+// RemoveOrphanLinks removes the links in ids that have no incoming edges and
+// were retrieved before the provided timestamp while holding the lock.
+func (s *InMemoryGraph) RemoveOrphanLinks(ctx context.Context, ids []uuid.UUID, retrievedBefore time.Time) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Select all orphans before removing any of them so that links orphaned
+	// by the removal are left for the next call.
+	var removed []uuid.UUID
+	for _, id := range ids {
+		link := s.links[id]
+		if link == nil || link.RetrievedAt.IsZero() || !link.RetrievedAt.Before(retrievedBefore) || len(s.linkInEdgeMap[id]) != 0 {
+			continue
+		}
+		removed = append(removed, id)
+	}
+	for _, id := range removed {
+		s.removeLink(id)
+	}
+	return removed, nil
+}
+
+// removeLink purges a link, its index entries and its edges. Self-loops are
+// removed from the reverse adjacency list when the outgoing edges are
+// processed. Callers must hold the lock.
+func (s *InMemoryGraph) removeLink(id uuid.UUID) {
+	for _, edgeID := range s.linkEdgeMap[id] {
+		edge := s.edges[edgeID]
+		delete(s.edges, edgeID)
+		s.removeInEdge(edge.Dst, edgeID)
+	}
+	for _, edgeID := range s.linkInEdgeMap[id] {
+		edge := s.edges[edgeID]
+		delete(s.edges, edgeID)
+		s.removeOutEdge(edge.Src, edgeID)
+	}
+	delete(s.linkEdgeMap, id)
+	delete(s.linkInEdgeMap, id)
+	delete(s.linkURLIndex, s.links[id].URL)
+	delete(s.links, id)
+}
+
+// FindLinkByURL looks up a link by its URL.
+func (s *InMemoryGraph) FindLinkByURL(ctx context.Context, url string) (*graph.Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	link := s.linkURLIndex[url]
+	if link == nil {
+		return nil, xerrors.Errorf("find link by url: %w", graph.ErrNotFound)
+	}
+	lCopy := new(graph.Link)
+	*lCopy = *link
+	return lCopy, nil
+}
+
+// LinksWithURLPrefix returns an iterator for the set of links whose URL
+// starts with prefix, ordered by URL.
+func (s *InMemoryGraph) LinksWithURLPrefix(ctx context.Context, prefix string) (graph.LinkIterator, error) {
+	s.mu.RLock()
+	var list []*graph.Link
+	for url, link := range s.linkURLIndex {
+		if strings.HasPrefix(url, prefix) {
+			list = append(list, link)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].URL < list[j].URL })
+	return &linkIterator{
+		s:     s,
+		links: list,
+	}, nil
+}
+
 // Links return an iterator for the set of links whose IDs belong to.
 // [fromID, toID] range is retrieved
 func (s *InMemoryGraph) Links(ctx context.Context, fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error) {
@@ -125,17 +243,48 @@ func (s *InMemoryGraph) Edges(ctx context.Context, fromID, toID uuid.UUID, updat
 func (s *InMemoryGraph) UpsertEdge(ctx context.Context, edge *graph.Edge) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	_, srcExists := s.links[edge.Src]
-	_, dstExists := s.links[edge.Dst]
-	if !srcExists || !dstExists {
+	if !s.edgeLinksExist(edge) {
 		return xerrors.Errorf("upsert edge: %w ", graph.ErrUnknownEdgeLinks)
 	}
+	s.upsertEdge(edge)
+	return nil
+}
+
+// UpsertEdges creates or updates a batch of edges while holding the lock only
+// once. If any of the edges refers to an unknown link, no edge is upserted.
+func (s *InMemoryGraph) UpsertEdges(ctx context.Context, edges []*graph.Edge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, edge := range edges {
+		if !s.edgeLinksExist(edge) {
+			return xerrors.Errorf("upsert edges: %w ", graph.ErrUnknownEdgeLinks)
+		}
+	}
+	for _, edge := range edges {
+		s.upsertEdge(edge)
+	}
+	return nil
+}
+
+// edgeLinksExist returns true if both edge endpoints are known links. Callers
+// must hold the lock.
+func (s *InMemoryGraph) edgeLinksExist(edge *graph.Edge) bool {
+	_, srcExists := s.links[edge.Src]
+	_, dstExists := s.links[edge.Dst]
+	return srcExists && dstExists
+}
+
+// upsertEdge implements the edge upsert logic. Callers must hold the lock.
+func (s *InMemoryGraph) upsertEdge(edge *graph.Edge) {
 	for _, edgeID := range s.linkEdgeMap[edge.Src] {
 		existingEdge := s.edges[edgeID]
 		if existingEdge.Src == edge.Src && existingEdge.Dst == edge.Dst {
 			existingEdge.UpdateAt = time.Now()
+			existingEdge.AnchorText = edge.AnchorText
+			existingEdge.NoFollow = edge.NoFollow
+			existingEdge.Position = edge.Position
 			*edge = *existingEdge
-			return nil
+			return
 		}
 
 	}
@@ -150,7 +299,45 @@ func (s *InMemoryGraph) UpsertEdge(ctx context.Context, edge *graph.Edge) error
 	*eCopy = *edge
 	s.edges[eCopy.ID] = eCopy
 	s.linkEdgeMap[edge.Src] = append(s.linkEdgeMap[edge.Src], eCopy.ID)
-	return nil
+	s.linkInEdgeMap[edge.Dst] = append(s.linkInEdgeMap[edge.Dst], eCopy.ID)
+}
+
+// EdgesTo returns an iterator for the set of edges that point to dstID and
+// were updated before the provided timestamp.
+func (s *InMemoryGraph) EdgesTo(ctx context.Context, dstID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error) {
+	s.mu.RLock()
+	var list []*graph.Edge
+	for _, edgeID := range s.linkInEdgeMap[dstID] {
+		if edge := s.edges[edgeID]; edge.UpdateAt.Before(updatedBefore) {
+			list = append(list, edge)
+		}
+	}
+	s.mu.RUnlock()
+	return &edgeIterator{
+		s:     s,
+		edges: list,
+	}, nil
+}
+
+// InDegree returns the number of edges that point to the specified link.
+func (s *InMemoryGraph) InDegree(ctx context.Context, linkID uuid.UUID) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.links[linkID] == nil {
+		return 0, xerrors.Errorf("in degree: %w", graph.ErrNotFound)
+	}
+	return len(s.linkInEdgeMap[linkID]), nil
+}
+
+// OutDegree returns the number of edges that originate from the specified
+// link.
+func (s *InMemoryGraph) OutDegree(ctx context.Context, linkID uuid.UUID) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.links[linkID] == nil {
+		return 0, xerrors.Errorf("out degree: %w", graph.ErrNotFound)
+	}
+	return len(s.linkEdgeMap[linkID]), nil
 }
 
 // RemoveStalEdges remove any edges specific from the link ID
@@ -163,6 +350,7 @@ func (s *InMemoryGraph) RemoveStalEdges(ctx context.Context, fromID uuid.UUID, u
 		edge := s.edges[edgeID]
 		if edge.UpdateAt.Before(updatedBefore) {
 			delete(s.edges, edgeID)
+			s.removeInEdge(edge.Dst, edgeID)
 			continue
 		}
 		newEdgelist = append(newEdgelist, edgeID)
@@ -170,3 +358,97 @@ func (s *InMemoryGraph) RemoveStalEdges(ctx context.Context, fromID uuid.UUID, u
 	s.linkEdgeMap[fromID] = newEdgelist
 	return nil
 }
+
+// removeInEdge removes edgeID from the reverse adjacency list of dstID.
+// Callers must hold the lock.
+func (s *InMemoryGraph) removeInEdge(dstID, edgeID uuid.UUID) {
+	inEdges := s.linkInEdgeMap[dstID]
+	for i, id := range inEdges {
+		if id == edgeID {
+			inEdges = append(inEdges[:i], inEdges[i+1:]...)
+			break
+		}
+	}
+	if len(inEdges) == 0 {
+		delete(s.linkInEdgeMap, dstID)
+		return
+	}
+	s.linkInEdgeMap[dstID] = inEdges
+}
+
+// removeOutEdge removes edgeID from the adjacency list of srcID. Callers must
+// hold the lock.
+func (s *InMemoryGraph) removeOutEdge(srcID, edgeID uuid.UUID) {
+	outEdges := s.linkEdgeMap[srcID]
+	for i, id := range outEdges {
+		if id == edgeID {
+			s.linkEdgeMap[srcID] = append(outEdges[:i], outEdges[i+1:]...)
+			return
+		}
+	}
+}
+
+// RestoreLinks inserts or replaces a batch of links keeping their IDs,
+// timestamps and fetch metadata. If any URL already belongs to a link with a
+// different ID, no link is restored.
+func (s *InMemoryGraph) RestoreLinks(ctx context.Context, links []*graph.Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batchURLs := make(map[string]uuid.UUID, len(links))
+	for _, link := range links {
+		if owner := s.linkURLIndex[link.URL]; owner != nil && owner.ID != link.ID {
+			return xerrors.Errorf("restore links: %w", graph.ErrConflict)
+		}
+		if id, exists := batchURLs[link.URL]; exists && id != link.ID {
+			return xerrors.Errorf("restore links: %w", graph.ErrConflict)
+		}
+		batchURLs[link.URL] = link.ID
+	}
+	for _, link := range links {
+		existing := s.links[link.ID]
+		if existing == nil {
+			existing = new(graph.Link)
+			s.links[link.ID] = existing
+		} else if existing.URL != link.URL {
+			delete(s.linkURLIndex, existing.URL)
+		}
+		*existing = *link
+		s.linkURLIndex[existing.URL] = existing
+	}
+	return nil
+}
+
+// RestoreEdges inserts or replaces a batch of edges keeping their IDs,
+// timestamps and annotations. If any edge refers to an unknown link or uses
+// an ID that belongs to an edge between different links, no edge is
+// restored.
+func (s *InMemoryGraph) RestoreEdges(ctx context.Context, edges []*graph.Edge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, edge := range edges {
+		if !s.edgeLinksExist(edge) {
+			return xerrors.Errorf("restore edges: %w", graph.ErrUnknownEdgeLinks)
+		}
+		if existing := s.edges[edge.ID]; existing != nil && (existing.Src != edge.Src || existing.Dst != edge.Dst) {
+			return xerrors.Errorf("restore edges: %w", graph.ErrConflict)
+		}
+	}
+	for _, edge := range edges {
+		// Drop the edge currently connecting the same links as it may use
+		// a different ID.
+		for _, edgeID := range s.linkEdgeMap[edge.Src] {
+			if existing := s.edges[edgeID]; existing.Dst == edge.Dst {
+				delete(s.edges, edgeID)
+				s.removeOutEdge(edge.Src, edgeID)
+				s.removeInEdge(edge.Dst, edgeID)
+				break
+			}
+		}
+		eCopy := new(graph.Edge)
+		*eCopy = *edge
+		s.edges[eCopy.ID] = eCopy
+		s.linkEdgeMap[eCopy.Src] = append(s.linkEdgeMap[eCopy.Src], eCopy.ID)
+		s.linkInEdgeMap[eCopy.Dst] = append(s.linkInEdgeMap[eCopy.Dst], eCopy.ID)
+	}
+	return nil
+}
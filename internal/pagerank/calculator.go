@@ -0,0 +1,178 @@
+package pagerank
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/joshvoll/linkrus/internal/bspgraph"
+	"github.com/joshvoll/linkrus/internal/bspgraph/aggregator"
+	"github.com/joshvoll/linkrus/internal/bspgraph/message"
+	"golang.org/x/xerrors"
+)
+
+// Calculator executes the iterative version of the PageRank algorithm
+// on a graph until the desired level of convergence is reached.
+type Calculator struct {
+	g   *bspgraph.Graph
+	cfg Config
+}
+
+// NewCalculator returns a new Calculator instance using the provided config
+// options.
+func NewCalculator(cfg Config) (*Calculator, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("PageRank calculator config validation failed: %w", err)
+	}
+	c := &Calculator{
+		cfg: cfg,
+	}
+	g, err := bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn:      c.updateScore,
+		ComputeWorkers: cfg.ComputeWorkers,
+		Combiner:       combineScores,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.g = g
+	return c, nil
+}
+
+// Close releases any resources allocated by this PageRank calculator.
+func (c *Calculator) Close() error {
+	return c.g.Close()
+}
+
+// Graph returns the underlying bspgraph.Graph instance.
+func (c *Calculator) Graph() *bspgraph.Graph {
+	return c.g
+}
+
+// AddVertex inserts a new vertex to the graph with the given id.
+func (c *Calculator) AddVertex(id string) {
+	c.g.AddVertex(id, 0.0)
+}
+
+// AddEdge inserts a directed edge from src to dst. If both src and dst refer
+// to the same vertex then this is a no-op.
+func (c *Calculator) AddEdge(src, dst string) error {
+	// Don't allow self-links
+	if src == dst {
+		return nil
+	}
+	return c.g.AddEdge(src, dst, nil)
+}
+
+// Executor creates and return a bspgraph.Executor for running the PageRank
+// algorithm once the graph layout has been properly set up.
+func (c *Calculator) Executor() *bspgraph.Executor {
+	c.registerAggregators()
+	cb := bspgraph.ExecutorCallbacks{
+		PreStep: func(_ context.Context, g *bspgraph.Graph) error {
+			// Reset sum of abs differences aggregator and residual
+			// aggregator for next step.
+			g.Aggregator("SAD").Set(0.0)
+			g.Aggregator(residualOutputAccName(g.Superstep())).Set(0.0)
+			return nil
+		},
+		PostStepKeepRunning: func(_ context.Context, g *bspgraph.Graph, _ int) (bool, error) {
+			// Supersteps 0 and 1 are part of the algorithm initialization;
+			// the predicate should only be evaluated for supersteps > 1
+			sad := g.Aggregator("SAD").Get().(float64)
+			return !(g.Superstep() > 1 && sad < c.cfg.MinSADForConvergence), nil
+		},
+	}
+	return bspgraph.NewExecutor(c.g, cb)
+}
+
+// registerAggregators creates and registers the aggregator instances that we
+// need to run the PageRank calculation algorithm.
+func (c *Calculator) registerAggregators() {
+	c.g.RegisterAggregator("page_count", new(aggregator.IntAccumulator))
+	c.g.RegisterAggregator("residual_0", new(aggregator.FloatAccumulator))
+	c.g.RegisterAggregator("residual_1", new(aggregator.FloatAccumulator))
+	c.g.RegisterAggregator("SAD", new(aggregator.FloatAccumulator))
+}
+
+// Scores invokes the provided visitor function for each vertex in the graph.
+func (c *Calculator) Scores(visitFn func(id string, score float64) error) error {
+	for id, v := range c.g.Vertices() {
+		if err := visitFn(id, v.Value().(float64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateScore implements the PageRank calculation as a bspgraph.ComputeFunc.
+//
+// At superstep 0, each vertex registers itself with the page counter. At
+// superstep 1, each vertex is assigned an initial score of 1/N and from
+// superstep 2 onwards the score of each vertex is calculated as:
+//
+//	score = (1 - d) / N + d * (sum(incoming scores) + residual)
+//
+// where d is the damping factor and residual is the score that was
+// distributed by dead-end vertices (vertices with no outgoing links) in the
+// previous superstep.
+func (c *Calculator) updateScore(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+	superstep := g.Superstep()
+	pageCountAgg := g.Aggregator("page_count")
+
+	// At step 0, we initialize the page count.
+	if superstep == 0 {
+		pageCountAgg.Aggregate(1)
+		return nil
+	}
+
+	var (
+		pageCount = float64(pageCountAgg.Get().(int))
+		newScore  float64
+	)
+	switch superstep {
+	case 1:
+		newScore = 1.0 / pageCount
+	default:
+		// Process incoming messages and calculate new score.
+		dampingFactor := c.cfg.DampingFactor
+		newScore = (1.0-dampingFactor)/pageCount + dampingFactor*incomingScore(g, msgIt)
+
+		// Update the sum of abs differences aggregator
+		g.Aggregator("SAD").Aggregate(math.Abs(v.Value().(float64) - newScore))
+	}
+	v.SetValue(newScore)
+
+	// If this is a dead-end (no outgoing links) we treat this link as if
+	// it was implicitly connected to all other links in the graph.
+	numOutLinks := float64(len(v.Edges()))
+	if numOutLinks == 0 {
+		g.Aggregator(residualOutputAccName(superstep)).Aggregate(newScore / pageCount)
+		return nil
+	}
+
+	// Otherwise, evenly distribute our score to all outgoing links.
+	return g.BroadcastToNeighbors(context.Background(), v, IncomingScoreMessage{Score: newScore / numOutLinks})
+}
+
+// incomingScore sums up the scores received by a vertex together with the
+// residual score distributed by dead-end vertices in the previous superstep.
+func incomingScore(g *bspgraph.Graph, msgIt message.Iterator) float64 {
+	var sum float64
+	for msgIt.Next() {
+		sum += msgIt.Message().(IncomingScoreMessage).Score
+	}
+	return sum + g.Aggregator(residualInputAccName(g.Superstep())).Get().(float64)
+}
+
+// residualOutputAccName returns the name of the accumulator where dead-end
+// vertices store their residual score for the current superstep.
+func residualOutputAccName(superstep int) string {
+	return fmt.Sprintf("residual_%d", superstep%2)
+}
+
+// residualInputAccName returns the name of the accumulator that contains the
+// residual score produced by dead-end vertices in the previous superstep.
+func residualInputAccName(superstep int) string {
+	return fmt.Sprintf("residual_%d", (superstep+1)%2)
+}
@@ -0,0 +1,118 @@
+package pagerank
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"github.com/joshvoll/linkrus/internal/linkgraph/store/memory"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CalculatorTestSuite))
+
+// CalculatorTestSuite verifies the PageRank calculator.
+type CalculatorTestSuite struct {
+	calc *Calculator
+}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *CalculatorTestSuite) SetUpTest(c *gc.C) {
+	calc, err := NewCalculator(Config{ComputeWorkers: 2, MinSADForConvergence: 0.0001})
+	c.Assert(err, gc.IsNil)
+	s.calc = calc
+}
+
+func (s *CalculatorTestSuite) TearDownTest(c *gc.C) {
+	c.Assert(s.calc.Close(), gc.IsNil)
+}
+
+func (s *CalculatorTestSuite) TestSimpleGraph(c *gc.C) {
+	// 0 <-> 1 <-> 2
+	for _, id := range []string{"0", "1", "2"} {
+		s.calc.AddVertex(id)
+	}
+	c.Assert(s.calc.AddEdge("0", "1"), gc.IsNil)
+	c.Assert(s.calc.AddEdge("1", "0"), gc.IsNil)
+	c.Assert(s.calc.AddEdge("1", "2"), gc.IsNil)
+	c.Assert(s.calc.AddEdge("2", "1"), gc.IsNil)
+
+	s.assertScores(c, map[string]float64{
+		"0": 0.2567,
+		"1": 0.4867,
+		"2": 0.2567,
+	})
+}
+
+func (s *CalculatorTestSuite) TestDeadEnds(c *gc.C) {
+	// 0 -> 1 -> 2; vertex 2 is a dead-end
+	for _, id := range []string{"0", "1", "2"} {
+		s.calc.AddVertex(id)
+	}
+	c.Assert(s.calc.AddEdge("0", "1"), gc.IsNil)
+	c.Assert(s.calc.AddEdge("1", "2"), gc.IsNil)
+
+	s.assertScores(c, map[string]float64{
+		"0": 0.1844,
+		"1": 0.3411,
+		"2": 0.4745,
+	})
+}
+
+func (s *CalculatorTestSuite) TestLoadAndUpdateScores(c *gc.C) {
+	ctx := context.Background()
+	lg := memory.NewInMemoryGraph()
+	linkIDs := make([]uuid.UUID, 3)
+	for i, u := range []string{"https://example.com/0", "https://example.com/1", "https://example.com/2"} {
+		link := &graph.Link{URL: u}
+		c.Assert(lg.UpsertLink(ctx, link), gc.IsNil)
+		linkIDs[i] = link.ID
+	}
+	for _, e := range [][2]int{{0, 1}, {1, 0}, {1, 2}, {2, 1}} {
+		c.Assert(lg.UpsertEdge(ctx, &graph.Edge{Src: linkIDs[e[0]], Dst: linkIDs[e[1]]}), gc.IsNil)
+	}
+
+	maxUUID := uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+	err := s.calc.LoadLinkGraph(ctx, lg, uuid.Nil, maxUUID, time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.calc.Graph().Vertices(), gc.HasLen, 3)
+	c.Assert(s.calc.Executor().RunToCompletion(ctx), gc.IsNil)
+
+	updater := make(scoreRecorder)
+	c.Assert(s.calc.UpdateScores(ctx, updater), gc.IsNil)
+	c.Assert(updater, gc.HasLen, 3)
+	assertScore(c, updater[linkIDs[0]], 0.2567)
+	assertScore(c, updater[linkIDs[1]], 0.4867)
+	assertScore(c, updater[linkIDs[2]], 0.2567)
+}
+
+func (s *CalculatorTestSuite) assertScores(c *gc.C, expScores map[string]float64) {
+	c.Assert(s.calc.Executor().RunToCompletion(context.Background()), gc.IsNil)
+
+	var sum float64
+	err := s.calc.Scores(func(id string, score float64) error {
+		assertScore(c, score, expScores[id])
+		sum += score
+		return nil
+	})
+	c.Assert(err, gc.IsNil)
+	assertScore(c, sum, 1.0)
+}
+
+func assertScore(c *gc.C, got, exp float64) {
+	c.Assert(math.Abs(got-exp) <= 0.001, gc.Equals, true, gc.Commentf("expected score to be %v; got %v", exp, got))
+}
+
+// scoreRecorder is a ScoreUpdater that keeps track of the updated scores.
+type scoreRecorder map[uuid.UUID]float64
+
+func (r scoreRecorder) UpdateScore(_ context.Context, linkID uuid.UUID, score float64) error {
+	r[linkID] = score
+	return nil
+}
@@ -0,0 +1,50 @@
+package pagerank
+
+import (
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+// Config encapsulates the required parameters for creating a new PageRank
+// calculator instance.
+type Config struct {
+	// DampingFactor is the probability that a random surfer will click on
+	// one of the outgoing links on the page they are currently visiting
+	// instead of visiting (teleporting to) a random page in the graph.
+	//
+	// If not specified, a default value of 0.85 will be used instead.
+	DampingFactor float64
+
+	// MinSADForConvergence is the threshold value for the sum of absolute
+	// differences (SAD) of the PageRank scores between two subsequent
+	// supersteps. Once the SAD drops below this value, the algorithm is
+	// considered to have converged and the calculation stops.
+	//
+	// If not specified, a default value of 0.001 will be used instead.
+	MinSADForConvergence float64
+
+	// ComputeWorkers specifies the number of workers to use for executing
+	// the PageRank compute function in each superstep. If not specified,
+	// a single worker will be used.
+	ComputeWorkers int
+}
+
+// validate checks whether a PageRank configuration is valid and sets the
+// default values where required.
+func (c *Config) validate() error {
+	var err error
+	if c.DampingFactor < 0 || c.DampingFactor > 1.0 {
+		err = multierror.Append(err, xerrors.New("DampingFactor must be in the range (0, 1]"))
+	} else if c.DampingFactor == 0 {
+		c.DampingFactor = 0.85
+	}
+	if c.MinSADForConvergence < 0 || c.MinSADForConvergence >= 1.0 {
+		err = multierror.Append(err, xerrors.New("MinSADForConvergence must be in the range (0, 1)"))
+	} else if c.MinSADForConvergence == 0 {
+		c.MinSADForConvergence = 0.001
+	}
+	if c.ComputeWorkers <= 0 {
+		c.ComputeWorkers = 1
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package pagerank
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/graphloader"
+	"github.com/joshvoll/linkrus/internal/linkgraph/graph"
+	"golang.org/x/xerrors"
+)
+
+// LoadLinkGraph populates the calculator graph with the links and edges
+// whose IDs belong to the [fromID, toID) range. Links must be retrieved and
+// edges must be updated before the specified cut-off timestamp. Edges whose
+// source or destination is not part of the loaded set of links are ignored
+// and so are nofollow edges as they must not pass on any PageRank score.
+func (c *Calculator) LoadLinkGraph(ctx context.Context, lg graphloader.LinkGraph, fromID, toID uuid.UUID, cutoff time.Time) error {
+	loader, err := graphloader.NewLoader(graphloader.Config{
+		LinkGraph:       lg,
+		VertexValueFn:   func(*graph.Link) interface{} { return 0.0 },
+		SkipRemoteEdges: true,
+		SkipSelfLinks:   true,
+	})
+	if err != nil {
+		return xerrors.Errorf("load link graph: %w", err)
+	}
+	if _, err = loader.Load(ctx, c.g, fromID, toID, cutoff, cutoff); err != nil {
+		return xerrors.Errorf("load link graph: %w", err)
+	}
+	return nil
+}
+
+// UpdateScores pushes the calculated PageRank score of each vertex in the
+// graph to the provided ScoreUpdater. Updating stops as soon as ctx expires.
+func (c *Calculator) UpdateScores(ctx context.Context, updater graphloader.ScoreUpdater) error {
+	if _, err := graphloader.WriteVertexValues(ctx, c.g, graphloader.NewScoreSink(updater)); err != nil {
+		return xerrors.Errorf("update scores: %w", err)
+	}
+	return nil
+}
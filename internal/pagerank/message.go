@@ -0,0 +1,27 @@
+package pagerank
+
+import "github.com/joshvoll/linkrus/internal/bspgraph/message"
+
+func init() {
+	message.RegisterCodec(IncomingScoreMessage{}.Type(), message.NewGobCodec(IncomingScoreMessage{}))
+}
+
+// IncomingScoreMessage is used for distributing the PageRank score of a
+// vertex to its neighbors.
+type IncomingScoreMessage struct {
+	// The score contribution of the vertex that sent the message.
+	Score float64
+}
+
+// Type returns the type of this message.
+func (pr IncomingScoreMessage) Type() string {
+	return "score"
+}
+
+// combineScores implements a message.Combiner that sums the scores of two
+// IncomingScoreMessage instances.
+func combineScores(a, b message.Message) message.Message {
+	return IncomingScoreMessage{
+		Score: a.(IncomingScoreMessage).Score + b.(IncomingScoreMessage).Score,
+	}
+}
@@ -0,0 +1,145 @@
+package partition
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// ErrInvalidPartitionCount is returned when attempting to split a UUID
+	// range into a non-positive number of partitions.
+	ErrInvalidPartitionCount = xerrors.New("number of partitions must be at least 1")
+
+	// ErrInvalidRange is returned when the range end is not greater than
+	// the range start.
+	ErrInvalidRange = xerrors.New("range end must be greater than the range start")
+
+	// ErrInvalidPartition is returned when requesting the extents of a
+	// partition that does not exist.
+	ErrInvalidPartition = xerrors.New("invalid partition index")
+
+	// ErrIDOutOfRange is returned when looking up the partition for an ID
+	// that is not part of the range.
+	ErrIDOutOfRange = xerrors.New("id is out of range")
+
+	// MaxUUID is the largest possible UUID value.
+	MaxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+)
+
+// Range represents a contiguous UUID region which is split into a number of
+// partitions.
+type Range struct {
+	start       uuid.UUID
+	rangeSplits []uuid.UUID
+}
+
+// NewFullRange creates a new range that uses the full UUID value space and
+// splits it into the provided number of partitions.
+func NewFullRange(numPartitions int) (Range, error) {
+	return NewRange(uuid.Nil, MaxUUID, numPartitions)
+}
+
+// NewRange creates a new range [start, end) and splits it into the provided
+// number of partitions.
+func NewRange(start, end uuid.UUID, numPartitions int) (Range, error) {
+	if bytes.Compare(start[:], end[:]) >= 0 {
+		return Range{}, xerrors.Errorf("new range: %w", ErrInvalidRange)
+	}
+	if numPartitions <= 0 {
+		return Range{}, xerrors.Errorf("new range: %w", ErrInvalidPartitionCount)
+	}
+
+	var (
+		err        error
+		to         uuid.UUID
+		tokenRange big.Int
+		partSize   big.Int
+		partIndex  big.Int
+		partStart  big.Int
+	)
+	partSize.SetBytes(end[:])
+	tokenRange.SetBytes(start[:])
+	partSize.Sub(&partSize, &tokenRange)
+	partSize.Div(&partSize, big.NewInt(int64(numPartitions)))
+
+	ranges := make([]uuid.UUID, numPartitions)
+	for partition := 0; partition < numPartitions; partition++ {
+		if partition == numPartitions-1 {
+			to = end
+		} else {
+			partIndex.SetInt64(int64(partition + 1))
+			partStart.Mul(&partSize, &partIndex)
+			partStart.Add(&partStart, &tokenRange)
+			if to, err = uuidFromBigInt(&partStart); err != nil {
+				return Range{}, xerrors.Errorf("new range: %w", err)
+			}
+		}
+		ranges[partition] = to
+	}
+	return Range{
+		start:       start,
+		rangeSplits: ranges,
+	}, nil
+}
+
+// Extents returns the [start, end) values for the entire range.
+func (r Range) Extents() (uuid.UUID, uuid.UUID) {
+	return r.start, r.rangeSplits[len(r.rangeSplits)-1]
+}
+
+// NumPartitions returns the number of partitions in the range.
+func (r Range) NumPartitions() int {
+	return len(r.rangeSplits)
+}
+
+// PartitionExtents returns the [start, end) values for the requested
+// partition.
+func (r Range) PartitionExtents(partition int) (uuid.UUID, uuid.UUID, error) {
+	if partition < 0 || partition >= len(r.rangeSplits) {
+		return uuid.Nil, uuid.Nil, xerrors.Errorf("partition extents: %w", ErrInvalidPartition)
+	}
+	if partition == 0 {
+		return r.start, r.rangeSplits[0], nil
+	}
+	return r.rangeSplits[partition-1], r.rangeSplits[partition], nil
+}
+
+// PartitionForID returns the partition index that the provided ID belongs
+// to. As a special case, the end value of the full UUID range is mapped to
+// the last partition.
+func (r Range) PartitionForID(id uuid.UUID) (int, error) {
+	end := r.rangeSplits[len(r.rangeSplits)-1]
+	if bytes.Compare(id[:], r.start[:]) < 0 || (bytes.Compare(id[:], end[:]) >= 0 && end != MaxUUID) {
+		return -1, xerrors.Errorf("partition for id %v: %w", id, ErrIDOutOfRange)
+	}
+	for partition, partEnd := range r.rangeSplits {
+		if bytes.Compare(id[:], partEnd[:]) < 0 {
+			return partition, nil
+		}
+	}
+	return len(r.rangeSplits) - 1, nil
+}
+
+// Contains returns true if id belongs to the [fromID, toID) range. As
+// MaxUUID is used as the upper bound of the full UUID range, it is
+// considered to belong to any range that ends at MaxUUID.
+func Contains(id, fromID, toID uuid.UUID) bool {
+	if bytes.Compare(id[:], fromID[:]) < 0 {
+		return false
+	}
+	return bytes.Compare(id[:], toID[:]) < 0 || (id == MaxUUID && toID == MaxUUID)
+}
+
+// uuidFromBigInt converts a big.Int value into a UUID.
+func uuidFromBigInt(v *big.Int) (uuid.UUID, error) {
+	b := v.Bytes()
+	if len(b) < 16 {
+		padded := make([]byte, 16)
+		copy(padded[16-len(b):], b)
+		b = padded
+	}
+	return uuid.FromBytes(b)
+}
@@ -0,0 +1,93 @@
+package partition
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(RangeTestSuite))
+
+type RangeTestSuite struct{}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *RangeTestSuite) TestNewRangeErrors(c *gc.C) {
+	_, err := NewRange(
+		uuid.MustParse("40000000-0000-0000-0000-000000000000"),
+		uuid.MustParse("00000000-0000-0000-0000-000000000000"),
+		1,
+	)
+	c.Assert(xerrors.Is(err, ErrInvalidRange), gc.Equals, true)
+
+	_, err = NewFullRange(0)
+	c.Assert(xerrors.Is(err, ErrInvalidPartitionCount), gc.Equals, true)
+}
+
+func (s *RangeTestSuite) TestPartitionExtents(c *gc.C) {
+	r, err := NewFullRange(4)
+	c.Assert(err, gc.IsNil)
+	c.Assert(r.NumPartitions(), gc.Equals, 4)
+
+	expExtents := [][2]string{
+		{"00000000-0000-0000-0000-000000000000", "3fffffff-ffff-ffff-ffff-ffffffffffff"},
+		{"3fffffff-ffff-ffff-ffff-ffffffffffff", "7fffffff-ffff-ffff-ffff-fffffffffffe"},
+		{"7fffffff-ffff-ffff-ffff-fffffffffffe", "bfffffff-ffff-ffff-ffff-fffffffffffd"},
+		{"bfffffff-ffff-ffff-ffff-fffffffffffd", "ffffffff-ffff-ffff-ffff-ffffffffffff"},
+	}
+	for partition, exp := range expExtents {
+		from, to, err := r.PartitionExtents(partition)
+		c.Assert(err, gc.IsNil)
+		c.Assert(from.String(), gc.Equals, exp[0], gc.Commentf("partition %d", partition))
+		c.Assert(to.String(), gc.Equals, exp[1], gc.Commentf("partition %d", partition))
+	}
+
+	_, _, err = r.PartitionExtents(4)
+	c.Assert(xerrors.Is(err, ErrInvalidPartition), gc.Equals, true)
+}
+
+func (s *RangeTestSuite) TestPartitionForID(c *gc.C) {
+	r, err := NewFullRange(4)
+	c.Assert(err, gc.IsNil)
+
+	specs := []struct {
+		id   string
+		part int
+	}{
+		{"00000000-0000-0000-0000-000000000000", 0},
+		{"3fffffff-ffff-ffff-ffff-fffffffffffe", 0},
+		{"3fffffff-ffff-ffff-ffff-ffffffffffff", 1},
+		{"80000000-0000-0000-0000-000000000000", 2},
+		{"ffffffff-ffff-ffff-ffff-ffffffffffff", 3},
+	}
+	for _, spec := range specs {
+		got, err := r.PartitionForID(uuid.MustParse(spec.id))
+		c.Assert(err, gc.IsNil)
+		c.Assert(got, gc.Equals, spec.part, gc.Commentf("id %s", spec.id))
+	}
+
+	r, err = NewRange(
+		uuid.MustParse("40000000-0000-0000-0000-000000000000"),
+		uuid.MustParse("80000000-0000-0000-0000-000000000000"),
+		2,
+	)
+	c.Assert(err, gc.IsNil)
+	_, err = r.PartitionForID(uuid.Nil)
+	c.Assert(xerrors.Is(err, ErrIDOutOfRange), gc.Equals, true)
+	_, err = r.PartitionForID(uuid.MustParse("80000000-0000-0000-0000-000000000000"))
+	c.Assert(xerrors.Is(err, ErrIDOutOfRange), gc.Equals, true)
+}
+
+func (s *RangeTestSuite) TestContains(c *gc.C) {
+	from := uuid.MustParse("40000000-0000-0000-0000-000000000000")
+	to := uuid.MustParse("80000000-0000-0000-0000-000000000000")
+	c.Assert(Contains(from, from, to), gc.Equals, true)
+	c.Assert(Contains(to, from, to), gc.Equals, false)
+	c.Assert(Contains(uuid.Nil, from, to), gc.Equals, false)
+	c.Assert(Contains(MaxUUID, from, MaxUUID), gc.Equals, true)
+	c.Assert(Contains(MaxUUID, uuid.Nil, to), gc.Equals, false)
+}
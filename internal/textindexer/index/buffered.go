@@ -0,0 +1,183 @@
+package index
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+// BufferConfig encapsulates the configuration options for a BufferedIndexer.
+type BufferConfig struct {
+	// MaxItems is the number of buffered documents or score updates that
+	// triggers a flush.
+	//
+	// If not specified, a default value of 500 will be used instead.
+	MaxItems int
+
+	// FlushInterval specifies how often buffered items are flushed even if
+	// the buffer is not full. If not specified, items are only flushed when
+	// the buffer is full or when Flush is invoked.
+	FlushInterval time.Duration
+}
+
+// validate checks whether a buffer configuration is valid and sets the
+// default values where required.
+func (c *BufferConfig) validate() error {
+	var err error
+	if c.MaxItems < 0 {
+		err = multierror.Append(err, xerrors.New("MaxItems must not be negative"))
+	} else if c.MaxItems == 0 {
+		c.MaxItems = 500
+	}
+	if c.FlushInterval < 0 {
+		err = multierror.Append(err, xerrors.New("FlushInterval must not be negative"))
+	}
+	return err
+}
+
+// BufferedIndexer buffers documents and score updates and writes them to an
+// Indexer using its bulk operations. Writes block while a flush is in
+// progress so that callers cannot outpace the underlying indexer.
+//
+// Errors from periodic flushes are reported by the next call to Index,
+// UpdateScore or Flush.
+type BufferedIndexer struct {
+	indexer Indexer
+	cfg     BufferConfig
+
+	mu      sync.Mutex
+	docs    []*Document
+	updates []ScoreUpdate
+	lastErr error
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewBufferedIndexer returns a new BufferedIndexer that writes to indexer.
+// Callers must invoke Close once they are done to flush any buffered items.
+func NewBufferedIndexer(indexer Indexer, cfg BufferConfig) (*BufferedIndexer, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("buffered indexer config validation failed: %w", err)
+	}
+	b := &BufferedIndexer{
+		indexer: indexer,
+		cfg:     cfg,
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	if cfg.FlushInterval > 0 {
+		go b.periodicFlush()
+	} else {
+		close(b.doneCh)
+	}
+	return b, nil
+}
+
+// Index buffers a document for indexing. The document must not be modified
+// by the caller until it has been flushed.
+func (b *BufferedIndexer) Index(ctx context.Context, doc *Document) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	b.docs = append(b.docs, doc)
+	if len(b.docs) < b.cfg.MaxItems {
+		return nil
+	}
+	return b.flush(ctx)
+}
+
+// UpdateScore buffers a pagerank score update for a link.
+func (b *BufferedIndexer) UpdateScore(ctx context.Context, linkID uuid.UUID, score float64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	b.updates = append(b.updates, ScoreUpdate{LinkID: linkID, Score: score})
+	if len(b.updates) < b.cfg.MaxItems {
+		return nil
+	}
+	return b.flush(ctx)
+}
+
+// Flush writes all buffered items to the underlying indexer.
+func (b *BufferedIndexer) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	err := b.takeErr()
+	if flushErr := b.flush(ctx); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// Close stops the periodic flushes and writes all buffered items to the
+// underlying indexer.
+func (b *BufferedIndexer) Close() error {
+	select {
+	case <-b.stopCh:
+		return nil
+	default:
+		close(b.stopCh)
+	}
+	<-b.doneCh
+	return b.Flush(context.Background())
+}
+
+func (b *BufferedIndexer) periodicFlush() {
+	defer close(b.doneCh)
+	tick := time.NewTicker(b.cfg.FlushInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case <-tick.C:
+			b.mu.Lock()
+			if err := b.flush(context.Background()); err != nil && b.lastErr == nil {
+				b.lastErr = err
+			}
+			b.mu.Unlock()
+		}
+	}
+}
+
+// flush writes the buffered documents followed by the buffered score
+// updates. Items are dropped from the buffer even if they could not be
+// written. The caller must hold b.mu.
+func (b *BufferedIndexer) flush(ctx context.Context) error {
+	var docErr, updateErr error
+	if len(b.docs) != 0 {
+		if docErr = b.indexer.BulkIndex(ctx, b.docs); docErr != nil {
+			docErr = xerrors.Errorf("flush documents: %w", docErr)
+		}
+		b.docs = nil
+	}
+	if len(b.updates) != 0 {
+		if updateErr = b.indexer.BulkUpdateScores(ctx, b.updates); updateErr != nil {
+			updateErr = xerrors.Errorf("flush score updates: %w", updateErr)
+		}
+		b.updates = nil
+	}
+	if docErr != nil && updateErr != nil {
+		return multierror.Append(docErr, updateErr)
+	} else if docErr != nil {
+		return docErr
+	}
+	return updateErr
+}
+
+// takeErr returns and clears the error from the last periodic flush. The
+// caller must hold b.mu.
+func (b *BufferedIndexer) takeErr() error {
+	err := b.lastErr
+	b.lastErr = nil
+	return err
+}
@@ -0,0 +1,97 @@
+package index
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(BufferedIndexerTestSuite))
+
+// BufferedIndexerTestSuite verifies the buffering and flushing logic of the
+// BufferedIndexer.
+type BufferedIndexerTestSuite struct{}
+
+func (s *BufferedIndexerTestSuite) TestFlushWhenFull(c *gc.C) {
+	rec := new(recordingIndexer)
+	b, err := NewBufferedIndexer(rec, BufferConfig{MaxItems: 2})
+	c.Assert(err, gc.IsNil)
+
+	for i := 0; i < 5; i++ {
+		c.Assert(b.Index(context.Background(), &Document{LinkID: uuid.New()}), gc.IsNil)
+	}
+	c.Assert(b.UpdateScore(context.Background(), uuid.New(), 0.5), gc.IsNil)
+	c.Assert(rec.batchSizes(), gc.DeepEquals, []int{2, 2})
+
+	c.Assert(b.Close(), gc.IsNil)
+	c.Assert(rec.batchSizes(), gc.DeepEquals, []int{2, 2, 1})
+	c.Assert(rec.updates, gc.HasLen, 1)
+}
+
+func (s *BufferedIndexerTestSuite) TestPeriodicFlush(c *gc.C) {
+	rec := new(recordingIndexer)
+	b, err := NewBufferedIndexer(rec, BufferConfig{MaxItems: 100, FlushInterval: 10 * time.Millisecond})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(b.Close(), gc.IsNil) }()
+
+	c.Assert(b.Index(context.Background(), &Document{LinkID: uuid.New()}), gc.IsNil)
+	for deadline := time.Now().Add(5 * time.Second); len(rec.batchSizes()) == 0; {
+		c.Assert(time.Now().Before(deadline), gc.Equals, true, gc.Commentf("timed out waiting for periodic flush"))
+		time.Sleep(time.Millisecond)
+	}
+	c.Assert(rec.batchSizes(), gc.DeepEquals, []int{1})
+}
+
+func (s *BufferedIndexerTestSuite) TestFlushErrors(c *gc.C) {
+	rec := &recordingIndexer{err: &BulkError{Items: []BulkItemError{{Err: ErrMissingLinkID}}}}
+	b, err := NewBufferedIndexer(rec, BufferConfig{MaxItems: 1})
+	c.Assert(err, gc.IsNil)
+
+	err = b.Index(context.Background(), &Document{})
+	c.Assert(xerrors.Is(err, ErrMissingLinkID), gc.Equals, true)
+	c.Assert(b.Close(), gc.IsNil, gc.Commentf("failed items should not be retried"))
+}
+
+func (s *BufferedIndexerTestSuite) TestInvalidConfig(c *gc.C) {
+	_, err := NewBufferedIndexer(new(recordingIndexer), BufferConfig{MaxItems: -1, FlushInterval: -time.Second})
+	c.Assert(err, gc.ErrorMatches, "(?s).*MaxItems must not be negative.*FlushInterval must not be negative.*")
+}
+
+// recordingIndexer is an Indexer that records the bulk operations invoked
+// on it.
+type recordingIndexer struct {
+	Indexer
+
+	mu      sync.Mutex
+	batches [][]*Document
+	updates []ScoreUpdate
+	err     error
+}
+
+func (r *recordingIndexer) BulkIndex(_ context.Context, docs []*Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, append([]*Document(nil), docs...))
+	return r.err
+}
+
+func (r *recordingIndexer) BulkUpdateScores(_ context.Context, updates []ScoreUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updates = append(r.updates, updates...)
+	return r.err
+}
+
+func (r *recordingIndexer) batchSizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sizes []int
+	for _, batch := range r.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
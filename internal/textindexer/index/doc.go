@@ -20,4 +20,17 @@ type Document struct {
 	IndexedAt time.Time
 	// the PageRank score (need some help for this one).
 	PageRank float64
+	// the highlighted snippets of the fields that matched a search query,
+	// keyed by field name (see HighlightTitle and HighlightContent).
+	// Matched terms are wrapped in <mark> tags; the remaining text is not
+	// escaped. It is only populated for search results when
+	// Query.Highlight is set and ignored when indexing documents.
+	Highlights map[string][]string
 }
+
+const (
+	// HighlightTitle is the Highlights key for the title snippets.
+	HighlightTitle = "Title"
+	// HighlightContent is the Highlights key for the content snippets.
+	HighlightContent = "Content"
+)
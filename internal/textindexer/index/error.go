@@ -1,10 +1,48 @@
 package index
 
-import "golang.org/x/xerrors"
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
 
 var (
 	// ErrNotFound is return by the indexer when there is no result
 	ErrNotFound = xerrors.New("not found")
 	// ErrMissingLinkID is return when atending to the indexer an there is no linkID
 	ErrMissingLinkID = xerrors.New("document do not provide a valid linkID")
+	// ErrInvalidQuery is returned when a query expression cannot be parsed
+	ErrInvalidQuery = xerrors.New("invalid query")
 )
+
+// BulkItemError describes an item of a bulk operation that could not be
+// processed.
+type BulkItemError struct {
+	LinkID uuid.UUID
+	Err    error
+}
+
+// BulkError is returned by bulk operations when some of their items could
+// not be processed.
+type BulkError struct {
+	Items []BulkItemError
+}
+
+// Error implements the error interface.
+func (e *BulkError) Error() string {
+	if len(e.Items) == 0 {
+		return "bulk operation failed"
+	}
+	return fmt.Sprintf("%d bulk item(s) failed; first failure for link %s: %v", len(e.Items), e.Items[0].LinkID, e.Items[0].Err)
+}
+
+// Is reports whether any of the failed items matches target.
+func (e *BulkError) Is(target error) bool {
+	for _, item := range e.Items {
+		if xerrors.Is(item.Err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package index
+
+import "time"
+
+// Field describes the document field(s) that a query term is matched against.
+type Field uint8
+
+const (
+	// FieldDefault matches terms against the document title and content.
+	FieldDefault Field = iota
+
+	// FieldTitle matches terms against the document title.
+	FieldTitle
+
+	// FieldURL matches terms against the full document URL. URLs are not
+	// analyzed so terms must match exactly (or via prefix/wildcard terms).
+	FieldURL
+
+	// FieldSite matches the host name of the document URL. A site term
+	// matches the host itself as well as any of its sub-domains.
+	FieldSite
+)
+
+// Expr is implemented by the nodes of a parsed query expression. Indexers
+// translate expression trees into the native query format of their backing
+// store.
+type Expr interface {
+	expr()
+}
+
+// TermExpr matches documents containing a single term.
+type TermExpr struct {
+	Field Field
+	Term  string
+}
+
+// PhraseExpr matches documents containing a sequence of terms in order.
+type PhraseExpr struct {
+	Field  Field
+	Phrase string
+}
+
+// PrefixExpr matches documents containing a term that starts with Prefix.
+type PrefixExpr struct {
+	Field  Field
+	Prefix string
+}
+
+// WildcardExpr matches documents containing a term that matches Pattern.
+// The pattern may contain '*' (any sequence of characters) and '?' (any
+// single character) wildcards.
+type WildcardExpr struct {
+	Field   Field
+	Pattern string
+}
+
+// DateRangeExpr matches documents whose IndexedAt timestamp lies within the
+// [From, To) range. A zero From or To value leaves the respective side of the
+// range open.
+type DateRangeExpr struct {
+	From time.Time
+	To   time.Time
+}
+
+// AndExpr matches documents that match all of its sub-expressions.
+type AndExpr struct {
+	Exprs []Expr
+}
+
+// OrExpr matches documents that match at least one of its sub-expressions.
+type OrExpr struct {
+	Exprs []Expr
+}
+
+// NotExpr matches documents that do not match its sub-expression.
+type NotExpr struct {
+	Expr Expr
+}
+
+func (*TermExpr) expr()      {}
+func (*PhraseExpr) expr()    {}
+func (*PrefixExpr) expr()    {}
+func (*WildcardExpr) expr()  {}
+func (*DateRangeExpr) expr() {}
+func (*AndExpr) expr()       {}
+func (*OrExpr) expr()        {}
+func (*NotExpr) expr()       {}
@@ -1,23 +1,49 @@
 package index
 
-import "github.com/google/uuid"
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
 
 // Indexer implement by objects that can index and search the documents.
-// all data is provider by the linkrus crawler
+// all data is provider by the linkrus crawler. All methods accept a context
+// which is used to cancel any in-flight requests to the underlying store.
 type Indexer interface {
 	// Index insert a new document to the index or update a existing one, it will return an error if there is a problem
-	Index(doc *Document) error
+	Index(ctx context.Context, doc *Document) error
 
 	// FindByID look up a document base on its Linkd ID. and return a document
-	FindByID(linkID uuid.UUID) (*Document, error)
+	FindByID(ctx context.Context, linkID uuid.UUID) (*Document, error)
 
 	// Searh the index for a particular query and return back the result
-	// Iterator
-	Search(query Query) (Iterator, error)
+	// Iterator. The context is also used by the iterator when it fetches
+	// the next page of results.
+	Search(ctx context.Context, query Query) (Iterator, error)
 
 	// UpdateScore updates the pagerank socre for a document with a specific link.
 	// if no exists , a place holder document with the provided score will be created
-	UpdateScore(linkID uuid.UUID, score float64) error
+	UpdateScore(ctx context.Context, linkID uuid.UUID, score float64) error
+
+	// BulkIndex inserts or updates a batch of documents. Documents that
+	// cannot be indexed are reported via a *BulkError; the remaining
+	// documents are indexed regardless.
+	BulkIndex(ctx context.Context, docs []*Document) error
+
+	// BulkUpdateScores updates the pagerank score for a batch of links,
+	// creating place holder documents where required. Failed updates are
+	// reported via a *BulkError.
+	BulkUpdateScores(ctx context.Context, updates []ScoreUpdate) error
+
+	// BulkDelete removes the documents for a batch of links from the index.
+	// Unknown link IDs are ignored.
+	BulkDelete(ctx context.Context, linkIDs []uuid.UUID) error
+}
+
+// ScoreUpdate describes a pagerank score update for a single link.
+type ScoreUpdate struct {
+	LinkID uuid.UUID
+	Score  float64
 }
 
 // Iterator is implemented by an object that can paginate the search
@@ -45,6 +71,10 @@ const (
 
 	// QueryTypeFrase searche for a expected search expression
 	QueryTypeFrase
+
+	// QueryTypeAdvanced parses the search expression using the query
+	// language supported by ParseQuery.
+	QueryTypeAdvanced
 )
 
 // QueryType describe the type of query that the indexer support
@@ -62,4 +92,8 @@ type Query struct {
 
 	// the number of search
 	Offset uint64
+
+	// Highlight requests snippets with the matched terms highlighted for
+	// each result. See Document.Highlights.
+	Highlight bool
 }
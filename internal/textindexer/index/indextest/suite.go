@@ -1,6 +1,8 @@
 package indextest
 
 import (
+	"context"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -32,8 +34,9 @@ func (s *SuiteBase) TestIndexDocument(c *gc.C) {
 		Content:   "lorem ipsum",
 		IndexedAt: time.Now().Add(-12 * time.Hour).UTC(),
 	}
-	err := s.idx.Index(doc)
+	err := s.idx.Index(context.Background(), doc)
 	c.Assert(err, gc.IsNil)
+	c.Assert(s.idx.UpdateScore(context.Background(), doc.LinkID, 0.5), gc.IsNil)
 	updateDoc := &index.Document{
 		LinkID:    doc.LinkID,
 		URL:       "https://www.sandals.com/",
@@ -41,12 +44,15 @@ func (s *SuiteBase) TestIndexDocument(c *gc.C) {
 		Content:   "nothing about beaches for now",
 		IndexedAt: time.Now().UTC(),
 	}
-	err = s.idx.Index(updateDoc)
+	err = s.idx.Index(context.Background(), updateDoc)
 	c.Assert(err, gc.IsNil)
+	got, err := s.idx.FindByID(context.Background(), doc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.PageRank, gc.Equals, 0.5, gc.Commentf("re-indexing a document should retain its PageRank score"))
 	imconpliteDoc := &index.Document{
 		URL: "http://wwww.sanservices.hn",
 	}
-	err = s.idx.Index(imconpliteDoc)
+	err = s.idx.Index(context.Background(), imconpliteDoc)
 	c.Assert(xerrors.Is(err, index.ErrMissingLinkID), gc.Equals, true)
 
 }
@@ -60,9 +66,335 @@ func (s *SuiteBase) TestFindByID(c *gc.C) {
 		Content:   "just another booking",
 		IndexedAt: time.Now().Add(-12 * time.Hour).UTC(),
 	}
-	err := s.idx.Index(doc)
+	err := s.idx.Index(context.Background(), doc)
 	c.Assert(err, gc.IsNil)
-	got, err := s.idx.FindByID(doc.LinkID)
+	got, err := s.idx.FindByID(context.Background(), doc.LinkID)
 	c.Assert(err, gc.IsNil)
 	c.Assert(got, gc.DeepEquals, doc, gc.Commentf("document returned by FindByID does not match inserted document"))
 }
+
+// TestSearchWithCancelledContext verifies that searches are aborted once the
+// caller's context has been cancelled.
+func (s *SuiteBase) TestSearchWithCancelledContext(c *gc.C) {
+	doc := &index.Document{
+		LinkID:    uuid.New(),
+		URL:       "https://www.beaches.com/",
+		Title:     "beach resorts",
+		Content:   "all inclusive beach resorts",
+		IndexedAt: time.Now().UTC(),
+	}
+	err := s.idx.Index(context.Background(), doc)
+	c.Assert(err, gc.IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.idx.Search(ctx, index.Query{Type: index.QueryTypeMatch, Expression: "beach"})
+	c.Assert(err, gc.NotNil, gc.Commentf("expected search with a cancelled context to fail"))
+}
+
+// TestAdvancedSearch verifies that the query language is translated
+// faithfully by checking the set of documents matched by each query.
+func (s *SuiteBase) TestAdvancedSearch(c *gc.C) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2020, month, d, 12, 0, 0, 0, time.UTC) }
+	docs := map[string]*index.Document{
+		"beach": {
+			URL:       "https://www.example.com/beach",
+			Title:     "Sunny beach resort",
+			Content:   "white sand and blue water",
+			IndexedAt: day(time.January, 5),
+		},
+		"cabin": {
+			URL:       "https://blog.example.com/mountains",
+			Title:     "Mountain cabin",
+			Content:   "snowy peaks and quiet forests",
+			IndexedAt: day(time.January, 15),
+		},
+		"house": {
+			URL:       "https://www.other.org/beach-house",
+			Title:     "Beach house rental",
+			Content:   "family friendly beach house close to the ocean",
+			IndexedAt: day(time.February, 1),
+		},
+		"hotel": {
+			URL:       "https://other.org/city",
+			Title:     "City hotel",
+			Content:   "downtown hotel with rooftop pool",
+			IndexedAt: day(time.February, 20),
+		},
+	}
+	names := make(map[string]string)
+	for name, doc := range docs {
+		doc.LinkID = uuid.New()
+		names[doc.LinkID.String()] = name
+		c.Assert(s.idx.Index(context.Background(), doc), gc.IsNil)
+	}
+
+	specs := []struct {
+		query string
+		exp   []string
+	}{
+		{query: "beach", exp: []string{"beach", "house"}},
+		{query: "beach -house", exp: []string{"beach"}},
+		{query: "beach NOT house", exp: []string{"beach"}},
+		{query: "-beach", exp: []string{"cabin", "hotel"}},
+		{query: "hotel OR cabin", exp: []string{"cabin", "hotel"}},
+		{query: "(beach OR hotel) AND pool", exp: []string{"hotel"}},
+		{query: `"beach house"`, exp: []string{"house"}},
+		{query: `"house beach"`, exp: nil},
+		{query: `title:"house rental"`, exp: []string{"house"}},
+		{query: "title:ocean", exp: nil},
+		{query: "ocean", exp: []string{"house"}},
+		{query: "site:example.com", exp: []string{"beach", "cabin"}},
+		{query: "site:www.other.org", exp: []string{"house"}},
+		{query: "url:https://other.org/city", exp: []string{"hotel"}},
+		{query: "url:https://www.example.com/*", exp: []string{"beach"}},
+		{query: "Moun*", exp: []string{"cabin"}},
+		{query: "b?ach", exp: []string{"beach", "house"}},
+		{query: "indexed:2020-01-01..2020-01-31", exp: []string{"beach", "cabin"}},
+		{query: "indexed:2020-02-01..", exp: []string{"hotel", "house"}},
+		{query: "beach indexed:..2020-01-10", exp: []string{"beach"}},
+	}
+	for i, spec := range specs {
+		c.Logf("[spec %d] query: %s", i, spec.query)
+		it, err := s.idx.Search(context.Background(), index.Query{Type: index.QueryTypeAdvanced, Expression: spec.query})
+		c.Assert(err, gc.IsNil)
+
+		var got []string
+		for it.Next() {
+			got = append(got, names[it.Document().LinkID.String()])
+		}
+		c.Assert(it.Error(), gc.IsNil)
+		c.Assert(it.Close(), gc.IsNil)
+		sort.Strings(got)
+		c.Assert(got, gc.DeepEquals, spec.exp)
+	}
+}
+
+// TestAdvancedSearchWithInvalidQuery verifies that query syntax errors are
+// reported to the caller.
+func (s *SuiteBase) TestAdvancedSearchWithInvalidQuery(c *gc.C) {
+	_, err := s.idx.Search(context.Background(), index.Query{Type: index.QueryTypeAdvanced, Expression: `(beach OR "resort`})
+	c.Assert(xerrors.Is(err, index.ErrInvalidQuery), gc.Equals, true)
+}
+
+// TestSearchHighlights verifies that snippets with the matched terms
+// highlighted are only returned when requested.
+func (s *SuiteBase) TestSearchHighlights(c *gc.C) {
+	doc := &index.Document{
+		LinkID:    uuid.New(),
+		URL:       "https://www.example.com/resort",
+		Title:     "a beach resort",
+		Content:   "our resort is right next to a quiet beach with white sand",
+		IndexedAt: time.Now().UTC(),
+	}
+	c.Assert(s.idx.Index(context.Background(), doc), gc.IsNil)
+
+	for _, highlight := range []bool{false, true} {
+		it, err := s.idx.Search(context.Background(), index.Query{Type: index.QueryTypeMatch, Expression: "beach", Highlight: highlight})
+		c.Assert(err, gc.IsNil)
+		c.Assert(it.Next(), gc.Equals, true)
+		got := it.Document()
+		c.Assert(it.Close(), gc.IsNil)
+
+		if !highlight {
+			c.Assert(got.Highlights, gc.IsNil)
+			continue
+		}
+		c.Assert(got.Highlights[index.HighlightTitle], gc.DeepEquals, []string{"a <mark>beach</mark> resort"})
+		c.Assert(got.Highlights[index.HighlightContent], gc.HasLen, 1)
+		c.Assert(got.Highlights[index.HighlightContent][0], gc.Matches, ".*quiet <mark>beach</mark> with.*")
+	}
+
+	// Highlights are never returned by look-ups.
+	got, err := s.idx.FindByID(context.Background(), doc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.Highlights, gc.IsNil)
+}
+
+// TestBulkIndex verifies that documents are indexed in bulk and that
+// documents without a link ID are reported without failing the batch.
+func (s *SuiteBase) TestBulkIndex(c *gc.C) {
+	existing := &index.Document{
+		LinkID:    uuid.New(),
+		URL:       "https://www.example.com/old",
+		Title:     "old title",
+		IndexedAt: time.Now().Add(-time.Hour).UTC(),
+	}
+	c.Assert(s.idx.Index(context.Background(), existing), gc.IsNil)
+	c.Assert(s.idx.UpdateScore(context.Background(), existing.LinkID, 0.5), gc.IsNil)
+
+	docs := []*index.Document{
+		{LinkID: existing.LinkID, URL: "https://www.example.com/new", Title: "new title", IndexedAt: time.Now().UTC()},
+		{URL: "https://www.example.com/no-id"},
+		{LinkID: uuid.New(), URL: "https://www.example.com/other", Title: "other title", IndexedAt: time.Now().UTC()},
+	}
+	err := s.idx.BulkIndex(context.Background(), docs)
+	c.Assert(xerrors.Is(err, index.ErrMissingLinkID), gc.Equals, true)
+	var bulkErr *index.BulkError
+	c.Assert(xerrors.As(err, &bulkErr), gc.Equals, true)
+	c.Assert(bulkErr.Items, gc.HasLen, 1)
+
+	for _, doc := range []*index.Document{docs[0], docs[2]} {
+		got, err := s.idx.FindByID(context.Background(), doc.LinkID)
+		c.Assert(err, gc.IsNil)
+		c.Assert(got.Title, gc.Equals, doc.Title)
+		c.Assert(got.URL, gc.Equals, doc.URL)
+	}
+
+	// Re-indexing a document retains its PageRank score.
+	got, err := s.idx.FindByID(context.Background(), existing.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.PageRank, gc.Equals, 0.5)
+}
+
+// TestBulkUpdateScores verifies that PageRank scores are updated in bulk for
+// both indexed and unknown links.
+func (s *SuiteBase) TestBulkUpdateScores(c *gc.C) {
+	doc := &index.Document{
+		LinkID:    uuid.New(),
+		URL:       "https://www.example.com/",
+		Title:     "example",
+		IndexedAt: time.Now().UTC(),
+	}
+	c.Assert(s.idx.Index(context.Background(), doc), gc.IsNil)
+
+	updates := []index.ScoreUpdate{
+		{LinkID: doc.LinkID, Score: 0.25},
+		{LinkID: uuid.New(), Score: 0.75},
+	}
+	c.Assert(s.idx.BulkUpdateScores(context.Background(), updates), gc.IsNil)
+
+	got, err := s.idx.FindByID(context.Background(), doc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.PageRank, gc.Equals, 0.25)
+	c.Assert(got.Title, gc.Equals, doc.Title, gc.Commentf("score update should not modify the document content"))
+
+	got, err = s.idx.FindByID(context.Background(), updates[1].LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.PageRank, gc.Equals, 0.75)
+}
+
+// TestBulkDelete verifies that deleted documents can no longer be looked up or
+// searched and that unknown link IDs are ignored.
+func (s *SuiteBase) TestBulkDelete(c *gc.C) {
+	docs := make([]*index.Document, 3)
+	for i := range docs {
+		docs[i] = &index.Document{
+			LinkID:    uuid.New(),
+			URL:       "https://www.example.com/" + string('a'+rune(i)),
+			Title:     "doomed",
+			IndexedAt: time.Now().UTC(),
+		}
+	}
+	c.Assert(s.idx.BulkIndex(context.Background(), docs), gc.IsNil)
+	c.Assert(s.idx.BulkDelete(context.Background(), []uuid.UUID{docs[0].LinkID, docs[2].LinkID, uuid.New()}), gc.IsNil)
+
+	for i, doc := range docs {
+		_, err := s.idx.FindByID(context.Background(), doc.LinkID)
+		c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, i != 1, gc.Commentf("doc %d", i))
+	}
+
+	c.Assert(s.rankedSearch(c, "doomed", 0), gc.DeepEquals, []uuid.UUID{docs[1].LinkID})
+}
+
+// TestSearchRankedByPageRank verifies that results are ordered by PageRank
+// score when the text relevance is ignored and that paginated results follow
+// the same order.
+func (s *SuiteBase) TestSearchRankedByPageRank(c *gc.C) {
+	s.setRanking(c, index.Ranking{PageRankWeight: 1})
+
+	scores := []float64{0.1, 0.5, 0.3, 0.4}
+	docs := make([]*index.Document, len(scores))
+	for i, score := range scores {
+		docs[i] = s.indexRankingDoc(c, "a beach resort", "sunny beach", time.Now().UTC(), score)
+	}
+
+	exp := []uuid.UUID{docs[1].LinkID, docs[3].LinkID, docs[2].LinkID, docs[0].LinkID}
+	c.Assert(s.rankedSearch(c, "beach", 0), gc.DeepEquals, exp)
+	c.Assert(s.rankedSearch(c, "beach", 2), gc.DeepEquals, exp[2:])
+}
+
+// TestSearchWithTitleBoost verifies that boosting title matches ranks
+// documents mentioning the query in their title first.
+func (s *SuiteBase) TestSearchWithTitleBoost(c *gc.C) {
+	s.setRanking(c, index.Ranking{RelevanceWeight: 1, TitleBoost: 10})
+
+	inContent := s.indexRankingDoc(c, "a resort", "beach beach beach resort", time.Now().UTC(), 0)
+	inTitle := s.indexRankingDoc(c, "beach", "a resort with a view of the mountains and the city", time.Now().UTC(), 0)
+
+	got := s.rankedSearch(c, "beach", 0)
+	c.Assert(got, gc.DeepEquals, []uuid.UUID{inTitle.LinkID, inContent.LinkID})
+}
+
+// TestSearchWithRecencyDecay verifies that the scores of older documents
+// decay according to the configured half-life.
+func (s *SuiteBase) TestSearchWithRecencyDecay(c *gc.C) {
+	now := time.Now().UTC()
+	s.setRanking(c, index.Ranking{PageRankWeight: 1, RecencyHalfLife: 24 * time.Hour})
+
+	old := s.indexRankingDoc(c, "beach", "beach", now.Add(-72*time.Hour), 0.5)
+	newest := s.indexRankingDoc(c, "beach", "beach", now.Add(-time.Hour), 0.5)
+	recent := s.indexRankingDoc(c, "beach", "beach", now.Add(-24*time.Hour), 0.5)
+	c.Assert(s.rankedSearch(c, "beach", 0), gc.DeepEquals, []uuid.UUID{newest.LinkID, recent.LinkID, old.LinkID})
+
+	// A popular document indexed 3 half-lives ago scores 0.8 / 8 = 0.1 and
+	// ranks below a fresh document with a lower PageRank score. Without
+	// decay the order is reversed.
+	popular := s.indexRankingDoc(c, "cabin", "cabin", now.Add(-72*time.Hour), 0.8)
+	fresh := s.indexRankingDoc(c, "cabin", "cabin", now, 0.3)
+	c.Assert(s.rankedSearch(c, "cabin", 0), gc.DeepEquals, []uuid.UUID{fresh.LinkID, popular.LinkID})
+
+	s.setRanking(c, index.Ranking{PageRankWeight: 1})
+	c.Assert(s.rankedSearch(c, "cabin", 0), gc.DeepEquals, []uuid.UUID{popular.LinkID, fresh.LinkID})
+}
+
+// TestSetInvalidRanking verifies that invalid ranking configurations are
+// rejected.
+func (s *SuiteBase) TestSetInvalidRanking(c *gc.C) {
+	ranker := s.ranker(c)
+	c.Assert(ranker.SetRanking(index.Ranking{}), gc.NotNil)
+	c.Assert(ranker.SetRanking(index.Ranking{RelevanceWeight: -1, PageRankWeight: 1}), gc.NotNil)
+}
+
+// ranker is implemented by indexers whose ranking can be configured.
+type ranker interface {
+	SetRanking(index.Ranking) error
+}
+
+func (s *SuiteBase) ranker(c *gc.C) ranker {
+	r, ok := s.idx.(ranker)
+	if !ok {
+		c.Skip("indexer does not support configurable ranking")
+	}
+	return r
+}
+
+func (s *SuiteBase) setRanking(c *gc.C, ranking index.Ranking) {
+	c.Assert(s.ranker(c).SetRanking(ranking), gc.IsNil)
+}
+
+func (s *SuiteBase) indexRankingDoc(c *gc.C, title, content string, indexedAt time.Time, pageRank float64) *index.Document {
+	doc := &index.Document{
+		LinkID:    uuid.New(),
+		URL:       "https://www.example.com/",
+		Title:     title,
+		Content:   content,
+		IndexedAt: indexedAt,
+	}
+	c.Assert(s.idx.Index(context.Background(), doc), gc.IsNil)
+	c.Assert(s.idx.UpdateScore(context.Background(), doc.LinkID, pageRank), gc.IsNil)
+	return doc
+}
+
+func (s *SuiteBase) rankedSearch(c *gc.C, expression string, offset uint64) []uuid.UUID {
+	it, err := s.idx.Search(context.Background(), index.Query{Type: index.QueryTypeMatch, Expression: expression, Offset: offset})
+	c.Assert(err, gc.IsNil)
+
+	var got []uuid.UUID
+	for it.Next() {
+		got = append(got, it.Document().LinkID)
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(it.Close(), gc.IsNil)
+	return got
+}
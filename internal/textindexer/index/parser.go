@@ -0,0 +1,392 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// dateRangeField is the field prefix for IndexedAt date-range filters.
+const dateRangeField = "indexed"
+
+// queryFields maps the field prefixes supported by the query language to
+// document fields.
+var queryFields = map[string]Field{
+	"title": FieldTitle,
+	"url":   FieldURL,
+	"site":  FieldSite,
+}
+
+// ParseQuery parses a user-facing query string into an expression tree. The
+// query language supports the following constructs:
+//
+//	beach resort          documents matching both terms (implicit AND)
+//	beach AND resort      same as above
+//	beach OR resort       documents matching either term
+//	NOT beach, -beach     documents not matching the term
+//	(beach OR sea) sand   parentheses group sub-expressions
+//	"beach resort"        documents containing the exact phrase
+//	title:beach           field scoping; supported fields are title, url
+//	title:"beach resort"  and site
+//	site:example.com      documents hosted on example.com or a sub-domain
+//	bea*, b?ach           prefix and wildcard terms; for URL terms, only '*'
+//	                      is treated as a wildcard
+//	indexed:2020-01-01..2020-01-31
+//	                      documents indexed within the date range; either
+//	                      side of the range may be omitted and dates may
+//	                      also be specified in RFC 3339 format
+//
+// NOT binds tighter than AND which in turn binds tighter than OR. The AND,
+// OR and NOT operators must be written in upper-case; lower-case versions
+// are treated as regular terms.
+func ParseQuery(query string) (Expr, error) {
+	toks, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 1 {
+		return nil, queryError(0, "empty query")
+	}
+
+	p := &queryParser{toks: toks}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.typ != tokEOF {
+		return nil, queryError(tok.pos, "unexpected %q", tok.raw)
+	}
+	return expr, nil
+}
+
+// queryError returns an ErrInvalidQuery error annotated with the offset in
+// the query string where the problem was detected.
+func queryError(pos int, format string, args ...interface{}) error {
+	return xerrors.Errorf("parse query: offset %d: %s: %w", pos, fmt.Sprintf(format, args...), ErrInvalidQuery)
+}
+
+type tokenType uint8
+
+const (
+	tokEOF tokenType = iota
+	tokTerm
+	tokPhrase
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+// token is a lexical element of a query string.
+type token struct {
+	typ tokenType
+	pos int
+	raw string
+
+	// The lower-cased field prefix and value of term and phrase tokens.
+	field string
+	val   string
+}
+
+// lexQuery splits a query string into tokens. The returned list is always
+// terminated by a tokEOF token.
+func lexQuery(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		start := i
+		switch c := s[i]; {
+		case isSpace(c):
+			i++
+			continue
+		case c == '(':
+			i++
+			toks = append(toks, token{typ: tokLParen, pos: start, raw: "("})
+		case c == ')':
+			i++
+			toks = append(toks, token{typ: tokRParen, pos: start, raw: ")"})
+		case c == '"':
+			phrase, end, err := readPhrase(s, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			toks = append(toks, token{typ: tokPhrase, pos: start, raw: s[start:end], val: phrase})
+		case c == '-' && i+1 < len(s) && !isSpace(s[i+1]):
+			i++
+			toks = append(toks, token{typ: tokNot, pos: start, raw: "-"})
+		default:
+			for i < len(s) && !isSpace(s[i]) && s[i] != '(' && s[i] != ')' && s[i] != '"' {
+				i++
+			}
+			tok, end, err := lexWord(s, start, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			toks = append(toks, tok)
+		}
+	}
+	return append(toks, token{typ: tokEOF, pos: len(s), raw: "end of query"}), nil
+}
+
+// lexWord converts the word in s[start:end] into an operator, term or
+// field-scoped term token. Field-scoped phrases (e.g. title:"foo bar") are
+// read in their entirety and the offset where lexing should resume is
+// returned.
+func lexWord(s string, start, end int) (token, int, error) {
+	word := s[start:end]
+	switch word {
+	case "AND":
+		return token{typ: tokAnd, pos: start, raw: word}, end, nil
+	case "OR":
+		return token{typ: tokOr, pos: start, raw: word}, end, nil
+	case "NOT":
+		return token{typ: tokNot, pos: start, raw: word}, end, nil
+	}
+
+	tok := token{typ: tokTerm, pos: start, raw: word, val: word}
+	colon := strings.IndexByte(word, ':')
+	if colon <= 0 {
+		return tok, end, nil
+	}
+	field := strings.ToLower(word[:colon])
+	if _, known := queryFields[field]; !known && field != dateRangeField {
+		// Not a field prefix (e.g. "http://..."); treat as a plain term.
+		return tok, end, nil
+	}
+
+	tok.field, tok.val = field, word[colon+1:]
+	if tok.val != "" {
+		return tok, end, nil
+	}
+	if end < len(s) && s[end] == '"' {
+		phrase, phraseEnd, err := readPhrase(s, end)
+		if err != nil {
+			return token{}, 0, err
+		}
+		tok.typ, tok.raw, tok.val = tokPhrase, s[start:phraseEnd], phrase
+		return tok, phraseEnd, nil
+	}
+	return token{}, 0, queryError(start, "missing value for field %q", field)
+}
+
+// readPhrase reads the quoted phrase that starts at s[start]. Double quotes
+// and backslashes within the phrase can be escaped with a backslash.
+func readPhrase(s string, start int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, queryError(start, "unterminated phrase")
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// queryParser is a recursive descent parser for the following grammar:
+//
+//	query   := or EOF
+//	or      := and { "OR" and }
+//	and     := unary { [ "AND" ] unary }
+//	unary   := ( "NOT" | "-" ) unary | primary
+//	primary := "(" or ")" | term | phrase
+type queryParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *queryParser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *queryParser) next() token {
+	tok := p.toks[p.pos]
+	if tok.typ != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (Expr, error) {
+	expr, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{expr}
+	for p.peek().typ == tokOr {
+		p.next()
+		if expr, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &OrExpr{Exprs: exprs}, nil
+}
+
+func (p *queryParser) parseAnd() (Expr, error) {
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []Expr{expr}
+	for {
+		switch p.peek().typ {
+		case tokAnd:
+			p.next()
+		case tokTerm, tokPhrase, tokLParen, tokNot:
+			// Adjacent expressions are implicitly AND-ed.
+		default:
+			if len(exprs) == 1 {
+				return exprs[0], nil
+			}
+			return &AndExpr{Exprs: exprs}, nil
+		}
+		if expr, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+}
+
+func (p *queryParser) parseUnary() (Expr, error) {
+	if p.peek().typ != tokNot {
+		return p.parsePrimary()
+	}
+	p.next()
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &NotExpr{Expr: expr}, nil
+}
+
+func (p *queryParser) parsePrimary() (Expr, error) {
+	tok := p.next()
+	switch tok.typ {
+	case tokLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.typ != tokRParen {
+			return nil, queryError(closing.pos, "expected closing parenthesis; got %q", closing.raw)
+		}
+		return expr, nil
+	case tokTerm:
+		return termExpr(tok)
+	case tokPhrase:
+		return phraseExpr(tok)
+	default:
+		return nil, queryError(tok.pos, "unexpected %q", tok.raw)
+	}
+}
+
+func termExpr(tok token) (Expr, error) {
+	switch tok.field {
+	case dateRangeField:
+		return dateRangeExpr(tok)
+	case "site":
+		return &TermExpr{Field: FieldSite, Term: strings.ToLower(tok.val)}, nil
+	}
+
+	// As question marks are common in URLs, only '*' is treated as a
+	// wildcard for URL terms.
+	field, wildcards := queryFields[tok.field], "*?"
+	if field == FieldURL {
+		wildcards = "*"
+	}
+	wildcard := strings.IndexAny(tok.val, wildcards)
+	switch {
+	case wildcard == -1:
+		return &TermExpr{Field: field, Term: tok.val}, nil
+	case strings.Trim(tok.val, wildcards) == "":
+		return nil, queryError(tok.pos, "term %q consists solely of wildcards", tok.raw)
+	case wildcard == len(tok.val)-1 && tok.val[wildcard] == '*':
+		return &PrefixExpr{Field: field, Prefix: tok.val[:wildcard]}, nil
+	default:
+		return &WildcardExpr{Field: field, Pattern: tok.val}, nil
+	}
+}
+
+func phraseExpr(tok token) (Expr, error) {
+	if strings.TrimSpace(tok.val) == "" {
+		return nil, queryError(tok.pos, "empty phrase")
+	}
+	switch tok.field {
+	case dateRangeField:
+		return dateRangeExpr(tok)
+	case "site":
+		return &TermExpr{Field: FieldSite, Term: strings.ToLower(tok.val)}, nil
+	case "url":
+		// URLs are not analyzed so phrases are matched as a single term.
+		return &TermExpr{Field: FieldURL, Term: tok.val}, nil
+	}
+	return &PhraseExpr{Field: queryFields[tok.field], Phrase: tok.val}, nil
+}
+
+// dateRangeExpr parses a "from..to" date range where either side may be
+// omitted. A single date is shorthand for the range covering that day.
+// Dates without a time component cover the whole day so "to" dates are
+// inclusive while RFC 3339 "to" timestamps are exclusive.
+func dateRangeExpr(tok token) (Expr, error) {
+	fromVal, toVal := tok.val, tok.val
+	sep := strings.Index(tok.val, "..")
+	if sep != -1 {
+		fromVal, toVal = tok.val[:sep], tok.val[sep+2:]
+	}
+	if fromVal == "" && toVal == "" {
+		return nil, queryError(tok.pos, "date range %q is unbounded", tok.raw)
+	}
+
+	var (
+		expr     DateRangeExpr
+		dateOnly bool
+		err      error
+	)
+	if fromVal != "" {
+		if expr.From, _, err = parseQueryDate(fromVal); err != nil {
+			return nil, queryError(tok.pos, "invalid date %q", fromVal)
+		}
+	}
+	if toVal != "" {
+		if expr.To, dateOnly, err = parseQueryDate(toVal); err != nil {
+			return nil, queryError(tok.pos, "invalid date %q", toVal)
+		}
+		if dateOnly {
+			expr.To = expr.To.AddDate(0, 0, 1)
+		} else if sep == -1 {
+			return nil, queryError(tok.pos, "timestamp %q must be part of a range", tok.raw)
+		}
+	}
+	if !expr.From.IsZero() && !expr.To.IsZero() && !expr.To.After(expr.From) {
+		return nil, queryError(tok.pos, "date range %q is empty", tok.raw)
+	}
+	return &expr, nil
+}
+
+// parseQueryDate parses a date in YYYY-MM-DD or RFC 3339 format and reports
+// whether the value only specified a date.
+func parseQueryDate(s string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t.UTC(), false, err
+}
@@ -0,0 +1,122 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ParserTestSuite))
+
+// ParserTestSuite verifies the query language parser.
+type ParserTestSuite struct{}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+func (s *ParserTestSuite) TestParseQuery(c *gc.C) {
+	day := func(d int) time.Time { return time.Date(2020, time.January, d, 0, 0, 0, 0, time.UTC) }
+
+	specs := []struct {
+		query string
+		exp   Expr
+	}{
+		{
+			query: "beach",
+			exp:   &TermExpr{Term: "beach"},
+		},
+		{
+			query: "beach resort",
+			exp:   &AndExpr{Exprs: []Expr{&TermExpr{Term: "beach"}, &TermExpr{Term: "resort"}}},
+		},
+		{
+			query: "beach AND resort OR hotel",
+			exp: &OrExpr{Exprs: []Expr{
+				&AndExpr{Exprs: []Expr{&TermExpr{Term: "beach"}, &TermExpr{Term: "resort"}}},
+				&TermExpr{Term: "hotel"},
+			}},
+		},
+		{
+			query: "beach (resort OR hotel) -pool NOT spa",
+			exp: &AndExpr{Exprs: []Expr{
+				&TermExpr{Term: "beach"},
+				&OrExpr{Exprs: []Expr{&TermExpr{Term: "resort"}, &TermExpr{Term: "hotel"}}},
+				&NotExpr{Expr: &TermExpr{Term: "pool"}},
+				&NotExpr{Expr: &TermExpr{Term: "spa"}},
+			}},
+		},
+		{
+			query: `"white sand" title:"all \"inclusive\"" Title:Resort`,
+			exp: &AndExpr{Exprs: []Expr{
+				&PhraseExpr{Phrase: "white sand"},
+				&PhraseExpr{Field: FieldTitle, Phrase: `all "inclusive"`},
+				&TermExpr{Field: FieldTitle, Term: "Resort"},
+			}},
+		},
+		{
+			query: `url:https://example.com/a?b=c site:Example.COM url:"https://example.com/x y"`,
+			exp: &AndExpr{Exprs: []Expr{
+				&TermExpr{Field: FieldURL, Term: "https://example.com/a?b=c"},
+				&TermExpr{Field: FieldSite, Term: "example.com"},
+				&TermExpr{Field: FieldURL, Term: "https://example.com/x y"},
+			}},
+		},
+		{
+			query: "bea* title:b?ach url:https://example.com/*",
+			exp: &AndExpr{Exprs: []Expr{
+				&PrefixExpr{Prefix: "bea"},
+				&WildcardExpr{Field: FieldTitle, Pattern: "b?ach"},
+				&PrefixExpr{Field: FieldURL, Prefix: "https://example.com/"},
+			}},
+		},
+		{
+			query: "http://example.com",
+			exp:   &TermExpr{Term: "http://example.com"},
+		},
+		{
+			query: "indexed:2020-01-02..2020-01-05 indexed:2020-01-10 indexed:..2020-01-03T10:00:00Z",
+			exp: &AndExpr{Exprs: []Expr{
+				&DateRangeExpr{From: day(2), To: day(6)},
+				&DateRangeExpr{From: day(10), To: day(11)},
+				&DateRangeExpr{To: day(3).Add(10 * time.Hour)},
+			}},
+		},
+	}
+
+	for i, spec := range specs {
+		c.Logf("[spec %d] query: %s", i, spec.query)
+		got, err := ParseQuery(spec.query)
+		c.Assert(err, gc.IsNil)
+		c.Assert(got, gc.DeepEquals, spec.exp)
+	}
+}
+
+func (s *ParserTestSuite) TestParseQueryErrors(c *gc.C) {
+	specs := []struct {
+		query  string
+		errMsg string
+	}{
+		{query: "  ", errMsg: "empty query"},
+		{query: `beach "resort`, errMsg: "unterminated phrase"},
+		{query: `""`, errMsg: "empty phrase"},
+		{query: "(beach OR resort", errMsg: `expected closing parenthesis; got "end of query"`},
+		{query: "beach)", errMsg: `unexpected "\)"`},
+		{query: "beach OR", errMsg: `unexpected "end of query"`},
+		{query: "title: beach", errMsg: `missing value for field "title"`},
+		{query: "**", errMsg: "term .* consists solely of wildcards"},
+		{query: "indexed:..", errMsg: "date range .* is unbounded"},
+		{query: "indexed:yesterday", errMsg: `invalid date "yesterday"`},
+		{query: "indexed:2020-01-05..2020-01-01", errMsg: "date range .* is empty"},
+		{query: "indexed:2020-01-03T10:00:00Z", errMsg: "timestamp .* must be part of a range"},
+	}
+
+	for i, spec := range specs {
+		c.Logf("[spec %d] query: %s", i, spec.query)
+		_, err := ParseQuery(spec.query)
+		c.Assert(xerrors.Is(err, ErrInvalidQuery), gc.Equals, true)
+		c.Assert(err, gc.ErrorMatches, ".*"+spec.errMsg+".*")
+	}
+}
@@ -0,0 +1,90 @@
+package index
+
+import (
+	"math"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+// Ranking configures how indexers order search results. Indexers calculate
+// the final score of each matching document as:
+//
+//	score = (RelevanceWeight * relevance + PageRankWeight * PageRank) * decay
+//	decay = 0.5 ^ (age / RecencyHalfLife)
+//
+// where relevance is the text relevance score reported by the backing store
+// and age is the time elapsed since the document was indexed. Results are
+// returned in descending score order.
+//
+// Note that text relevance and PageRank scores use very different scales;
+// PageRank scores sum up to 1 across the whole link graph. The weights
+// should be tuned accordingly.
+type Ranking struct {
+	// RelevanceWeight specifies how much the text relevance score counts
+	// towards the final score.
+	RelevanceWeight float64
+
+	// PageRankWeight specifies how much the PageRank score counts towards
+	// the final score.
+	PageRankWeight float64
+
+	// TitleBoost multiplies the relevance of matches in the document
+	// title. If not specified, title matches are not boosted.
+	TitleBoost float64
+
+	// RecencyHalfLife specifies the document age at which its score is
+	// halved. If not specified, scores do not decay with age. Documents
+	// with a zero IndexedAt timestamp are never decayed.
+	RecencyHalfLife time.Duration
+}
+
+// DefaultRanking returns the ranking configuration used by indexers unless
+// configured otherwise. It sums the relevance and PageRank scores and does
+// not boost title matches or decay the scores of older documents.
+func DefaultRanking() Ranking {
+	return Ranking{
+		RelevanceWeight: 1,
+		PageRankWeight:  1,
+		TitleBoost:      1,
+	}
+}
+
+// Validate checks whether a ranking configuration is valid and sets the
+// default values where required.
+func (r *Ranking) Validate() error {
+	var err error
+	if r.RelevanceWeight < 0 {
+		err = multierror.Append(err, xerrors.New("RelevanceWeight must not be negative"))
+	}
+	if r.PageRankWeight < 0 {
+		err = multierror.Append(err, xerrors.New("PageRankWeight must not be negative"))
+	}
+	if r.RelevanceWeight == 0 && r.PageRankWeight == 0 {
+		err = multierror.Append(err, xerrors.New("at least one of RelevanceWeight and PageRankWeight must be positive"))
+	}
+	if r.TitleBoost < 0 {
+		err = multierror.Append(err, xerrors.New("TitleBoost must not be negative"))
+	} else if r.TitleBoost == 0 {
+		r.TitleBoost = 1
+	}
+	if r.RecencyHalfLife < 0 {
+		err = multierror.Append(err, xerrors.New("RecencyHalfLife must not be negative"))
+	}
+	return err
+}
+
+// Score returns the final score for a document given its text relevance and
+// PageRank scores and the time when it was indexed. Indexers whose backing
+// store computes the final score natively must implement the same formula.
+func (r Ranking) Score(relevance, pageRank float64, indexedAt, now time.Time) float64 {
+	score := r.RelevanceWeight*relevance + r.PageRankWeight*pageRank
+	if r.RecencyHalfLife <= 0 || indexedAt.IsZero() {
+		return score
+	}
+	if age := now.Sub(indexedAt); age > 0 {
+		score *= math.Pow(0.5, float64(age)/float64(r.RecencyHalfLife))
+	}
+	return score
+}
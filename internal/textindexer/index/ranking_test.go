@@ -0,0 +1,56 @@
+package index
+
+import (
+	"time"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(RankingTestSuite))
+
+// RankingTestSuite verifies the calculation of ranking scores.
+type RankingTestSuite struct{}
+
+func (s *RankingTestSuite) TestScore(c *gc.C) {
+	now := time.Date(2020, time.January, 10, 0, 0, 0, 0, time.UTC)
+	decaying := Ranking{RelevanceWeight: 2, PageRankWeight: 10, RecencyHalfLife: 24 * time.Hour}
+
+	specs := []struct {
+		ranking   Ranking
+		indexedAt time.Time
+		exp       float64
+	}{
+		{ranking: DefaultRanking(), indexedAt: now.Add(-48 * time.Hour), exp: 1.2},
+		{ranking: decaying, indexedAt: now, exp: 4},
+		{ranking: decaying, indexedAt: now.Add(-24 * time.Hour), exp: 2},
+		{ranking: decaying, indexedAt: now.Add(-48 * time.Hour), exp: 1},
+		// Documents without a timestamp or indexed in the future are not
+		// decayed.
+		{ranking: decaying, indexedAt: time.Time{}, exp: 4},
+		{ranking: decaying, indexedAt: now.Add(time.Hour), exp: 4},
+	}
+
+	for i, spec := range specs {
+		c.Logf("[spec %d]", i)
+		got := spec.ranking.Score(1.0, 0.2, spec.indexedAt, now)
+		c.Assert(got, gc.Equals, spec.exp)
+	}
+}
+
+func (s *RankingTestSuite) TestValidate(c *gc.C) {
+	r := Ranking{PageRankWeight: 1}
+	c.Assert(r.Validate(), gc.IsNil)
+	c.Assert(r.TitleBoost, gc.Equals, 1.0, gc.Commentf("expected default title boost to be applied"))
+
+	invalid := []Ranking{
+		{},
+		{RelevanceWeight: -1, PageRankWeight: 1},
+		{RelevanceWeight: 1, PageRankWeight: -1},
+		{RelevanceWeight: 1, TitleBoost: -2},
+		{RelevanceWeight: 1, RecencyHalfLife: -time.Hour},
+	}
+	for i, r := range invalid {
+		c.Logf("[spec %d]", i)
+		c.Assert(r.Validate(), gc.NotNil)
+	}
+}
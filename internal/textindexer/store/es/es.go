@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/elastic/go-elasticsearch"
@@ -21,6 +23,13 @@ const indexName = "textindexer"
 // batchSize is the size result for each query cached locally
 const batchSize = 10
 
+// bulkMaxItems and bulkMaxBytes cap the number of items and the body size of
+// each _bulk request; larger batches are split into multiple requests.
+const (
+	bulkMaxItems = 1000
+	bulkMaxBytes = 5 << 20
+)
+
 /*
 var esMappings = `
 {
@@ -45,6 +54,7 @@ var esMappings = `
     "properties": {
       "LinkID": {"type": "keyword"},
       "URL": {"type": "keyword"},
+      "Site": {"type": "keyword"},
       "Content": {"type": "text"},
       "Title": {"type": "text"},
       "IndexedAt": {"type": "date"},
@@ -53,6 +63,41 @@ var esMappings = `
   }
 }`
 
+// esHighlight requests snippets that mirror the ones produced by the bleve
+// html highlighter: the whole title and a single content fragment of up to
+// 200 characters with the matched terms wrapped in <mark> tags.
+var esHighlight = map[string]interface{}{
+	"pre_tags":  []string{"<mark>"},
+	"post_tags": []string{"</mark>"},
+	"fields": map[string]interface{}{
+		titleField: map[string]interface{}{
+			"number_of_fragments": 0,
+		},
+		contentField: map[string]interface{}{
+			"fragment_size":       200,
+			"number_of_fragments": 1,
+		},
+	},
+}
+
+// esRankingScript implements index.Ranking.Score so that results are ranked
+// by elastic search. Documents without a PageRank score are treated as having
+// a zero score and documents with a zero IndexedAt timestamp are never
+// decayed.
+const esRankingScript = `
+double score = params.relevanceWeight * _score;
+if (doc['PageRank'].size() != 0) {
+  score += params.pageRankWeight * doc['PageRank'].value;
+}
+if (params.halfLife > 0 && doc['IndexedAt'].size() != 0) {
+  long ts = doc['IndexedAt'].value.toInstant().toEpochMilli();
+  long age = params.now - ts;
+  if (ts != params.zeroTime && age > 0) {
+    score *= Math.pow(0.5, (double) age / params.halfLife);
+  }
+}
+return score;`
+
 // esSearchRes search query document definition
 type esSearchRes struct {
 	Hits esSearchResHits `json:"hits"`
@@ -66,22 +111,27 @@ type esSearchResHits struct {
 
 // esTotal count the total of the hits
 type esTotal struct {
-	Count uint64 `json:"count"`
+	Count uint64 `json:"value"`
 }
 
 // HitList gets the total list
 type esHitWrapper struct {
-	DocSource esDoc `json:"_source"`
+	DocSource esDoc               `json:"_source"`
+	Highlight map[string][]string `json:"highlight"`
 }
 
-// esDoc are the documentation definition base on index.Document
+// esDoc are the documentation definition base on index.Document. The JSON
+// field names must match the ones used by esMappings.
 type esDoc struct {
 	LinkID    string    `json:"LinkID"`
-	URL       string    `json:"url"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
+	URL       string    `json:"URL"`
+	Site      string    `json:"Site"`
+	Title     string    `json:"Title"`
+	Content   string    `json:"Content"`
 	IndexedAt time.Time `json:"IndexedAt"`
-	PageRank  float64   `json:"PageRank"`
+	// PageRank is omitted from the documents sent by Index and BulkIndex
+	// so that upserts do not overwrite the stored score.
+	PageRank *float64 `json:"PageRank,omitempty"`
 }
 
 // esUpdateRes define the update for the index method
@@ -89,6 +139,19 @@ type esUpdateRes struct {
 	Result string `json:"result"`
 }
 
+// esBulkRes is the response to a _bulk request. Each item maps the bulk
+// action to its result.
+type esBulkRes struct {
+	Errors bool                       `json:"errors"`
+	Items  []map[string]esBulkItemRes `json:"items"`
+}
+
+// esBulkItemRes is the result of a single bulk action
+type esBulkItemRes struct {
+	Status int      `json:"status"`
+	Error  *esError `json:"error"`
+}
+
 // esErrorRes define the erros for the response unmarshal
 type esErrorRes struct {
 	Error esError `json:"error"`
@@ -108,8 +171,12 @@ func (e esError) Error() string {
 // ElasticSearchIndexer is an indexer implementation using elastic search.
 // instance for the search query
 type ElasticSearchIndexer struct {
-	es         *elasticsearch.Client
-	refreshOpt func(*esapi.UpdateRequest)
+	es             *elasticsearch.Client
+	refreshOpt     func(*esapi.UpdateRequest)
+	bulkRefreshOpt func(*esapi.BulkRequest)
+
+	mu      sync.RWMutex
+	ranking index.Ranking
 }
 
 // NewElasticSearchIndexer create a new instance of the elastic search engine
@@ -125,17 +192,33 @@ func NewElasticSearchIndexer(esNode []string, syncUpdates bool) (*ElasticSearchI
 		return nil, err
 	}
 	refreshOpt := es.Update.WithRefresh("false")
+	bulkRefreshOpt := es.Bulk.WithRefresh("false")
 	if syncUpdates {
 		refreshOpt = es.Update.WithRefresh("true")
+		bulkRefreshOpt = es.Bulk.WithRefresh("true")
 	}
 	return &ElasticSearchIndexer{
-		es:         es,
-		refreshOpt: refreshOpt,
+		es:             es,
+		refreshOpt:     refreshOpt,
+		bulkRefreshOpt: bulkRefreshOpt,
+		ranking:        index.DefaultRanking(),
 	}, nil
 }
 
+// SetRanking configures how search results are ranked. If not invoked, the
+// indexer uses index.DefaultRanking.
+func (i *ElasticSearchIndexer) SetRanking(ranking index.Ranking) error {
+	if err := ranking.Validate(); err != nil {
+		return xerrors.Errorf("set ranking: %w", err)
+	}
+	i.mu.Lock()
+	i.ranking = ranking
+	i.mu.Unlock()
+	return nil
+}
+
 // Index insert a new document to the indexer elastict search or update a existing one
-func (i *ElasticSearchIndexer) Index(doc *index.Document) error {
+func (i *ElasticSearchIndexer) Index(ctx context.Context, doc *index.Document) error {
 	if doc.LinkID == uuid.Nil {
 		return xerrors.Errorf("index: %w ", index.ErrMissingLinkID)
 	}
@@ -150,7 +233,7 @@ func (i *ElasticSearchIndexer) Index(doc *index.Document) error {
 	if err := json.NewEncoder(&buf).Encode(&update); err != nil {
 		return xerrors.Errorf("index: %w ", err)
 	}
-	res, err := i.es.Update(indexName, esDoc.LinkID, &buf, i.refreshOpt)
+	res, err := i.es.Update(indexName, esDoc.LinkID, &buf, i.refreshOpt, i.es.Update.WithContext(ctx))
 	if err != nil {
 		return xerrors.Errorf("index update: %w ", err)
 	}
@@ -162,7 +245,7 @@ func (i *ElasticSearchIndexer) Index(doc *index.Document) error {
 }
 
 // FindByID look up a document base on its Linkd ID. and return a document
-func (i *ElasticSearchIndexer) FindByID(linkID uuid.UUID) (*index.Document, error) {
+func (i *ElasticSearchIndexer) FindByID(ctx context.Context, linkID uuid.UUID) (*index.Document, error) {
 	if linkID == uuid.Nil {
 		return nil, xerrors.Errorf("FindByID: %w ", index.ErrMissingLinkID)
 	}
@@ -179,7 +262,7 @@ func (i *ElasticSearchIndexer) FindByID(linkID uuid.UUID) (*index.Document, erro
 	if err := json.NewEncoder(&buf).Encode(query); err != nil {
 		return nil, xerrors.Errorf("find by id  query: %w ", err)
 	}
-	searchRes, err := runSearch(i.es, query)
+	searchRes, err := runSearch(ctx, i.es, query)
 	if err != nil {
 		return nil, xerrors.Errorf("run search: %w ", err)
 	}
@@ -190,35 +273,54 @@ func (i *ElasticSearchIndexer) FindByID(linkID uuid.UUID) (*index.Document, erro
 }
 
 // Search the index for a particular query and return back the results
-// these result can be multiple queries
-func (i *ElasticSearchIndexer) Search(q index.Query) (index.Iterator, error) {
-	var querytype string
+// these result can be multiple queries. The search is aborted if ctx
+// expires; the same context is used for fetching the following pages.
+func (i *ElasticSearchIndexer) Search(ctx context.Context, q index.Query) (index.Iterator, error) {
+	i.mu.RLock()
+	ranking := i.ranking
+	i.mu.RUnlock()
+
+	var (
+		textQuery map[string]interface{}
+		t         = queryTranslator{titleBoost: ranking.TitleBoost}
+	)
 	switch q.Type {
 	case index.QueryTypeFrase:
-		querytype = "phrase"
+		textQuery = t.multiMatchQuery("phrase", q.Expression)
+	case index.QueryTypeAdvanced:
+		expr, err := index.ParseQuery(q.Expression)
+		if err != nil {
+			return nil, xerrors.Errorf("search: %w", err)
+		}
+		if textQuery, err = t.translate(expr); err != nil {
+			return nil, xerrors.Errorf("search: %w", err)
+		}
 	default:
-		querytype = "best_fields"
+		textQuery = t.multiMatchQuery("best_fields", q.Expression)
 	}
 	query := map[string]interface{}{
 		"query": map[string]interface{}{
 			"function_score": map[string]interface{}{
-				"query": map[string]interface{}{
-					"multi_match": map[string]interface{}{
-						"type":   querytype,
-						"query":  q.Expression,
-						"fields": []string{"Title", "Content"},
-					},
-				},
+				"query":        textQuery,
+				"script_score": rankingScript(ranking, time.Now()),
+				"boost_mode":   "replace",
 			},
 		},
+		// Break ties by link ID so that results are paginated
+		// deterministically.
+		"sort": []interface{}{"_score", map[string]interface{}{"LinkID": "asc"}},
 		"from": q.Offset,
-		"to":   batchSize,
+		"size": batchSize,
+	}
+	if q.Highlight {
+		query["highlight"] = esHighlight
 	}
-	searchRes, err := runSearch(i.es, query)
+	searchRes, err := runSearch(ctx, i.es, query)
 	if err != nil {
 		return nil, xerrors.Errorf("search run search %w ", err)
 	}
 	return &esIterator{
+		ctx:       ctx,
 		es:        i.es,
 		searchReq: query,
 		rs:        searchRes,
@@ -228,7 +330,7 @@ func (i *ElasticSearchIndexer) Search(q index.Query) (index.Iterator, error) {
 
 // UpdateScore updates the PageRank score from a document with specific link
 // if the linkid not exists with put a place holder with a new score
-func (i *ElasticSearchIndexer) UpdateScore(linkID uuid.UUID, score float64) error {
+func (i *ElasticSearchIndexer) UpdateScore(ctx context.Context, linkID uuid.UUID, score float64) error {
 	var buf bytes.Buffer
 	update := map[string]interface{}{
 		"doc": map[string]interface{}{
@@ -240,7 +342,7 @@ func (i *ElasticSearchIndexer) UpdateScore(linkID uuid.UUID, score float64) erro
 	if err := json.NewEncoder(&buf).Encode(update); err != nil {
 		return xerrors.Errorf("UpdateScore encode update: %w ", err)
 	}
-	res, err := i.es.Update(indexName, linkID.String(), &buf, i.refreshOpt)
+	res, err := i.es.Update(indexName, linkID.String(), &buf, i.refreshOpt, i.es.Update.WithContext(ctx))
 	if err != nil {
 		return xerrors.Errorf("update score updating %w ", err)
 	}
@@ -251,28 +353,212 @@ func (i *ElasticSearchIndexer) UpdateScore(linkID uuid.UUID, score float64) erro
 	return nil
 }
 
+// rankingScript returns a script_score function that applies ranking to
+// the matching documents. All pages of a search must use the same now value
+// so that document scores do not change while paginating.
+func rankingScript(ranking index.Ranking, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"script": map[string]interface{}{
+			"source": esRankingScript,
+			"params": map[string]interface{}{
+				"relevanceWeight": ranking.RelevanceWeight,
+				"pageRankWeight":  ranking.PageRankWeight,
+				"halfLife":        ranking.RecencyHalfLife.Milliseconds(),
+				"now":             unixMillis(now),
+				"zeroTime":        unixMillis(time.Time{}),
+			},
+		},
+	}
+}
+
+func unixMillis(t time.Time) int64 {
+	return t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+}
+
+// BulkIndex inserts or updates a batch of documents using the _bulk API.
+func (i *ElasticSearchIndexer) BulkIndex(ctx context.Context, docs []*index.Document) error {
+	var (
+		failed []index.BulkItemError
+		items  = make([]bulkItem, 0, len(docs))
+	)
+	for _, doc := range docs {
+		if doc.LinkID == uuid.Nil {
+			failed = append(failed, index.BulkItemError{Err: index.ErrMissingLinkID})
+			continue
+		}
+		items = append(items, bulkItem{
+			linkID: doc.LinkID,
+			update: map[string]interface{}{
+				"doc":           makeEsDoc(doc),
+				"doc_as_upsert": true,
+			},
+		})
+	}
+	if err := i.bulkUpdate(ctx, items, &failed); err != nil {
+		return xerrors.Errorf("bulk index: %w", err)
+	}
+	if len(failed) != 0 {
+		return xerrors.Errorf("bulk index: %w", &index.BulkError{Items: failed})
+	}
+	return nil
+}
+
+// BulkUpdateScores updates the PageRank score of a batch of links using the
+// _bulk API. Place holder documents are created for unknown links.
+func (i *ElasticSearchIndexer) BulkUpdateScores(ctx context.Context, updates []index.ScoreUpdate) error {
+	var (
+		failed []index.BulkItemError
+		items  = make([]bulkItem, len(updates))
+	)
+	for j, update := range updates {
+		items[j] = bulkItem{
+			linkID: update.LinkID,
+			update: map[string]interface{}{
+				"doc": map[string]interface{}{
+					"LinkID":   update.LinkID.String(),
+					"PageRank": update.Score,
+				},
+				"doc_as_upsert": true,
+			},
+		}
+	}
+	if err := i.bulkUpdate(ctx, items, &failed); err != nil {
+		return xerrors.Errorf("bulk update scores: %w", err)
+	}
+	if len(failed) != 0 {
+		return xerrors.Errorf("bulk update scores: %w", &index.BulkError{Items: failed})
+	}
+	return nil
+}
+
+// BulkDelete removes the documents for a batch of links using the _bulk API.
+// Deleting an unknown document is not reported as an error by elastic
+// search.
+func (i *ElasticSearchIndexer) BulkDelete(ctx context.Context, linkIDs []uuid.UUID) error {
+	var (
+		failed []index.BulkItemError
+		items  = make([]bulkItem, len(linkIDs))
+	)
+	for j, linkID := range linkIDs {
+		items[j] = bulkItem{linkID: linkID}
+	}
+	if err := i.bulkUpdate(ctx, items, &failed); err != nil {
+		return xerrors.Errorf("bulk delete: %w", err)
+	}
+	if len(failed) != 0 {
+		return xerrors.Errorf("bulk delete: %w", &index.BulkError{Items: failed})
+	}
+	return nil
+}
+
+// bulkItem is an update action for a single document. Items without an
+// update are sent as delete actions.
+type bulkItem struct {
+	linkID uuid.UUID
+	update map[string]interface{}
+}
+
+// bulkUpdate sends items as update or delete actions to the _bulk API,
+// splitting them into requests of at most bulkMaxItems items and roughly
+// bulkMaxBytes. The items rejected by elastic search are appended to failed;
+// an error is only returned if a request fails as a whole.
+func (i *ElasticSearchIndexer) bulkUpdate(ctx context.Context, items []bulkItem, failed *[]index.BulkItemError) error {
+	var (
+		buf, itemBuf bytes.Buffer
+		pending      []uuid.UUID
+	)
+	for _, item := range items {
+		itemBuf.Reset()
+		enc := json.NewEncoder(&itemBuf)
+		actionType := "update"
+		if item.update == nil {
+			actionType = "delete"
+		}
+		action := map[string]interface{}{
+			actionType: map[string]interface{}{"_id": item.linkID.String()},
+		}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if item.update != nil {
+			if err := enc.Encode(item.update); err != nil {
+				return err
+			}
+		}
+
+		if len(pending) != 0 && (len(pending) == bulkMaxItems || buf.Len()+itemBuf.Len() > bulkMaxBytes) {
+			if err := i.sendBulk(ctx, &buf, pending, failed); err != nil {
+				return err
+			}
+			buf.Reset()
+			pending = pending[:0]
+		}
+		_, _ = buf.Write(itemBuf.Bytes())
+		pending = append(pending, item.linkID)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	return i.sendBulk(ctx, &buf, pending, failed)
+}
+
+// sendBulk performs a single _bulk request and appends the rejected items to
+// failed. linkIDs lists the link of each action in body in request order.
+func (i *ElasticSearchIndexer) sendBulk(ctx context.Context, body *bytes.Buffer, linkIDs []uuid.UUID, failed *[]index.BulkItemError) error {
+	res, err := i.es.Bulk(
+		body,
+		i.es.Bulk.WithIndex(indexName),
+		i.bulkRefreshOpt,
+		i.es.Bulk.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	var bulkRes esBulkRes
+	if err = unmarshalResponse(res, &bulkRes); err != nil {
+		return err
+	}
+	if !bulkRes.Errors {
+		return nil
+	}
+	for j, item := range bulkRes.Items {
+		if j >= len(linkIDs) {
+			break
+		}
+		for _, itemRes := range item {
+			if itemRes.Error != nil {
+				*failed = append(*failed, index.BulkItemError{LinkID: linkIDs[j], Err: *itemRes.Error})
+			}
+		}
+	}
+	return nil
+}
+
 // mapEsDoc helper function return the index.Document ready for work with elastic search
 func mapEsDoc(d *esDoc) *index.Document {
-	return &index.Document{
+	doc := &index.Document{
 		LinkID:    uuid.MustParse(d.LinkID),
 		URL:       d.URL,
 		Title:     d.Title,
 		Content:   d.Content,
 		IndexedAt: d.IndexedAt.UTC(),
-		PageRank:  d.PageRank,
 	}
+	if d.PageRank != nil {
+		doc.PageRank = *d.PageRank
+	}
+	return doc
 }
 
 // runSearch going to run the search on the elastic search db and return the struct with the findings
 // decode the search for bytes
-// perform the search query and check the error
-func runSearch(es *elasticsearch.Client, searchQuery map[string]interface{}) (*esSearchRes, error) {
+// perform the search query and check the error, the request is cancelled once ctx expires
+func runSearch(ctx context.Context, es *elasticsearch.Client, searchQuery map[string]interface{}) (*esSearchRes, error) {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(searchQuery); err != nil {
 		return nil, xerrors.Errorf("run search: %w ", err)
 	}
 	res, err := es.Search(
-		es.Search.WithContext(context.Background()),
+		es.Search.WithContext(ctx),
 		es.Search.WithIndex(indexName),
 		es.Search.WithBody(&buf),
 	)
@@ -287,18 +573,28 @@ func runSearch(es *elasticsearch.Client, searchQuery map[string]interface{}) (*e
 }
 
 // makeEsDoc make the document ready for elastic search and prepper to the inserted
-// Note: PageRank is intentionally skip it, still have no idea how to do this, and don't want to overwrite existing PageRank values.
-// for update PageRank we will use another method with an id.
+// Note: PageRank is intentionally left unset so that upserts retain the
+// existing PageRank values; scores are updated with UpdateScore instead.
 func makeEsDoc(doc *index.Document) esDoc {
 	return esDoc{
 		LinkID:    doc.LinkID.String(),
 		URL:       doc.URL,
+		Site:      siteForURL(doc.URL),
 		Title:     doc.Title,
 		Content:   doc.Content,
 		IndexedAt: doc.IndexedAt.UTC(),
 	}
 }
 
+// siteForURL returns the lower-cased host name of a document URL.
+func siteForURL(docURL string) string {
+	u, err := url.Parse(docURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
 // ensureIndex helper function to create the instance
 func ensureIndex(es *elasticsearch.Client) error {
 	mappingsReader := strings.NewReader(esMappings)
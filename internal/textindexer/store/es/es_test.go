@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/joshvoll/linkrus/internal/textindexer/index"
 	"github.com/joshvoll/linkrus/internal/textindexer/index/indextest"
 	gc "gopkg.in/check.v1"
 )
@@ -44,6 +45,8 @@ func (s *ElasticSearchTestSuite) SetUpTest(c *gc.C) {
 		c.Assert(err, gc.IsNil)
 		err = ensureIndex(s.idx.es)
 		c.Assert(err, gc.IsNil)
+		// Reset the ranking configured by previous tests.
+		c.Assert(s.idx.SetRanking(index.DefaultRanking()), gc.IsNil)
 	}
 
 }
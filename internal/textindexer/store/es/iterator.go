@@ -1,12 +1,15 @@
 package es
 
 import (
+	"context"
+
 	"github.com/elastic/go-elasticsearch"
 	"github.com/joshvoll/linkrus/internal/textindexer/index"
 )
 
 // esIterator implements the index.Iterator interface
 type esIterator struct {
+	ctx        context.Context
 	es         *elasticsearch.Client
 	searchReq  map[string]interface{}
 	rs         *esSearchRes
@@ -18,6 +21,7 @@ type esIterator struct {
 
 // Close add the Close iterator from the index.Iterator
 func (it *esIterator) Close() error {
+	it.ctx = nil
 	it.es = nil
 	it.searchReq = nil
 	it.cumIdx = it.rs.Hits.Total.Count
@@ -32,12 +36,16 @@ func (it *esIterator) Next() bool {
 	}
 	if it.rsIdx >= len(it.rs.Hits.HitList) {
 		it.searchReq["from"] = it.searchReq["from"].(uint64) + batchSize
-		if it.rs, it.lastErr = runSearch(it.es, it.searchReq); it.lastErr != nil {
+		if it.rs, it.lastErr = runSearch(it.ctx, it.es, it.searchReq); it.lastErr != nil {
 			return false
 		}
 		it.rsIdx = 0
 	}
-	it.latchedDoc = mapEsDoc(&it.rs.Hits.HitList[it.rsIdx].DocSource)
+	hit := &it.rs.Hits.HitList[it.rsIdx]
+	it.latchedDoc = mapEsDoc(&hit.DocSource)
+	if len(hit.Highlight) != 0 {
+		it.latchedDoc.Highlights = hit.Highlight
+	}
 	it.cumIdx++
 	it.rsIdx++
 	return true
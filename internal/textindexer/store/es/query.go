@@ -0,0 +1,219 @@
+package es
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshvoll/linkrus/internal/textindexer/index"
+	"golang.org/x/xerrors"
+)
+
+// The esDoc fields that query expressions are matched against.
+const (
+	titleField     = "Title"
+	contentField   = "Content"
+	urlField       = "URL"
+	siteField      = "Site"
+	indexedAtField = "IndexedAt"
+)
+
+// queryTranslator converts queries into elastic search query DSL clauses.
+// Matches in the title field are boosted by titleBoost.
+type queryTranslator struct {
+	titleBoost float64
+}
+
+// translate converts a parsed query expression into an elastic search query
+// DSL clause.
+func (t queryTranslator) translate(expr index.Expr) (map[string]interface{}, error) {
+	switch e := expr.(type) {
+	case *index.TermExpr:
+		return t.termQuery(e.Field, e.Term), nil
+	case *index.PhraseExpr:
+		switch e.Field {
+		case index.FieldURL, index.FieldSite:
+			return t.termQuery(e.Field, e.Phrase), nil
+		default:
+			return t.textQuery(e.Field, "phrase", e.Phrase), nil
+		}
+	case *index.PrefixExpr:
+		return t.perField(e.Field, "prefix", normalizeTerm(e.Field, e.Prefix)), nil
+	case *index.WildcardExpr:
+		return t.perField(e.Field, "wildcard", normalizeTerm(e.Field, e.Pattern)), nil
+	case *index.DateRangeExpr:
+		bounds := make(map[string]interface{})
+		if !e.From.IsZero() {
+			bounds["gte"] = e.From.UTC().Format(time.RFC3339Nano)
+		}
+		if !e.To.IsZero() {
+			bounds["lt"] = e.To.UTC().Format(time.RFC3339Nano)
+		}
+		return map[string]interface{}{
+			"range": map[string]interface{}{indexedAtField: bounds},
+		}, nil
+	case *index.AndExpr:
+		must := []interface{}{}
+		mustNot := []interface{}{}
+		for _, sub := range e.Exprs {
+			if not, isNot := sub.(*index.NotExpr); isNot {
+				q, err := t.translate(not.Expr)
+				if err != nil {
+					return nil, err
+				}
+				mustNot = append(mustNot, q)
+				continue
+			}
+			q, err := t.translate(sub)
+			if err != nil {
+				return nil, err
+			}
+			must = append(must, q)
+		}
+		clauses := map[string]interface{}{"must": must}
+		if len(mustNot) != 0 {
+			clauses["must_not"] = mustNot
+		}
+		return boolQuery(clauses), nil
+	case *index.OrExpr:
+		should := make([]interface{}, len(e.Exprs))
+		for i, sub := range e.Exprs {
+			q, err := t.translate(sub)
+			if err != nil {
+				return nil, err
+			}
+			should[i] = q
+		}
+		return shouldQuery(should), nil
+	case *index.NotExpr:
+		// Bool queries with only must_not clauses match all documents
+		// except the ones matched by those clauses.
+		q, err := t.translate(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return boolQuery(map[string]interface{}{
+			"must_not": []interface{}{q},
+		}), nil
+	default:
+		return nil, xerrors.Errorf("unsupported query expression %T: %w", expr, index.ErrInvalidQuery)
+	}
+}
+
+// termQuery returns a query for a single term. Title and content terms are
+// analyzed whereas URL and site terms must match exactly. Site terms also
+// match any sub-domain of the specified host.
+func (t queryTranslator) termQuery(f index.Field, term string) map[string]interface{} {
+	switch f {
+	case index.FieldURL:
+		return map[string]interface{}{
+			"term": map[string]interface{}{urlField: term},
+		}
+	case index.FieldSite:
+		return shouldQuery([]interface{}{
+			map[string]interface{}{
+				"term": map[string]interface{}{siteField: term},
+			},
+			map[string]interface{}{
+				"wildcard": map[string]interface{}{siteField: "*." + term},
+			},
+		})
+	default:
+		return t.textQuery(f, "best_fields", term)
+	}
+}
+
+// textQuery returns a match query of the specified multi_match type against
+// the analyzed document fields that f refers to.
+func (t queryTranslator) textQuery(f index.Field, matchType, expression string) map[string]interface{} {
+	if f != index.FieldTitle {
+		return t.multiMatchQuery(matchType, expression)
+	}
+	queryType := "match"
+	if matchType == "phrase" {
+		queryType = "match_phrase"
+	}
+	return map[string]interface{}{
+		queryType: map[string]interface{}{titleField: t.fieldQuery(titleField, "query", expression)},
+	}
+}
+
+// perField returns a query of the specified type for each document field
+// that f refers to, combined so that matching any of them suffices.
+func (t queryTranslator) perField(f index.Field, queryType, value string) map[string]interface{} {
+	fields := fieldNames(f)
+	queries := make([]interface{}, len(fields))
+	for i, field := range fields {
+		queries[i] = map[string]interface{}{
+			queryType: map[string]interface{}{field: t.fieldQuery(field, "value", value)},
+		}
+	}
+	if len(queries) == 1 {
+		return queries[0].(map[string]interface{})
+	}
+	return shouldQuery(queries)
+}
+
+// fieldQuery returns the per-field parameters of a query. Title queries use
+// the long form so that they can be boosted; valueKey is the name the query
+// type uses for its value.
+func (t queryTranslator) fieldQuery(field, valueKey, value string) interface{} {
+	if field != titleField || t.titleBoost == 1 {
+		return value
+	}
+	return map[string]interface{}{
+		valueKey: value,
+		"boost":  t.titleBoost,
+	}
+}
+
+func fieldNames(f index.Field) []string {
+	switch f {
+	case index.FieldTitle:
+		return []string{titleField}
+	case index.FieldURL:
+		return []string{urlField}
+	case index.FieldSite:
+		return []string{siteField}
+	default:
+		return []string{titleField, contentField}
+	}
+}
+
+// normalizeTerm lower-cases prefix and wildcard terms for analyzed fields as
+// these queries are not analyzed by elastic search.
+func normalizeTerm(f index.Field, term string) string {
+	if f == index.FieldURL {
+		return term
+	}
+	return strings.ToLower(term)
+}
+
+// multiMatchQuery returns a multi_match query of the specified type against
+// the document title and content.
+func (t queryTranslator) multiMatchQuery(matchType, expression string) map[string]interface{} {
+	title := titleField
+	if t.titleBoost != 1 {
+		title = titleField + "^" + strconv.FormatFloat(t.titleBoost, 'g', -1, 64)
+	}
+	return map[string]interface{}{
+		"multi_match": map[string]interface{}{
+			"type":   matchType,
+			"query":  expression,
+			"fields": []string{title, contentField},
+		},
+	}
+}
+
+// shouldQuery returns a bool query that matches documents matching at least
+// one of the specified clauses.
+func shouldQuery(clauses []interface{}) map[string]interface{} {
+	return boolQuery(map[string]interface{}{
+		"should":               clauses,
+		"minimum_should_match": 1,
+	})
+}
+
+func boolQuery(clauses map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"bool": clauses}
+}
@@ -0,0 +1,136 @@
+package es
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshvoll/linkrus/internal/textindexer/index"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(QueryTranslationTestSuite))
+
+// QueryTranslationTestSuite verifies the translation of query expressions
+// into the elastic search query DSL.
+type QueryTranslationTestSuite struct{}
+
+func (s *QueryTranslationTestSuite) TestTranslateExpr(c *gc.C) {
+	expr, err := index.ParseQuery(`title:"sunny beach" -site:Example.com Moun* indexed:2020-01-01..`)
+	c.Assert(err, gc.IsNil)
+
+	got, err := queryTranslator{titleBoost: 1}.translate(expr)
+	c.Assert(err, gc.IsNil)
+
+	exp := `{
+	  "bool": {
+	    "must": [
+	      {"match_phrase": {"Title": "sunny beach"}},
+	      {"bool": {"minimum_should_match": 1, "should": [
+	        {"prefix": {"Title": "moun"}},
+	        {"prefix": {"Content": "moun"}}
+	      ]}},
+	      {"range": {"IndexedAt": {"gte": "2020-01-01T00:00:00Z"}}}
+	    ],
+	    "must_not": [
+	      {"bool": {"minimum_should_match": 1, "should": [
+	        {"term": {"Site": "example.com"}},
+	        {"wildcard": {"Site": "*.example.com"}}
+	      ]}}
+	    ]
+	  }
+	}`
+	assertJSONEquals(c, got, exp)
+}
+
+func (s *QueryTranslationTestSuite) TestTranslateNot(c *gc.C) {
+	expr, err := index.ParseQuery(`NOT url:https://example.com/ OR b?ach`)
+	c.Assert(err, gc.IsNil)
+
+	got, err := queryTranslator{titleBoost: 1}.translate(expr)
+	c.Assert(err, gc.IsNil)
+
+	exp := `{
+	  "bool": {
+	    "minimum_should_match": 1,
+	    "should": [
+	      {"bool": {"must_not": [{"term": {"URL": "https://example.com/"}}]}},
+	      {"bool": {"minimum_should_match": 1, "should": [
+	        {"wildcard": {"Title": "b?ach"}},
+	        {"wildcard": {"Content": "b?ach"}}
+	      ]}}
+	    ]
+	  }
+	}`
+	assertJSONEquals(c, got, exp)
+}
+
+func (s *QueryTranslationTestSuite) TestTranslateTitleBoost(c *gc.C) {
+	expr, err := index.ParseQuery(`beach title:"sunny resort" b?ach`)
+	c.Assert(err, gc.IsNil)
+
+	got, err := queryTranslator{titleBoost: 2.5}.translate(expr)
+	c.Assert(err, gc.IsNil)
+
+	exp := `{
+	  "bool": {
+	    "must": [
+	      {"multi_match": {"type": "best_fields", "query": "beach", "fields": ["Title^2.5", "Content"]}},
+	      {"match_phrase": {"Title": {"query": "sunny resort", "boost": 2.5}}},
+	      {"bool": {"minimum_should_match": 1, "should": [
+	        {"wildcard": {"Title": {"value": "b?ach", "boost": 2.5}}},
+	        {"wildcard": {"Content": "b?ach"}}
+	      ]}}
+	    ]
+	  }
+	}`
+	assertJSONEquals(c, got, exp)
+}
+
+func (s *QueryTranslationTestSuite) TestRankingScript(c *gc.C) {
+	ranking := index.Ranking{
+		RelevanceWeight: 0.5,
+		PageRankWeight:  2,
+		RecencyHalfLife: time.Hour,
+	}
+	now := time.Date(2020, time.January, 1, 0, 0, 0, int(time.Millisecond), time.UTC)
+
+	got := rankingScript(ranking, now)
+	params := got["script"].(map[string]interface{})["params"]
+	c.Assert(params, gc.DeepEquals, map[string]interface{}{
+		"relevanceWeight": 0.5,
+		"pageRankWeight":  2.0,
+		"halfLife":        int64(3600000),
+		"now":             int64(1577836800001),
+		"zeroTime":        int64(-62135596800000),
+	})
+}
+
+func assertJSONEquals(c *gc.C, got map[string]interface{}, exp string) {
+	var expVal, gotVal interface{}
+	c.Assert(json.Unmarshal([]byte(exp), &expVal), gc.IsNil)
+	data, err := json.Marshal(got)
+	c.Assert(err, gc.IsNil)
+	c.Assert(json.Unmarshal(data, &gotVal), gc.IsNil)
+	c.Assert(gotVal, gc.DeepEquals, expVal, gc.Commentf("got: %s", data))
+}
+
+func (s *QueryTranslationTestSuite) TestDocumentTranslationOmitsPageRank(c *gc.C) {
+	doc := &index.Document{
+		LinkID:   uuid.New(),
+		URL:      "https://www.example.com/",
+		PageRank: 0.5,
+	}
+	data, err := json.Marshal(makeEsDoc(doc))
+	c.Assert(err, gc.IsNil)
+	var fields map[string]interface{}
+	c.Assert(json.Unmarshal(data, &fields), gc.IsNil)
+	_, hasPageRank := fields["PageRank"]
+	c.Assert(hasPageRank, gc.Equals, false, gc.Commentf("upserted documents must not overwrite the stored PageRank: %s", data))
+
+	var decoded esDoc
+	c.Assert(json.Unmarshal([]byte(`{"LinkID":"`+doc.LinkID.String()+`","PageRank":0.25}`), &decoded), gc.IsNil)
+	c.Assert(mapEsDoc(&decoded).PageRank, gc.Equals, 0.25)
+	decoded.PageRank = nil
+	c.Assert(mapEsDoc(&decoded).PageRank, gc.Equals, 0.0)
+}
@@ -1,17 +1,16 @@
 package memory
 
 import (
-	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search"
 	"github.com/joshvoll/linkrus/internal/textindexer/index"
 )
 
 // bleveIterator implements index.Iterator
 type bleveIterator struct {
 	idx        *InMemoryBleveIndexer
-	searchReq  *bleve.SearchRequest
+	hits       search.DocumentMatchCollection
+	total      uint64
 	cumIdx     uint64
-	rsIdx      int
-	rs         *bleve.SearchResult
 	latchedDoc *index.Document
 	lastErr    error
 }
@@ -19,10 +18,7 @@ type bleveIterator struct {
 // Close implements Close from index.Iterator
 func (b *bleveIterator) Close() error {
 	b.idx = nil
-	b.searchReq = nil
-	if b.rs != nil {
-		b.cumIdx = b.rs.Total
-	}
+	b.hits = nil
 	return nil
 }
 
@@ -34,22 +30,17 @@ func (b *bleveIterator) Error() error {
 // Next implements the Next() from index.Iterator
 // load the next document matching the search if is not available return false
 func (b *bleveIterator) Next() bool {
-	if b.lastErr != nil || b.rs == nil || b.cumIdx >= b.rs.Total {
+	if b.lastErr != nil || b.cumIdx >= uint64(len(b.hits)) {
 		return false
 	}
-	if b.rsIdx >= b.rs.Hits.Len() {
-		b.searchReq.From += b.searchReq.Size
-		if b.rs, b.lastErr = b.idx.idx.Search(b.searchReq); b.lastErr != nil {
-			return false
-		}
-		b.rsIdx = 0
-	}
-	nextID := b.rs.Hits[b.rsIdx].ID
-	if b.latchedDoc, b.lastErr = b.idx.findByID(nextID); b.lastErr != nil {
+	hit := b.hits[b.cumIdx]
+	if b.latchedDoc, b.lastErr = b.idx.findByID(hit.ID); b.lastErr != nil {
 		return false
 	}
+	if len(hit.Fragments) != 0 {
+		b.latchedDoc.Highlights = map[string][]string(hit.Fragments)
+	}
 	b.cumIdx++
-	b.rsIdx++
 	return true
 }
 
@@ -60,8 +51,5 @@ func (b *bleveIterator) Document() *index.Document {
 
 // TotalCount return the total count of document
 func (b *bleveIterator) TotalCount() uint64 {
-	if b.rs == nil {
-		return 0
-	}
-	return b.rs.Total
+	return b.total
 }
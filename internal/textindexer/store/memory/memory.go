@@ -1,29 +1,39 @@
 package memory
 
 import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/mapping"
+	"github.com/blevesearch/bleve/search"
+	"github.com/blevesearch/bleve/search/highlight/highlighter/html"
 	"github.com/blevesearch/bleve/search/query"
 	"github.com/google/uuid"
 	"github.com/joshvoll/linkrus/internal/textindexer/index"
 	"golang.org/x/xerrors"
 )
 
-const batchSize = 10
-
 // bleveDoc struct definition
 type bleveDoc struct {
-	Title    string
-	Content  string
-	PageRank float64
+	Title     string
+	Content   string
+	URL       string
+	Site      string
+	IndexedAt time.Time
+	PageRank  float64
 }
 
 // InMemoryBleveIndexer is the indexer defintion from the index
 type InMemoryBleveIndexer struct {
-	mu   sync.RWMutex
-	docs map[string]*index.Document
+	mu      sync.RWMutex
+	docs    map[string]*index.Document
+	ranking index.Ranking
 
 	idx bleve.Index
 }
@@ -31,43 +41,99 @@ type InMemoryBleveIndexer struct {
 // NewInMemoryBleveIndexer return the memory test
 func NewInMemoryBleveIndexer() (*InMemoryBleveIndexer, error) {
 	mapping := bleve.NewIndexMapping()
+	mapping.DefaultMapping = newDocMapping()
 	idx, err := bleve.NewMemOnly(mapping)
 	if err != nil {
 		return nil, err
 	}
 	return &InMemoryBleveIndexer{
-		idx:  idx,
-		docs: make(map[string]*index.Document),
+		idx:     idx,
+		docs:    make(map[string]*index.Document),
+		ranking: index.DefaultRanking(),
 	}, nil
 }
 
+// SetRanking configures how search results are ranked. If not invoked, the
+// indexer uses index.DefaultRanking.
+func (i *InMemoryBleveIndexer) SetRanking(ranking index.Ranking) error {
+	if err := ranking.Validate(); err != nil {
+		return xerrors.Errorf("set ranking: %w", err)
+	}
+	i.mu.Lock()
+	i.ranking = ranking
+	i.mu.Unlock()
+	return nil
+}
+
 // Close the indexer and release all connection
 func (i *InMemoryBleveIndexer) Close() error {
 	return i.idx.Close()
 }
 
 // Index insert a new document index or update an existing one
-func (i *InMemoryBleveIndexer) Index(doc *index.Document) error {
+func (i *InMemoryBleveIndexer) Index(_ context.Context, doc *index.Document) error {
 	if doc.LinkID == uuid.Nil {
 		return xerrors.Errorf("index %w ", index.ErrMissingLinkID)
 	}
-	doc.IndexedAt = time.Now()
-	dcopy := copyDoc(doc)
-	key := dcopy.LinkID.String()
 	i.mu.Lock()
-	if orig, exists := i.docs[key]; exists {
-		dcopy.PageRank = orig.PageRank
-	}
+	defer i.mu.Unlock()
+	dcopy := i.prepareDoc(doc)
+	key := dcopy.LinkID.String()
 	if err := i.idx.Index(key, makeBleveDoc(dcopy)); err != nil {
 		return xerrors.Errorf("index: %w ", err)
 	}
 	i.docs[key] = dcopy
-	i.mu.Unlock()
 	return nil
 }
 
+// BulkIndex inserts or updates a batch of documents using a single bleve
+// batch.
+func (i *InMemoryBleveIndexer) BulkIndex(_ context.Context, docs []*index.Document) error {
+	var failed []index.BulkItemError
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	batch := i.idx.NewBatch()
+	dcopies := make([]*index.Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc.LinkID == uuid.Nil {
+			failed = append(failed, index.BulkItemError{Err: index.ErrMissingLinkID})
+			continue
+		}
+		dcopy := i.prepareDoc(doc)
+		if err := batch.Index(dcopy.LinkID.String(), makeBleveDoc(dcopy)); err != nil {
+			failed = append(failed, index.BulkItemError{LinkID: dcopy.LinkID, Err: err})
+			continue
+		}
+		dcopies = append(dcopies, dcopy)
+	}
+	if err := i.idx.Batch(batch); err != nil {
+		return xerrors.Errorf("bulk index: %w", err)
+	}
+	for _, dcopy := range dcopies {
+		i.docs[dcopy.LinkID.String()] = dcopy
+	}
+	if len(failed) != 0 {
+		return xerrors.Errorf("bulk index: %w", &index.BulkError{Items: failed})
+	}
+	return nil
+}
+
+// prepareDoc returns a copy of doc ready to be indexed, retaining the
+// PageRank score of the document it replaces. The caller must hold i.mu.
+func (i *InMemoryBleveIndexer) prepareDoc(doc *index.Document) *index.Document {
+	if doc.IndexedAt.IsZero() {
+		doc.IndexedAt = time.Now()
+	}
+	dcopy := copyDoc(doc)
+	dcopy.Highlights = nil
+	if orig, exists := i.docs[dcopy.LinkID.String()]; exists {
+		dcopy.PageRank = orig.PageRank
+	}
+	return dcopy
+}
+
 // FindByID return the document base on the id from the link R us link project
-func (i *InMemoryBleveIndexer) FindByID(linkID uuid.UUID) (*index.Document, error) {
+func (i *InMemoryBleveIndexer) FindByID(_ context.Context, linkID uuid.UUID) (*index.Document, error) {
 	return i.findByID(linkID.String())
 }
 
@@ -81,33 +147,76 @@ func (i *InMemoryBleveIndexer) findByID(linkID string) (*index.Document, error)
 	return nil, xerrors.Errorf("find by id: %w ", index.ErrNotFound)
 }
 
-// Search for a particular document return back an Iterator
-func (i *InMemoryBleveIndexer) Search(q index.Query) (index.Iterator, error) {
-	var bq query.Query
+// Search for a particular document return back an Iterator. The search is
+// aborted if ctx expires.
+//
+// As bleve cannot calculate the ranking score natively, all matching
+// documents are retrieved and ranked before the results are paginated.
+func (i *InMemoryBleveIndexer) Search(ctx context.Context, q index.Query) (index.Iterator, error) {
+	i.mu.RLock()
+	ranking, docCount := i.ranking, len(i.docs)
+	i.mu.RUnlock()
+
+	var (
+		bq query.Query
+		t  = queryTranslator{titleBoost: ranking.TitleBoost}
+	)
 	switch q.Type {
 	case index.QueryTypeFrase:
-		bq = bleve.NewMatchPhraseQuery(q.Expression)
+		bq = t.perField(index.FieldDefault, func() boostableQuery { return bleve.NewMatchPhraseQuery(q.Expression) })
+	case index.QueryTypeAdvanced:
+		expr, err := index.ParseQuery(q.Expression)
+		if err != nil {
+			return nil, xerrors.Errorf("search: %w", err)
+		}
+		if bq, err = t.translate(expr); err != nil {
+			return nil, xerrors.Errorf("search: %w", err)
+		}
 	default:
-		bq = bleve.NewMatchQuery(q.Expression)
+		bq = t.perField(index.FieldDefault, func() boostableQuery { return bleve.NewMatchQuery(q.Expression) })
 	}
 	searchReq := bleve.NewSearchRequest(bq)
-	searchReq.SortBy([]string{"-PageRank", "-_score"})
-	searchReq.Size = batchSize
-	searchReq.From = int(q.Offset)
-	rs, err := i.idx.Search(searchReq)
+	searchReq.Size = docCount
+	if q.Highlight {
+		searchReq.Highlight = bleve.NewHighlightWithStyle(html.Name)
+		searchReq.Highlight.AddField(titleField)
+		searchReq.Highlight.AddField(contentField)
+	}
+	rs, err := i.idx.SearchInContext(ctx, searchReq)
 	if err != nil {
 		return nil, xerrors.Errorf("serach %w : ", err)
 	}
 	return &bleveIterator{
-		idx:       i,
-		searchReq: searchReq,
-		rs:        rs,
-		cumIdx:    q.Offset,
+		idx:    i,
+		hits:   i.rank(ranking, rs.Hits, time.Now()),
+		total:  uint64(len(rs.Hits)),
+		cumIdx: q.Offset,
 	}, nil
 }
 
+// rank replaces the relevance score of each hit with its ranking score and
+// sorts the hits in descending score order. Ties are broken by link ID so
+// that results are paginated deterministically.
+func (i *InMemoryBleveIndexer) rank(ranking index.Ranking, hits search.DocumentMatchCollection, now time.Time) search.DocumentMatchCollection {
+	i.mu.RLock()
+	for _, hit := range hits {
+		if doc, found := i.docs[hit.ID]; found {
+			hit.Score = ranking.Score(hit.Score, doc.PageRank, doc.IndexedAt, now)
+		}
+	}
+	i.mu.RUnlock()
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ID < hits[b].ID
+	})
+	return hits
+}
+
 // UpdateScore it will udpate the score or existing one base on link id and score pass
-func (i *InMemoryBleveIndexer) UpdateScore(linkID uuid.UUID, score float64) error {
+func (i *InMemoryBleveIndexer) UpdateScore(_ context.Context, linkID uuid.UUID, score float64) error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 	key := linkID.String()
@@ -125,6 +234,57 @@ func (i *InMemoryBleveIndexer) UpdateScore(linkID uuid.UUID, score float64) erro
 	return nil
 }
 
+// BulkUpdateScores updates the PageRank score of a batch of links using a
+// single bleve batch.
+func (i *InMemoryBleveIndexer) BulkUpdateScores(_ context.Context, updates []index.ScoreUpdate) error {
+	var failed []index.BulkItemError
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	batch := i.idx.NewBatch()
+	dcopies := make([]*index.Document, 0, len(updates))
+	for _, update := range updates {
+		key := update.LinkID.String()
+		dcopy := &index.Document{LinkID: update.LinkID}
+		if orig, exists := i.docs[key]; exists {
+			dcopy = copyDoc(orig)
+		}
+		dcopy.PageRank = update.Score
+		if err := batch.Index(key, makeBleveDoc(dcopy)); err != nil {
+			failed = append(failed, index.BulkItemError{LinkID: update.LinkID, Err: err})
+			continue
+		}
+		dcopies = append(dcopies, dcopy)
+	}
+	if err := i.idx.Batch(batch); err != nil {
+		return xerrors.Errorf("bulk update scores: %w", err)
+	}
+	for _, dcopy := range dcopies {
+		i.docs[dcopy.LinkID.String()] = dcopy
+	}
+	if len(failed) != 0 {
+		return xerrors.Errorf("bulk update scores: %w", &index.BulkError{Items: failed})
+	}
+	return nil
+}
+
+// BulkDelete removes the documents for a batch of links using a single bleve
+// batch.
+func (i *InMemoryBleveIndexer) BulkDelete(_ context.Context, linkIDs []uuid.UUID) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	batch := i.idx.NewBatch()
+	for _, linkID := range linkIDs {
+		batch.Delete(linkID.String())
+	}
+	if err := i.idx.Batch(batch); err != nil {
+		return xerrors.Errorf("bulk delete: %w", err)
+	}
+	for _, linkID := range linkIDs {
+		delete(i.docs, linkID.String())
+	}
+	return nil
+}
+
 func copyDoc(d *index.Document) *index.Document {
 	dcopy := new(index.Document)
 	*dcopy = *d
@@ -134,8 +294,51 @@ func copyDoc(d *index.Document) *index.Document {
 // makeBleveDoc just copy the doc to the bleve memory
 func makeBleveDoc(d *index.Document) bleveDoc {
 	return bleveDoc{
-		Title:    d.Title,
-		Content:  d.Content,
-		PageRank: d.PageRank,
+		Title:     d.Title,
+		Content:   d.Content,
+		URL:       d.URL,
+		Site:      siteForURL(d.URL),
+		IndexedAt: d.IndexedAt,
+		PageRank:  d.PageRank,
+	}
+}
+
+// newDocMapping returns the mapping for bleveDoc values. The title and content
+// are stored together with their term vectors which the highlighter requires
+// for generating snippets. The URL and Site fields are indexed verbatim so
+// they can be matched exactly by field-scoped queries. These fields and
+// IndexedAt are excluded from the composite field that unscoped match queries
+// are evaluated against.
+func newDocMapping() *mapping.DocumentMapping {
+	textField := func() *mapping.FieldMapping {
+		fm := bleve.NewTextFieldMapping()
+		fm.Store = true
+		fm.IncludeTermVectors = true
+		return fm
+	}
+	keywordField := func() *mapping.FieldMapping {
+		fm := bleve.NewTextFieldMapping()
+		fm.Analyzer = keyword.Name
+		fm.IncludeInAll = false
+		return fm
+	}
+	indexedAtMapping := bleve.NewDateTimeFieldMapping()
+	indexedAtMapping.IncludeInAll = false
+
+	docMapping := bleve.NewDocumentMapping()
+	docMapping.AddFieldMappingsAt(titleField, textField())
+	docMapping.AddFieldMappingsAt(contentField, textField())
+	docMapping.AddFieldMappingsAt(urlField, keywordField())
+	docMapping.AddFieldMappingsAt(siteField, keywordField())
+	docMapping.AddFieldMappingsAt(indexedAtField, indexedAtMapping)
+	return docMapping
+}
+
+// siteForURL returns the lower-cased host name of a document URL.
+func siteForURL(docURL string) string {
+	u, err := url.Parse(docURL)
+	if err != nil {
+		return ""
 	}
+	return strings.ToLower(u.Hostname())
 }
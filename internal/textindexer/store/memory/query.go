@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"strings"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/joshvoll/linkrus/internal/textindexer/index"
+	"golang.org/x/xerrors"
+)
+
+// The bleveDoc fields that query expressions are matched against.
+const (
+	titleField     = "Title"
+	contentField   = "Content"
+	urlField       = "URL"
+	siteField      = "Site"
+	indexedAtField = "IndexedAt"
+)
+
+// boostableQuery is implemented by bleve queries that can be restricted to a
+// field and whose score can be boosted.
+type boostableQuery interface {
+	query.FieldableQuery
+	SetBoost(b float64)
+}
+
+// queryTranslator converts queries into bleve queries. Matches in the title
+// field are boosted by titleBoost.
+type queryTranslator struct {
+	titleBoost float64
+}
+
+// translate converts a parsed query expression into a bleve query.
+func (t queryTranslator) translate(expr index.Expr) (query.Query, error) {
+	switch e := expr.(type) {
+	case *index.TermExpr:
+		return t.termQuery(e.Field, e.Term), nil
+	case *index.PhraseExpr:
+		if e.Field == index.FieldURL || e.Field == index.FieldSite {
+			return t.termQuery(e.Field, e.Phrase), nil
+		}
+		return t.perField(e.Field, func() boostableQuery {
+			return bleve.NewMatchPhraseQuery(e.Phrase)
+		}), nil
+	case *index.PrefixExpr:
+		prefix := normalizeTerm(e.Field, e.Prefix)
+		return t.perField(e.Field, func() boostableQuery {
+			return bleve.NewPrefixQuery(prefix)
+		}), nil
+	case *index.WildcardExpr:
+		pattern := normalizeTerm(e.Field, e.Pattern)
+		return t.perField(e.Field, func() boostableQuery {
+			return bleve.NewWildcardQuery(pattern)
+		}), nil
+	case *index.DateRangeExpr:
+		inclusive, exclusive := true, false
+		q := bleve.NewDateRangeInclusiveQuery(e.From, e.To, &inclusive, &exclusive)
+		q.SetField(indexedAtField)
+		return q, nil
+	case *index.AndExpr:
+		var must, mustNot []query.Query
+		for _, sub := range e.Exprs {
+			if not, isNot := sub.(*index.NotExpr); isNot {
+				q, err := t.translate(not.Expr)
+				if err != nil {
+					return nil, err
+				}
+				mustNot = append(mustNot, q)
+				continue
+			}
+			q, err := t.translate(sub)
+			if err != nil {
+				return nil, err
+			}
+			must = append(must, q)
+		}
+		if len(mustNot) == 0 {
+			return bleve.NewConjunctionQuery(must...), nil
+		}
+		return bleve.NewBooleanQuery(must, nil, mustNot), nil
+	case *index.OrExpr:
+		should := make([]query.Query, len(e.Exprs))
+		for i, sub := range e.Exprs {
+			q, err := t.translate(sub)
+			if err != nil {
+				return nil, err
+			}
+			should[i] = q
+		}
+		return bleve.NewDisjunctionQuery(should...), nil
+	case *index.NotExpr:
+		// Boolean queries with only must-not clauses match all documents
+		// except the ones matched by those clauses.
+		q, err := t.translate(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return bleve.NewBooleanQuery(nil, nil, []query.Query{q}), nil
+	default:
+		return nil, xerrors.Errorf("unsupported query expression %T: %w", expr, index.ErrInvalidQuery)
+	}
+}
+
+// termQuery returns a query for a single term. Title and content terms are
+// analyzed whereas URL and site terms must match exactly. Site terms also
+// match any sub-domain of the specified host.
+func (t queryTranslator) termQuery(f index.Field, term string) query.Query {
+	switch f {
+	case index.FieldURL:
+		q := bleve.NewTermQuery(term)
+		q.SetField(urlField)
+		return q
+	case index.FieldSite:
+		host := bleve.NewTermQuery(term)
+		host.SetField(siteField)
+		subDomain := bleve.NewWildcardQuery("*." + term)
+		subDomain.SetField(siteField)
+		return bleve.NewDisjunctionQuery(host, subDomain)
+	default:
+		return t.perField(f, func() boostableQuery {
+			return bleve.NewMatchQuery(term)
+		})
+	}
+}
+
+// perField invokes newQuery for each document field that f refers to and
+// returns a query that matches any of them.
+func (t queryTranslator) perField(f index.Field, newQuery func() boostableQuery) query.Query {
+	fields := fieldNames(f)
+	queries := make([]query.Query, len(fields))
+	for i, field := range fields {
+		q := newQuery()
+		q.SetField(field)
+		if field == titleField && t.titleBoost != 1 {
+			q.SetBoost(t.titleBoost)
+		}
+		queries[i] = q
+	}
+	if len(queries) == 1 {
+		return queries[0]
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+func fieldNames(f index.Field) []string {
+	switch f {
+	case index.FieldTitle:
+		return []string{titleField}
+	case index.FieldURL:
+		return []string{urlField}
+	case index.FieldSite:
+		return []string{siteField}
+	default:
+		return []string{titleField, contentField}
+	}
+}
+
+// normalizeTerm lower-cases prefix and wildcard terms for analyzed fields as
+// these queries are not analyzed by bleve.
+func normalizeTerm(f index.Field, term string) string {
+	if f == index.FieldURL {
+		return term
+	}
+	return strings.ToLower(term)
+}
@@ -1,19 +1,4 @@
-DROP DATABASE IF EXISTS linkgraph CASCADE;
+-- The link graph tables are managed by the versioned migrations defined in
+-- internal/linkgraph/store/cdb/migrations.go. They are applied on startup
+-- when the CockroachDB graph is created with ApplyMigrations enabled.
 CREATE DATABASE IF NOT EXISTS linkgraph;
-SET DATABASE = linkgraph;
-
-DROP TABLE IF EXISTS links;
-CREATE TABLE IF NOT EXISTS links (
-	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-	url STRING UNIQUE,
-	retrieved_at TIMESTAMP
-);
-
-DROP TABLE IF EXISTS edges;
-CREATE TABLE IF NOT EXISTS edges (
-	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-	src UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
-	dst UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
-	updated_at TIMESTAMP,
-	CONSTRAINT edge_links UNIQUE(src,dst)
-);